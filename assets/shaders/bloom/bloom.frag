@@ -0,0 +1,34 @@
+#version 330 core
+layout (location = 0) out vec4 fragCol;
+
+in vec2 fragUV;
+
+uniform sampler2D tex0; // Image
+uniform sampler2D tex2; // Blurred highlights
+
+uniform int stage;       // 0: extract highlights, 1: blur, 2: combine
+uniform vec2 direction;  // Blur direction in texels
+uniform float threshold; // Brightness at which pixels start to bloom
+uniform float intensity;
+
+const float weights[5] = float[](0.227027, 0.1945946, 0.1216216, 0.054054, 0.016216);
+
+void main() {
+  if (stage == 0) {
+    vec3 color = texture(tex0, fragUV).rgb;
+    float brightness = max(color.r, max(color.g, color.b));
+    float contribution = max(brightness - threshold, 0.0) / max(brightness, 0.0001);
+    fragCol = vec4(color * contribution, 1.0);
+  } else if (stage == 1) {
+    vec2 step = direction / vec2(textureSize(tex0, 0));
+    vec3 color = texture(tex0, fragUV).rgb * weights[0];
+    for (int i = 1; i < 5; i++) {
+      color += texture(tex0, fragUV + step * float(i)).rgb * weights[i];
+      color += texture(tex0, fragUV - step * float(i)).rgb * weights[i];
+    }
+    fragCol = vec4(color, 1.0);
+  } else {
+    vec4 color = texture(tex0, fragUV);
+    fragCol = vec4(color.rgb + texture(tex2, fragUV).rgb * intensity, color.a);
+  }
+}
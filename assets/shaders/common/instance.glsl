@@ -0,0 +1,14 @@
+// Model matrices of instanced draws. Requires shader_data to be declared.
+layout (std140) uniform instance_data {
+  int numInstances;
+  mat4 instanceMats[128];
+};
+
+////////////////////////////////////////////////////////////////////////////////
+// Returns the model matrix of the instance being drawn.
+mat4 instanceMatrix() {
+  if (numInstances == 0) {
+    return modelMat;
+  }
+  return instanceMats[gl_InstanceID];
+}
@@ -0,0 +1,186 @@
+// Lights, shadows and the Cook-Torrance BRDF.
+
+const float PI = 3.14159265359;
+
+const int LIGHT_DIRECTIONAL = 0;
+const int LIGHT_POINT = 1;
+const int LIGHT_SPOT = 2;
+
+struct Light {
+  vec4 position;  // w: type
+  vec4 direction; // w: range
+  vec4 color;     // rgb: color multiplied by intensity, w: shadow map or -1
+  vec4 cone;      // x: cos(inner angle), y: cos(outer angle)
+};
+
+uniform sampler2DShadow cascadeMap0;
+uniform sampler2DShadow cascadeMap1;
+uniform sampler2DShadow cascadeMap2;
+uniform sampler2DShadow spotShadowMap0;
+uniform sampler2DShadow spotShadowMap1;
+uniform sampler2DShadow spotShadowMap2;
+uniform sampler2DShadow spotShadowMap3;
+uniform samplerCubeShadow pointShadowMap0;
+uniform samplerCubeShadow pointShadowMap1;
+
+layout (std140) uniform shadow_data {
+  mat4 cascadeMats[3];
+  mat4 spotMats[4];
+  vec4 pointFar;
+  vec4 shadowParams; // x: bias, y: slope bias, z: PCF radius, w: texel size
+};
+
+const float POINT_SHADOW_NEAR = 0.05;
+
+////////////////////////////////////////////////////////////////////////////////
+// GGX / Trowbridge-Reitz normal distribution
+float distributionGGX(float NdotH, float roughness) {
+  float a = roughness * roughness;
+  float a2 = a * a;
+  float d = NdotH * NdotH * (a2 - 1.0) + 1.0;
+  return a2 / (PI * d * d);
+}
+
+// Smith's method with Schlick-GGX
+float geometrySmith(float NdotV, float NdotL, float roughness) {
+  float k = (roughness + 1.0) * (roughness + 1.0) / 8.0;
+  float gV = NdotV / (NdotV * (1.0 - k) + k);
+  float gL = NdotL / (NdotL * (1.0 - k) + k);
+  return gV * gL;
+}
+
+// Schlick's approximation
+vec3 fresnelSchlick(float cosTheta, vec3 F0) {
+  return F0 + (1.0 - F0) * pow(1.0 - cosTheta, 5.0);
+}
+
+////////////////////////////////////////////////////////////////////////////////
+// Cook-Torrance BRDF multiplied by the incoming radiance.
+vec3 calcLight(vec3 lightVec, vec3 radiance, vec3 N, vec3 V,
+               vec3 albedo, float metallic, float roughness) {
+  vec3 H = normalize(V + lightVec);
+  float NdotL = max(dot(N, lightVec), 0.0);
+  float NdotV = max(dot(N, V), 0.0001);
+  float NdotH = max(dot(N, H), 0.0);
+
+  vec3 F0 = mix(vec3(0.04), albedo, metallic);
+  vec3 F = fresnelSchlick(max(dot(H, V), 0.0), F0);
+  float D = distributionGGX(NdotH, roughness);
+  float G = geometrySmith(NdotV, NdotL, roughness);
+
+  vec3 specular = D * G * F / (4.0 * NdotV * NdotL + 0.0001);
+  vec3 kD = (1.0 - F) * (1.0 - metallic);
+  return (kD * albedo / PI + specular) * radiance * NdotL;
+}
+
+////////////////////////////////////////////////////////////////////////////////
+// Returns the direction towards the light and its radiance at pos.
+vec3 calcRadiance(Light light, vec3 pos, out vec3 lightVec) {
+  int type = int(light.position.w);
+  if (type == LIGHT_DIRECTIONAL) {
+    lightVec = -light.direction.xyz;
+    return light.color.rgb;
+  }
+
+  vec3 toLight = light.position.xyz - pos;
+  float dist = length(toLight);
+  lightVec = toLight / dist;
+
+  // Inverse square falloff, windowed to reach zero at the range
+  float attenuation = 1.0 / max(dist * dist, 0.0001);
+  float range = light.direction.w;
+  if (range > 0) {
+    attenuation *= pow(clamp(1.0 - pow(dist / range, 4.0), 0.0, 1.0), 2.0);
+  }
+
+  if (type == LIGHT_SPOT) {
+    float cd = dot(light.direction.xyz, -lightVec);
+    attenuation *= smoothstep(light.cone.y, max(light.cone.x, light.cone.y + 0.0001), cd);
+  }
+  return light.color.rgb * attenuation;
+}
+
+////////////////////////////////////////////////////////////////////////////////
+// Percentage closer filtering of a shadow map.
+float filterShadow(sampler2DShadow map, vec3 coord) {
+  int r = int(shadowParams.z);
+  float sum = 0.0;
+  for (int x = -r; x <= r; x++) {
+    for (int y = -r; y <= r; y++) {
+      sum += texture(map, vec3(coord.xy + vec2(x, y) * shadowParams.w, coord.z));
+    }
+  }
+  return sum / float((2 * r + 1) * (2 * r + 1));
+}
+
+float filterCubeShadow(samplerCubeShadow map, vec3 dir, float depth) {
+  int r = int(shadowParams.z);
+  if (r == 0) {
+    return texture(map, vec4(dir, depth));
+  }
+  // Sample the corners of a cube around the direction
+  float offset = float(r) * shadowParams.w * 2.0 * length(dir);
+  float sum = texture(map, vec4(dir, depth));
+  for (int i = 0; i < 8; i++) {
+    vec3 o = vec3(i & 1, (i >> 1) & 1, (i >> 2) & 1) * 2.0 - 1.0;
+    sum += texture(map, vec4(dir + o * offset, depth));
+  }
+  return sum / 9.0;
+}
+
+// Returns the fraction of light reaching pos.
+float calcShadow(Light light, vec3 pos, vec3 N, vec3 lightVec) {
+  int index = int(light.color.w);
+  if (index < 0) {
+    return 1.0;
+  }
+  float NdotL = clamp(dot(N, lightVec), 0.05, 1.0);
+  float bias = shadowParams.x + shadowParams.y * sqrt(1.0 - NdotL * NdotL) / NdotL;
+  bias = min(bias, 0.01);
+
+  int type = int(light.position.w);
+  if (type == LIGHT_DIRECTIONAL) {
+    // Use the first cascade containing the position
+    for (int i = 0; i < 3; i++) {
+      vec3 c = (cascadeMats[i] * vec4(pos, 1)).xyz * 0.5 + 0.5;
+      if (any(lessThan(c, vec3(0))) || any(greaterThan(c, vec3(1)))) {
+        continue;
+      }
+      c.z -= bias;
+      if (i == 0) return filterShadow(cascadeMap0, c);
+      if (i == 1) return filterShadow(cascadeMap1, c);
+      return filterShadow(cascadeMap2, c);
+    }
+    return 1.0;
+  }
+
+  if (type == LIGHT_SPOT) {
+    vec4 p = spotMats[index] * vec4(pos, 1);
+    vec3 c = p.xyz / p.w * 0.5 + 0.5;
+    c.z -= bias;
+    if (index == 0) return filterShadow(spotShadowMap0, c);
+    if (index == 1) return filterShadow(spotShadowMap1, c);
+    if (index == 2) return filterShadow(spotShadowMap2, c);
+    return filterShadow(spotShadowMap3, c);
+  }
+
+  // Point lights store the depth of the cube face facing the position
+  vec3 dir = pos - light.position.xyz;
+  float far = pointFar[index];
+  float near = POINT_SHADOW_NEAR;
+  float z = max(max(abs(dir.x), abs(dir.y)), abs(dir.z));
+  float depth = (far + near) / (far - near) - (2.0 * far * near) / ((far - near) * z);
+  depth = depth * 0.5 + 0.5 - bias;
+  if (index == 0) return filterCubeShadow(pointShadowMap0, dir, depth);
+  return filterCubeShadow(pointShadowMap1, dir, depth);
+}
+
+////////////////////////////////////////////////////////////////////////////////
+// Returns the light reflected towards V by a surface at pos.
+vec3 shade(Light light, vec3 pos, vec3 N, vec3 V,
+           vec3 albedo, float metallic, float roughness) {
+  vec3 lightVec;
+  vec3 radiance = calcRadiance(light, pos, lightVec);
+  radiance *= calcShadow(light, pos, N, lightVec);
+  return calcLight(lightVec, radiance, N, V, albedo, metallic, roughness);
+}
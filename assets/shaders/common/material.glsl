@@ -0,0 +1,93 @@
+// Evaluates the PBR material of the fragment.
+// Requires fragUV and TBN from common/vertex.glsl.
+
+uniform sampler2D tex0; // Base color
+uniform sampler2D tex1; // Normal
+uniform sampler2D tex2; // Metallic (B) and roughness (G)
+uniform sampler2D tex3; // Occlusion (R)
+uniform sampler2D tex4; // Emissive
+
+uniform vec4 baseColorFactor;
+uniform float metallicFactor;
+uniform float roughnessFactor;
+uniform float normalScale;
+uniform float occlusionStrength;
+uniform vec3 emissiveFactor;
+uniform int alphaMode; // 0 = opaque, 1 = mask, 2 = blend
+uniform float alphaCutoff;
+uniform int textures;  // Bit i is set if tex<i> is bound
+
+struct Surface {
+  vec4 baseColor; // Linear color and alpha
+  float metallic;
+  float roughness;
+  vec3 normal;    // World space
+  float occlusion;
+  vec3 emissive;  // Linear color
+};
+
+////////////////////////////////////////////////////////////////////////////////
+bool hasTexture(int unit) {
+  return (textures & (1 << unit)) != 0;
+}
+
+vec3 toLinear(vec3 srgb) {
+  return pow(srgb, vec3(2.2));
+}
+
+////////////////////////////////////////////////////////////////////////////////
+vec3 calcNormal() {
+  vec3 n = TBN[2];
+  if (hasTexture(1)) {
+    n = texture(tex1, fragUV).rgb * 2 - 1;
+    n.xy *= normalScale;
+    n = TBN * n;
+  }
+  n = normalize(n);
+  return gl_FrontFacing ? n : -n;
+}
+
+////////////////////////////////////////////////////////////////////////////////
+// Discards the fragment if it is cut off by the alpha mode.
+Surface calcSurface() {
+  Surface s;
+
+  // Base color
+  s.baseColor = baseColorFactor;
+  if (hasTexture(0)) {
+    vec4 t = texture(tex0, fragUV);
+    s.baseColor *= vec4(toLinear(t.rgb), t.a);
+  }
+  if (alphaMode == 1) {
+    if (s.baseColor.a < alphaCutoff) {
+      discard;
+    }
+    s.baseColor.a = 1.0;
+  } else if (alphaMode == 0) {
+    s.baseColor.a = 1.0;
+  }
+
+  // Metallic and roughness
+  s.metallic = metallicFactor;
+  s.roughness = roughnessFactor;
+  if (hasTexture(2)) {
+    vec4 mr = texture(tex2, fragUV);
+    s.metallic *= mr.b;
+    s.roughness *= mr.g;
+  }
+  s.metallic = clamp(s.metallic, 0.0, 1.0);
+  s.roughness = clamp(s.roughness, 0.04, 1.0);
+
+  s.normal = calcNormal();
+
+  s.occlusion = 1.0;
+  if (hasTexture(3)) {
+    s.occlusion = 1.0 + occlusionStrength * (texture(tex3, fragUV).r - 1.0);
+  }
+
+  s.emissive = emissiveFactor;
+  if (hasTexture(4)) {
+    s.emissive *= toLinear(texture(tex4, fragUV).rgb);
+  }
+  return s;
+}
@@ -0,0 +1,67 @@
+// Vertex stage shared by shaders rendering PBR materials.
+layout (location = 0) in vec3 vertPos;
+layout (location = 1) in vec3 vertNorm;
+layout (location = 2) in vec2 vertUV;
+layout (location = 3) in vec4 vertTang; // w holds the handedness
+layout (location = 4) in uvec4 vertJoints;
+layout (location = 5) in vec4 vertWeights;
+layout (location = 6) in vec3 morphPos[4];
+layout (location = 10) in vec3 morphNorm[4];
+
+layout (std140) uniform shader_data {
+  mat4 viewProjMat;
+  mat4 modelMat;
+  vec4 viewPos;
+};
+
+#include "common/instance.glsl"
+
+layout (std140) uniform skin_data {
+  int numJoints;
+  vec4 morphWeights;
+  mat4 jointMats[64];
+};
+
+out vec3 fragPos;
+out vec2 fragUV;
+out mat3 TBN;
+
+////////////////////////////////////////////////////////////////////////////////
+mat4 calcSkinMatrix() {
+  if (numJoints == 0) {
+    return mat4(1.0);
+  }
+  return vertWeights.x * jointMats[vertJoints.x] +
+         vertWeights.y * jointMats[vertJoints.y] +
+         vertWeights.z * jointMats[vertJoints.z] +
+         vertWeights.w * jointMats[vertJoints.w];
+}
+
+void main() {
+  // Apply morph targets. Unused targets have a weight of 0.
+  vec3 pos = vertPos;
+  vec3 norm = vertNorm;
+  for (int i = 0; i < 4; i++) {
+    pos += morphWeights[i] * morphPos[i];
+    norm += morphWeights[i] * morphNorm[i];
+  }
+
+  mat4 model = instanceMatrix() * calcSkinMatrix();
+
+  gl_Position = viewProjMat * model * vec4(pos, 1.0);
+  fragPos = vec3(model * vec4(pos, 1.0));
+  fragUV = vertUV;
+
+  // Fall back to an arbitrary tangent if the mesh has none
+  vec3 tang = vertTang.xyz;
+  float handedness = vertTang.w < 0 ? -1.0 : 1.0;
+  if (dot(tang, tang) == 0) {
+    tang = abs(norm.x) < 0.9 ? cross(norm, vec3(1, 0, 0)) : cross(norm, vec3(0, 1, 0));
+  }
+
+  vec3 N = normalize(vec3(model * vec4(norm, 0.0)));
+  vec3 T = normalize(vec3(model * vec4(tang, 0.0)));
+  T = normalize(T - dot(T, N) * N);
+  vec3 B = cross(N, T) * handedness;
+  TBN = mat3(T, B, N);
+}
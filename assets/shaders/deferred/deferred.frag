@@ -0,0 +1,72 @@
+#version 330 core
+layout (location = 0) out vec4 fragCol;
+
+in vec2 fragPos;
+in vec2 fragUV;
+
+uniform sampler2D tex0; // Base color (rgb) and metallic (a)
+uniform sampler2D tex1; // Normal (xyz) and roughness (w)
+uniform sampler2D tex2; // Emissive (rgb) and occlusion (a)
+uniform sampler2D tex3; // Depth
+
+uniform samplerBuffer lightData; // 4 texels per light
+uniform isamplerBuffer tileData; // [offset, count] per tile, followed by light indices
+
+uniform mat4 invViewProj;
+uniform int tileSize;
+uniform int numTilesX;
+
+layout (std140) uniform shader_data {
+  mat4 viewProjMat;
+  mat4 modelMat;
+  vec4 viewPos;
+};
+
+#include "common/lighting.glsl"
+
+const vec3 ambient = vec3(0.03);
+
+////////////////////////////////////////////////////////////////////////////////
+Light fetchLight(int i) {
+  Light l;
+  l.position = texelFetch(lightData, i * 4);
+  l.direction = texelFetch(lightData, i * 4 + 1);
+  l.color = texelFetch(lightData, i * 4 + 2);
+  l.cone = texelFetch(lightData, i * 4 + 3);
+  return l;
+}
+
+////////////////////////////////////////////////////////////////////////////////
+void main() {
+  float depth = texture(tex3, fragUV).r;
+  if (depth == 1.0) {
+    discard;
+  }
+
+  // Reconstruct the world position from depth
+  vec4 p = invViewProj * vec4(vec3(fragUV, depth) * 2.0 - 1.0, 1.0);
+  vec3 pos = p.xyz / p.w;
+
+  vec4 g0 = texture(tex0, fragUV);
+  vec4 g1 = texture(tex1, fragUV);
+  vec4 g2 = texture(tex2, fragUV);
+  vec3 albedo = g0.rgb;
+  vec3 N = normalize(g1.xyz);
+  vec3 V = normalize(vec3(viewPos) - pos);
+
+  // Only shade with the lights overlapping this tile
+  ivec2 tile = ivec2(gl_FragCoord.xy) / tileSize;
+  int t = tile.y * numTilesX + tile.x;
+  int offset = texelFetch(tileData, t * 2).r;
+  int count = texelFetch(tileData, t * 2 + 1).r;
+
+  vec3 color = vec3(0);
+  for (int i = 0; i < count; i++) {
+    Light l = fetchLight(texelFetch(tileData, offset + i).r);
+    color += shade(l, pos, N, V, albedo, g0.a, g1.w);
+  }
+  color += ambient * albedo * g2.a;
+  color += g2.rgb;
+
+  fragCol = vec4(color, 1.0);
+}
@@ -0,0 +1,12 @@
+#version 330 core
+layout (location = 0) out vec4 fragCol;
+
+in vec2 fragUV;
+
+uniform sampler2D tex0;
+uniform float exposure; // In stops
+
+void main() {
+  vec4 color = texture(tex0, fragUV);
+  fragCol = vec4(color.rgb * exp2(exposure), color.a);
+}
@@ -0,0 +1,13 @@
+#version 330 core
+layout (location = 0) out vec4 fragCol;
+
+in vec2 fragUV;
+
+uniform sampler2D tex0;
+// The window encodes its output as sRGB, so 1 leaves the image unchanged.
+uniform float gamma;
+
+void main() {
+  vec4 color = texture(tex0, fragUV);
+  fragCol = vec4(pow(max(color.rgb, 0.0), vec3(1.0 / gamma)), color.a);
+}
@@ -0,0 +1,18 @@
+#version 330 core
+layout (location = 0) out vec4 gBaseColor; // rgb: base color, a: metallic
+layout (location = 1) out vec4 gNormal;    // xyz: normal, w: roughness
+layout (location = 2) out vec4 gEmissive;  // rgb: emissive, a: occlusion
+
+in vec3 fragPos;
+in vec2 fragUV;
+in mat3 TBN;
+
+#include "common/material.glsl"
+
+////////////////////////////////////////////////////////////////////////////////
+void main() {
+  Surface s = calcSurface();
+  gBaseColor = vec4(s.baseColor.rgb, s.metallic);
+  gNormal = vec4(s.normal, s.roughness);
+  gEmissive = vec4(s.emissive, s.occlusion);
+}
@@ -0,0 +1,31 @@
+#version 330 core
+layout (location = 0) out vec4 fragCol;
+
+in vec2 fragUV;
+
+uniform sampler2D tex0; // Image
+uniform sampler2D tex2; // Lookup table of N slices of NxN laid out horizontally
+
+uniform float intensity;
+
+// lookup maps an sRGB color through the lookup table.
+vec3 lookup(vec3 c) {
+  float size = float(textureSize(tex2, 0).y);
+  float slice = c.b * (size - 1.0);
+  float s0 = floor(slice);
+  float s1 = min(s0 + 1.0, size - 1.0);
+
+  // Sample texel centers to avoid bleeding between slices
+  vec2 uv = (c.rg * (size - 1.0) + 0.5) / vec2(size * size, size);
+  vec3 a = texture(tex2, uv + vec2(s0 / size, 0.0)).rgb;
+  vec3 b = texture(tex2, uv + vec2(s1 / size, 0.0)).rgb;
+  return mix(a, b, slice - s0);
+}
+
+void main() {
+  vec4 color = texture(tex0, fragUV);
+  // Lookup tables are authored in sRGB
+  vec3 srgb = pow(clamp(color.rgb, 0.0, 1.0), vec3(1.0 / 2.2));
+  vec3 graded = pow(lookup(srgb), vec3(2.2));
+  fragCol = vec4(mix(color.rgb, graded, intensity), color.a);
+}
@@ -0,0 +1,38 @@
+#version 330 core
+layout (location = 0) in vec3 vertPos;
+layout (location = 4) in uvec4 vertJoints;
+layout (location = 5) in vec4 vertWeights;
+layout (location = 6) in vec3 morphPos[4];
+
+layout (std140) uniform shader_data {
+  mat4 viewProjMat;
+  mat4 modelMat;
+  vec4 viewPos;
+};
+
+#include "common/instance.glsl"
+
+layout (std140) uniform skin_data {
+  int numJoints;
+  vec4 morphWeights;
+  mat4 jointMats[64];
+};
+
+////////////////////////////////////////////////////////////////////////////////
+mat4 calcSkinMatrix() {
+  if (numJoints == 0) {
+    return mat4(1.0);
+  }
+  return vertWeights.x * jointMats[vertJoints.x] +
+         vertWeights.y * jointMats[vertJoints.y] +
+         vertWeights.z * jointMats[vertJoints.z] +
+         vertWeights.w * jointMats[vertJoints.w];
+}
+
+void main() {
+  vec3 pos = vertPos;
+  for (int i = 0; i < 4; i++) {
+    pos += morphWeights[i] * morphPos[i];
+  }
+  gl_Position = viewProjMat * instanceMatrix() * calcSkinMatrix() * vec4(pos, 1.0);
+}
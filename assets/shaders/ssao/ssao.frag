@@ -0,0 +1,65 @@
+#version 330 core
+layout (location = 0) out vec4 fragCol;
+
+in vec2 fragPos;
+in vec2 fragUV;
+
+uniform sampler2D tex0; // Image
+uniform sampler2D tex1; // Depth
+
+uniform mat4 proj;
+uniform mat4 invProj;
+uniform float radius;    // Sampling radius in world units
+uniform float intensity;
+uniform float bias;      // Depth difference ignored to avoid self-occlusion
+
+const int numSamples = 16;
+
+// viewPos reconstructs the view space position at uv.
+vec3 viewPos(vec2 uv) {
+  float depth = texture(tex1, uv).r;
+  vec4 p = invProj * vec4(vec3(uv, depth) * 2.0 - 1.0, 1.0);
+  return p.xyz / p.w;
+}
+
+float hash(vec2 p) {
+  return fract(sin(dot(p, vec2(12.9898, 78.233))) * 43758.5453);
+}
+
+void main() {
+  vec4 color = texture(tex0, fragUV);
+  if (texture(tex1, fragUV).r == 1.0) {
+    fragCol = color; // Background
+    return;
+  }
+
+  vec3 P = viewPos(fragUV);
+  vec3 N = normalize(cross(dFdx(P), dFdy(P)));
+
+  // Randomly rotated tangent frame around the normal
+  float angle = hash(gl_FragCoord.xy) * 6.2831853;
+  vec3 R = vec3(cos(angle), sin(angle), 0.0);
+  vec3 T = normalize(R - N * dot(R, N));
+  mat3 TBN = mat3(T, cross(N, T), N);
+
+  float occlusion = 0.0;
+  for (int i = 0; i < numSamples; i++) {
+    // Points spread over the hemisphere, denser near the center
+    float f = float(i) / float(numSamples);
+    float a = float(i) * 2.3999632; // Golden angle
+    float r = sqrt(f);
+    vec3 dir = vec3(cos(a) * r, sin(a) * r, sqrt(1.0 - f));
+    float scale = mix(0.1, 1.0, f * f);
+    vec3 S = P + TBN * dir * radius * scale;
+
+    vec4 offset = proj * vec4(S, 1.0);
+    vec2 uv = offset.xy / offset.w * 0.5 + 0.5;
+    float sceneZ = viewPos(uv).z;
+
+    float rangeCheck = smoothstep(0.0, 1.0, radius / abs(P.z - sceneZ));
+    occlusion += (sceneZ >= S.z + bias ? 1.0 : 0.0) * rangeCheck;
+  }
+
+  float ao = 1.0 - intensity * occlusion / float(numSamples);
+  fragCol = vec4(color.rgb * clamp(ao, 0.0, 1.0), color.a);
+}
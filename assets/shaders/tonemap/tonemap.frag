@@ -0,0 +1,29 @@
+#version 330 core
+layout (location = 0) out vec4 fragCol;
+
+in vec2 fragUV;
+
+uniform sampler2D tex0;
+uniform int operator;     // 0: ACES, 1: Reinhard
+uniform float whitePoint; // Reinhard: the smallest value mapped to white
+
+// Fit of the ACES filmic curve by Krzysztof Narkowicz.
+vec3 aces(vec3 x) {
+  const float a = 2.51;
+  const float b = 0.03;
+  const float c = 2.43;
+  const float d = 0.59;
+  const float e = 0.14;
+  return clamp((x * (a * x + b)) / (x * (c * x + d) + e), 0.0, 1.0);
+}
+
+// Extended Reinhard operator.
+vec3 reinhard(vec3 x) {
+  return x * (1.0 + x / (whitePoint * whitePoint)) / (1.0 + x);
+}
+
+void main() {
+  vec4 color = texture(tex0, fragUV);
+  vec3 mapped = operator == 0 ? aces(color.rgb) : reinhard(color.rgb);
+  fragCol = vec4(mapped, color.a);
+}
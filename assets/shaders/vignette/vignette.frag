@@ -0,0 +1,17 @@
+#version 330 core
+layout (location = 0) out vec4 fragCol;
+
+in vec2 fragPos;
+in vec2 fragUV;
+
+uniform sampler2D tex0;
+uniform float intensity;
+uniform float radius;     // Distance from the center at which darkening starts
+uniform float smoothness; // Width of the transition
+
+void main() {
+  vec4 color = texture(tex0, fragUV);
+  float d = length(fragPos) / sqrt(2.0);
+  float v = smoothstep(radius, radius + smoothness, d);
+  fragCol = vec4(color.rgb * (1.0 - v * intensity), color.a);
+}
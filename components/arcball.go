@@ -5,6 +5,7 @@ import (
 
 	mgl "github.com/go-gl/mathgl/mgl32"
 
+	"github.com/patrick-jessen/goplay/engine/clock"
 	"github.com/patrick-jessen/goplay/engine/log"
 	"github.com/patrick-jessen/goplay/engine/scene"
 	"github.com/patrick-jessen/goplay/engine/shader"
@@ -27,7 +28,12 @@ type ArcBall struct {
 	node *scene.Node
 }
 
-const damping = 0.90
+// Velocities are measured per frame at the reference rate, and damped by
+// the same amount per second at any frame rate.
+const (
+	damping = 0.90 // Fraction of velocity kept per reference frame.
+	refRate = 60   // Reference frame rate.
+)
 
 func (c *ArcBall) Initialize(n *scene.Node) {
 	c.node = n
@@ -36,31 +42,36 @@ func (c *ArcBall) Initialize(n *scene.Node) {
 }
 func (c *ArcBall) Render() {}
 
-func (c *ArcBall) Update() {
+// Update moves the camera by the mouse input. The camera is controlled in
+// real time, such that it keeps responding while the game is paused or
+// slowed down.
+func (c *ArcBall) Update(float32) {
+	frames := clock.RealDelta() * refRate
+	decay := float32(math.Pow(damping, float64(frames)))
 
 	// Handle movement
 	if window.MouseButton(0) {
 		move := window.MouseMove().Mul(0.005)
 
 		if move.X()*move.X() < c.rotYVelocity*c.rotYVelocity {
-			c.rotYVelocity *= damping
+			c.rotYVelocity *= decay
 		} else if move.X() != c.rotYVelocity {
 			c.rotYVelocity += 0.1 * move.X()
 		}
 
 		if move.Y()*move.Y() < c.rotXVelocity*c.rotXVelocity {
-			c.rotXVelocity *= damping
+			c.rotXVelocity *= decay
 		} else if move.Y() != c.rotXVelocity {
 			c.rotXVelocity += 0.1 * move.Y()
 		}
 
 	} else {
-		c.rotXVelocity *= damping
-		c.rotYVelocity *= damping
+		c.rotXVelocity *= decay
+		c.rotYVelocity *= decay
 	}
 
-	c.RotX += c.rotXVelocity
-	c.RotY += c.rotYVelocity
+	c.RotX += c.rotXVelocity * frames
+	c.RotY += c.rotYVelocity * frames
 
 	if c.RotX > math.Pi/2-0.001 {
 		c.RotX = math.Pi/2 - 0.001
@@ -70,11 +81,11 @@ func (c *ArcBall) Update() {
 
 	// Handle zoom
 	if window.MouseScroll() == 0 {
-		c.zoomVelocity *= damping
+		c.zoomVelocity *= decay
 	} else {
 		c.zoomVelocity += window.MouseScroll() * c.Dist * 0.005
 	}
-	c.Dist -= c.zoomVelocity
+	c.Dist -= c.zoomVelocity * frames
 
 	// Calculate camera position
 	sinY := float32(math.Sin(float64(c.RotY)))
@@ -69,12 +69,30 @@ export default {
         body: JSON.stringify({res:r})
       });
     },
+    getErrors(then) {
+      fetch(baseURL + "texture/errors")
+        .then(r => r.json()).then(r => {
+          then(r.errors);
+        })
+    },
     apply() {
       fetch(baseURL + "texture/apply");
     }
   },
 
   renderer: {
+    getType(then) {
+      fetch(baseURL + "renderer/type")
+        .then(r => r.json()).then(r => {
+          then(r.type);
+        })
+    },
+    setType(t) {
+      fetch(baseURL + "renderer/type", {
+        method: "POST", 
+        body: JSON.stringify({type:t})
+      });
+    },
     getAA(then) {
       fetch(baseURL + "renderer/aa")
         .then(r => r.json()).then(r => {
@@ -87,8 +105,57 @@ export default {
         body: JSON.stringify({aa:a})
       });
     },
+    getShadows(then) {
+      fetch(baseURL + "renderer/shadows")
+        .then(r => r.json()).then(r => {
+          then(r.resolution, r.filter, r.bias, r.slopeBias);
+        })
+    },
+    setShadows(resolution, filter, bias, slopeBias) {
+      fetch(baseURL + "renderer/shadows", {
+        method: "POST", 
+        body: JSON.stringify({resolution,filter,bias,slopeBias})
+      });
+    },
+    getPostProcessing(then) {
+      fetch(baseURL + "renderer/postfx")
+        .then(r => r.json()).then(then)
+    },
+    setPostProcessing(effects) {
+      fetch(baseURL + "renderer/postfx", {
+        method: "POST", 
+        body: JSON.stringify(effects)
+      });
+    },
+    getStats(then) {
+      fetch(baseURL + "renderer/stats")
+        .then(r => r.json()).then(r => {
+          then(r.drawn, r.culled);
+        })
+    },
     apply() {
       fetch(baseURL + "renderer/apply");
     }
+  },
+
+  scene: {
+    load(name) {
+      return fetch(baseURL + "scene/load", {
+        method: "POST", 
+        body: JSON.stringify({name})
+      });
+    },
+    save(name) {
+      return fetch(baseURL + "scene/save", {
+        method: "POST", 
+        body: JSON.stringify({name})
+      });
+    },
+    export(name) {
+      return fetch(baseURL + "scene/export", {
+        method: "POST", 
+        body: JSON.stringify({name})
+      });
+    }
   }
 }
\ No newline at end of file
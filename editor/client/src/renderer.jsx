@@ -2,14 +2,23 @@ import {h, Component} from "preact";
 import Option from "./option";
 import api from "./api";
 
+const types = ["Forward", "Deferred"];
+const filters = ["None", "3x3 PCF", "5x5 PCF", "7x7 PCF"];
+
 export default class Renderer extends Component {
   constructor() {
     super();
 
     this.state = {
-      antialiasing: 0
+      type: types[0],
+      antialiasing: 0,
+      shadows: {resolution: 1024, filter: 1, bias: 0, slopeBias: 0}
     };
 
+    api.renderer.getType(t => {
+      this.setState({type: types[t]});
+    })
+
     api.renderer.getAA(a => {
       let val;
       switch(a) {
@@ -23,7 +32,19 @@ export default class Renderer extends Component {
       this.setState({antialiasing:val})
     })
 
+    api.renderer.getShadows((resolution, filter, bias, slopeBias) => {
+      this.setState({shadows: {resolution, filter, bias, slopeBias}});
+    })
+
+    this.onType = this.onType.bind(this);
     this.onAntialiasing = this.onAntialiasing.bind(this);
+    this.onShadowResolution = this.onShadowResolution.bind(this);
+    this.onShadowFilter = this.onShadowFilter.bind(this);
+  }
+
+  onType(t) {
+    api.renderer.setType(types.indexOf(t));
+    this.setState({type: t});
   }
 
   onAntialiasing(a) {
@@ -41,19 +62,50 @@ export default class Renderer extends Component {
     this.setState({antialiasing: a});
   }
 
+  setShadows(shadows) {
+    api.renderer.setShadows(shadows.resolution, shadows.filter, shadows.bias, shadows.slopeBias);
+    this.setState({shadows});
+  }
+
+  onShadowResolution(r) {
+    this.setShadows({...this.state.shadows, resolution: parseInt(r)});
+  }
+
+  onShadowFilter(f) {
+    this.setShadows({...this.state.shadows, filter: filters.indexOf(f)});
+  }
+
   onApply() {
     api.renderer.apply();
   }
 
-  render({}, {antialiasing}) {
+  render({}, {type, antialiasing, shadows}) {
     return (
       <div>
+        <Option
+          text="Renderer"
+          options={types}
+          selected={type}
+          onSelect={this.onType}
+        />
         <Option
           text="Antialiasing"
           options={["None", "FXAA", "2x MSAA", "4x MSAA", "8x MSAA", "16x MSAA"]}
           selected={antialiasing}
           onSelect={this.onAntialiasing}
         />
+        <Option
+          text="Shadow resolution"
+          options={["512", "1024", "2048", "4096"]}
+          selected={String(shadows.resolution)}
+          onSelect={this.onShadowResolution}
+        />
+        <Option
+          text="Shadow filter"
+          options={filters}
+          selected={filters[shadows.filter]}
+          onSelect={this.onShadowFilter}
+        />
 
         <button onClick={this.onApply}>Apply</button>
       </div>
@@ -9,6 +9,7 @@ export default class Texture extends Component {
     this.state = {
       filter: "Bilinear",
       res: "Normal",
+      errors: [],
     };
 
     this.onFilter = this.onFilter.bind(this);
@@ -31,6 +32,7 @@ export default class Texture extends Component {
       else if (r == 1) res = "High";
       this.setState({res});
     });
+    api.texture.getErrors(errors => this.setState({errors}));
   }
 
   onFilter(f) {
@@ -77,7 +79,7 @@ export default class Texture extends Component {
     api.texture.apply();
   }
 
-  render({}, {filter, res}) {
+  render({}, {filter, res, errors}) {
     return (
       <div>
         <table>
@@ -105,6 +107,11 @@ export default class Texture extends Component {
           />
         </table>
         <button onClick={this.onApply}>Apply</button>
+        {errors.length > 0 &&
+          <ul>
+            {errors.map(e => <li>{e}</li>)}
+          </ul>
+        }
       </div>
     );
   }
@@ -3,27 +3,32 @@ package editor
 import (
 	"encoding/json"
 	"net/http"
+	"os"
+	"path/filepath"
+	"strings"
 
+	"github.com/patrick-jessen/goplay/engine/model"
 	"github.com/patrick-jessen/goplay/engine/renderer"
+	"github.com/patrick-jessen/goplay/engine/resource"
+	"github.com/patrick-jessen/goplay/engine/scene"
 
 	"github.com/gorilla/handlers"
 	"github.com/gorilla/mux"
 
 	"github.com/patrick-jessen/goplay/engine/texture"
 	"github.com/patrick-jessen/goplay/engine/window"
+	"github.com/patrick-jessen/goplay/engine/worker"
 )
 
-var Channel = make(chan func(), 10)
-
 func windowSetVsync(w http.ResponseWriter, r *http.Request) {
 	tmp := struct {
 		Vsync bool `json:"vsync"`
 	}{}
 	json.NewDecoder(r.Body).Decode(&tmp)
 
-	Channel <- func() {
+	worker.Schedule(worker.PriorityHigh, func() {
 		window.Settings.SetVSync(tmp.Vsync)
-	}
+	})
 	w.WriteHeader(http.StatusOK)
 }
 func windowGetVsync(w http.ResponseWriter, r *http.Request) {
@@ -47,9 +52,9 @@ func windowSetFullScreen(w http.ResponseWriter, r *http.Request) {
 	}{}
 	json.NewDecoder(r.Body).Decode(&tmp)
 
-	Channel <- func() {
+	worker.Schedule(worker.PriorityHigh, func() {
 		window.Settings.SetFullScreen(tmp.FullScreen)
-	}
+	})
 	w.WriteHeader(http.StatusOK)
 }
 func windowGetSize(w http.ResponseWriter, r *http.Request) {
@@ -69,16 +74,16 @@ func windowSetSize(w http.ResponseWriter, r *http.Request) {
 	}{}
 	json.NewDecoder(r.Body).Decode(&tmp)
 
-	Channel <- func() {
+	worker.Schedule(worker.PriorityHigh, func() {
 		window.Settings.SetSize(tmp.Width, tmp.Height)
-	}
+	})
 	w.WriteHeader(http.StatusOK)
 }
 
 func windowApply(w http.ResponseWriter, r *http.Request) {
-	Channel <- func() {
+	worker.Schedule(worker.PriorityHigh, func() {
 		window.Settings.Apply()
-	}
+	})
 	w.WriteHeader(http.StatusOK)
 }
 
@@ -99,9 +104,9 @@ func textureSetFilter(w http.ResponseWriter, r *http.Request) {
 	}{}
 	json.NewDecoder(r.Body).Decode(&tmp)
 
-	Channel <- func() {
+	worker.Schedule(worker.PriorityHigh, func() {
 		texture.Settings.SetFilter(texture.Filter(tmp.Filter), tmp.Aniso)
-	}
+	})
 	w.WriteHeader(http.StatusOK)
 }
 
@@ -118,15 +123,57 @@ func textureSetResolution(w http.ResponseWriter, r *http.Request) {
 	}{}
 	json.NewDecoder(r.Body).Decode(&tmp)
 
-	Channel <- func() {
+	worker.Schedule(worker.PriorityHigh, func() {
 		texture.Settings.SetResolution(uint(tmp.Res))
-	}
+	})
 	w.WriteHeader(http.StatusOK)
 }
+func textureGetErrors(w http.ResponseWriter, r *http.Request) {
+	var errs []error
+	worker.Call(worker.PriorityHigh, func() {
+		errs = texture.Errors()
+	})
+
+	msgs := make([]string, len(errs))
+	for i, e := range errs {
+		msgs[i] = e.Error()
+	}
+	json.NewEncoder(w).Encode(struct {
+		Errors []string `json:"errors"`
+	}{
+		Errors: msgs,
+	})
+}
 func textureApply(w http.ResponseWriter, r *http.Request) {
-	Channel <- func() {
+	worker.Schedule(worker.PriorityHigh, func() {
 		texture.Settings.Apply()
+	})
+	w.WriteHeader(http.StatusOK)
+}
+
+func rendererGetType(w http.ResponseWriter, r *http.Request) {
+	json.NewEncoder(w).Encode(struct {
+		Type int `json:"type"`
+	}{
+		Type: int(renderer.Settings.Type()),
+	})
+}
+func rendererSetType(w http.ResponseWriter, r *http.Request) {
+	tmp := struct {
+		Type int `json:"type"`
+	}{}
+	if err := json.NewDecoder(r.Body).Decode(&tmp); err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+	if !renderer.Type(tmp.Type).Valid() {
+		http.Error(w, "invalid type", http.StatusBadRequest)
+		return
 	}
+
+	worker.Schedule(worker.PriorityHigh, func() {
+		renderer.Settings.SetType(renderer.Type(tmp.Type))
+	})
 	w.WriteHeader(http.StatusOK)
 }
 
@@ -143,14 +190,159 @@ func rendererSetAA(w http.ResponseWriter, r *http.Request) {
 	}{}
 	json.NewDecoder(r.Body).Decode(&tmp)
 
-	Channel <- func() {
+	worker.Schedule(worker.PriorityHigh, func() {
 		renderer.Settings.SetAntialising(renderer.Antialiasing(tmp.AA))
+	})
+	w.WriteHeader(http.StatusOK)
+}
+
+type rendererShadows struct {
+	Resolution int     `json:"resolution"`
+	Filter     int     `json:"filter"`
+	Bias       float32 `json:"bias"`
+	SlopeBias  float32 `json:"slopeBias"`
+}
+
+func rendererGetShadows(w http.ResponseWriter, r *http.Request) {
+	tmp := rendererShadows{
+		Resolution: renderer.Settings.ShadowResolution(),
+		Filter:     renderer.Settings.ShadowFilter(),
+	}
+	tmp.Bias, tmp.SlopeBias = renderer.Settings.ShadowBias()
+	json.NewEncoder(w).Encode(&tmp)
+}
+func rendererSetShadows(w http.ResponseWriter, r *http.Request) {
+	var tmp rendererShadows
+	if err := json.NewDecoder(r.Body).Decode(&tmp); err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
 	}
+	if tmp.Resolution <= 0 || tmp.Resolution > renderer.MaxShadowResolution {
+		http.Error(w, "invalid resolution", http.StatusBadRequest)
+		return
+	}
+
+	worker.Schedule(worker.PriorityHigh, func() {
+		renderer.Settings.SetShadowResolution(tmp.Resolution)
+		renderer.Settings.SetShadowFilter(tmp.Filter)
+		renderer.Settings.SetShadowBias(tmp.Bias, tmp.SlopeBias)
+	})
 	w.WriteHeader(http.StatusOK)
 }
+func rendererGetPostProcessing(w http.ResponseWriter, r *http.Request) {
+	effects := renderer.Settings.PostProcessing()
+	if effects == nil {
+		effects = []renderer.Effect{}
+	}
+	json.NewEncoder(w).Encode(effects)
+}
+func rendererSetPostProcessing(w http.ResponseWriter, r *http.Request) {
+	var effects []renderer.Effect
+	if err := json.NewDecoder(r.Body).Decode(&effects); err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	worker.Schedule(worker.PriorityHigh, func() {
+		renderer.Settings.SetPostProcessing(effects)
+	})
+	w.WriteHeader(http.StatusOK)
+}
+func rendererGetStats(w http.ResponseWriter, r *http.Request) {
+	stats := renderer.Stats()
+	json.NewEncoder(w).Encode(struct {
+		Drawn  int `json:"drawn"`
+		Culled int `json:"culled"`
+	}{
+		Drawn:  stats.Drawn,
+		Culled: stats.Culled,
+	})
+}
 func rendererApply(w http.ResponseWriter, r *http.Request) {
-	Channel <- func() {
+	worker.Schedule(worker.PriorityHigh, func() {
 		renderer.Settings.Apply()
+	})
+	w.WriteHeader(http.StatusOK)
+}
+
+// validName returns whether name is a plain file name, such that joining
+// it with a directory cannot refer to a file outside of that directory.
+func validName(name string) bool {
+	return name != "" && !strings.ContainsAny(name, `/\:`) && !strings.Contains(name, "..")
+}
+
+// decodeName decodes the name of a request, responding with an error if
+// it is missing or not a plain file name.
+func decodeName(w http.ResponseWriter, r *http.Request) (string, bool) {
+	tmp := struct {
+		Name string `json:"name"`
+	}{}
+	if err := json.NewDecoder(r.Body).Decode(&tmp); err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return "", false
+	}
+	if !validName(tmp.Name) {
+		http.Error(w, "invalid name", http.StatusBadRequest)
+		return "", false
+	}
+	return tmp.Name, true
+}
+
+func sceneLoad(w http.ResponseWriter, r *http.Request) {
+	name, ok := decodeName(w, r)
+	if !ok {
+		return
+	}
+
+	var err error
+	worker.Call(worker.PriorityHigh, func() {
+		var s *scene.Scene
+		if s, err = resource.TryLoadScene(name); err == nil {
+			s.MakeCurrent()
+		}
+	})
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+	w.WriteHeader(http.StatusOK)
+}
+func sceneSave(w http.ResponseWriter, r *http.Request) {
+	name, ok := decodeName(w, r)
+	if !ok {
+		return
+	}
+
+	var err error
+	worker.Call(worker.PriorityHigh, func() {
+		err = scene.Current().Save(name)
+	})
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+	w.WriteHeader(http.StatusOK)
+}
+
+// exportDir is the directory which scenes are exported to.
+const exportDir = "./exports/"
+
+func sceneExport(w http.ResponseWriter, r *http.Request) {
+	name, ok := decodeName(w, r)
+	if !ok {
+		return
+	}
+	file := filepath.Join(exportDir, name)
+
+	var err error
+	worker.Call(worker.PriorityHigh, func() {
+		if err = os.MkdirAll(exportDir, 0755); err == nil {
+			err = model.Export(scene.Current().Root, file)
+		}
+	})
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
 	}
 	w.WriteHeader(http.StatusOK)
 }
@@ -172,13 +364,26 @@ func Start() {
 	texture.HandleFunc("/filter", textureSetFilter).Methods("POST")
 	texture.HandleFunc("/resolution", textureGetResolution).Methods("GET")
 	texture.HandleFunc("/resolution", textureSetResolution).Methods("POST")
+	texture.HandleFunc("/errors", textureGetErrors).Methods("GET")
 	texture.HandleFunc("/apply", textureApply).Methods("GET")
 
 	renderer := router.PathPrefix("/renderer").Subrouter()
+	renderer.HandleFunc("/type", rendererGetType).Methods("GET")
+	renderer.HandleFunc("/type", rendererSetType).Methods("POST")
 	renderer.HandleFunc("/aa", rendererGetAA).Methods("GET")
 	renderer.HandleFunc("/aa", rendererSetAA).Methods("POST")
+	renderer.HandleFunc("/shadows", rendererGetShadows).Methods("GET")
+	renderer.HandleFunc("/shadows", rendererSetShadows).Methods("POST")
+	renderer.HandleFunc("/postfx", rendererGetPostProcessing).Methods("GET")
+	renderer.HandleFunc("/postfx", rendererSetPostProcessing).Methods("POST")
+	renderer.HandleFunc("/stats", rendererGetStats).Methods("GET")
 	renderer.HandleFunc("/apply", rendererApply).Methods("GET")
 
+	scene := router.PathPrefix("/scene").Subrouter()
+	scene.HandleFunc("/load", sceneLoad).Methods("POST")
+	scene.HandleFunc("/save", sceneSave).Methods("POST")
+	scene.HandleFunc("/export", sceneExport).Methods("POST")
+
 	corsObj := handlers.AllowedOrigins([]string{"*"})
 
 	http.ListenAndServe(":8000", handlers.CORS(corsObj)(router))
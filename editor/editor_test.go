@@ -0,0 +1,23 @@
+package editor
+
+import "testing"
+
+func Test_validName(t *testing.T) {
+	tests := map[string]bool{
+		"main":         true,
+		"level.2":      true,
+		"":             false,
+		"..":           false,
+		"../main":      false,
+		"a/b":          false,
+		`a\b`:          false,
+		"/etc/passwd":  false,
+		"C:main":       false,
+		"main..backup": false,
+	}
+	for name, expected := range tests {
+		if got := validName(name); got != expected {
+			t.Errorf("wrong result for %q. got %v, expected %v", name, got, expected)
+		}
+	}
+}
@@ -0,0 +1,172 @@
+package animation
+
+import (
+	"encoding/json"
+	"math"
+
+	"github.com/patrick-jessen/goplay/engine/log"
+	"github.com/patrick-jessen/goplay/engine/scene"
+)
+
+func init() {
+	scene.RegisterComponent(&Animator{})
+}
+
+// playback is the state of a playing clip.
+type playback struct {
+	clip *Clip
+	time float32
+}
+
+// advance advances the playback by dt seconds.
+func (p *playback) advance(dt float32, loop bool) {
+	p.time += dt
+	if d := p.clip.Duration; loop && d > 0 {
+		p.time = float32(math.Mod(float64(p.time), float64(d)))
+		if p.time < 0 {
+			p.time += d
+		}
+	}
+}
+
+// Animator plays the animation clips of the model mounted onto its node.
+type Animator struct {
+	Clip   string  // The name of the clip to play. If empty, the first clip is played.
+	Loop   bool    // Whether clips loop.
+	Speed  float32 // Playback speed multiplier.
+	Paused bool    // Whether playback is paused.
+
+	clips    []*Clip
+	current  *playback
+	previous *playback // The clip being faded out.
+	fade     float32   // Duration of the fade in seconds.
+	faded    float32   // Time elapsed since the fade started.
+	node     *scene.Node
+}
+
+// UnmarshalJSON sets default values for Animator.
+func (a *Animator) UnmarshalJSON(d []byte) error {
+	type alias Animator
+	out := &alias{
+		Loop:  true,
+		Speed: 1,
+	}
+	e := json.Unmarshal(d, out)
+	*a = Animator(*out)
+	return e
+}
+
+// SetClips sets the clips available to the animator.
+// Usually called when a model is mounted.
+func (a *Animator) SetClips(clips []*Clip) {
+	a.clips = clips
+	a.previous = nil
+	a.Play(a.Clip)
+}
+
+// Clips returns the clips available to the animator.
+func (a *Animator) Clips() []*Clip {
+	return a.clips
+}
+
+// Play starts playing the clip with the given name from the beginning.
+// An empty name plays the first clip.
+func (a *Animator) Play(name string) {
+	a.Clip = name
+	a.current = nil
+	a.previous = nil
+
+	if c := a.find(name); c != nil {
+		a.current = &playback{clip: c}
+	}
+}
+
+// CrossFade starts playing the clip with the given name, while fading
+// out the current clip over the given duration in seconds.
+func (a *Animator) CrossFade(name string, duration float32) {
+	prev := a.current
+	a.Play(name)
+	if prev == nil || a.current == nil || duration <= 0 {
+		return
+	}
+	a.previous = prev
+	a.fade = duration
+	a.faded = 0
+}
+
+// Pause pauses playback.
+func (a *Animator) Pause() {
+	a.Paused = true
+}
+
+// Resume resumes paused playback.
+func (a *Animator) Resume() {
+	a.Paused = false
+}
+
+// Playing returns the name of the playing clip, or an empty string if
+// no clip is playing.
+func (a *Animator) Playing() string {
+	if a.current == nil {
+		return ""
+	}
+	return a.current.clip.Name
+}
+
+// Time returns the current time within the playing clip.
+func (a *Animator) Time() float32 {
+	if a.current == nil {
+		return 0
+	}
+	return a.current.time
+}
+
+// find returns the clip with the given name.
+// An empty name returns the first clip.
+func (a *Animator) find(name string) *Clip {
+	for _, c := range a.clips {
+		if c.Name == name || len(name) == 0 {
+			return c
+		}
+	}
+	if len(a.clips) > 0 {
+		log.Warn("animation clip not found", "clip", name)
+	}
+	return nil
+}
+
+func (a *Animator) Initialize(n *scene.Node) {
+	a.node = n
+}
+
+func (a *Animator) Update(dt float32) {
+	if a.current == nil {
+		return
+	}
+	if a.Paused {
+		dt = 0
+	}
+	a.step(dt * a.Speed)
+}
+
+// step advances playback by dt seconds and poses the nodes.
+func (a *Animator) step(dt float32) {
+	a.current.advance(dt, a.Loop)
+
+	if a.previous == nil {
+		a.current.clip.Apply(a.current.time)
+		return
+	}
+
+	a.faded += float32(math.Abs(float64(dt)))
+	if a.faded >= a.fade {
+		a.previous = nil
+		a.current.clip.Apply(a.current.time)
+		return
+	}
+	a.previous.advance(dt, a.Loop)
+	a.previous.clip.Apply(a.previous.time)
+	a.current.clip.Blend(a.current.time, a.faded/a.fade)
+}
+
+func (a *Animator) Render() {}
@@ -0,0 +1,138 @@
+package animation
+
+import (
+	"encoding/json"
+	"testing"
+
+	mgl "github.com/go-gl/mathgl/mgl32"
+	"github.com/patrick-jessen/goplay/engine/scene"
+)
+
+type testMorph struct {
+	weights []float32
+}
+
+func (m *testMorph) MorphWeights() []float32     { return m.weights }
+func (m *testMorph) SetMorphWeights(w []float32) { m.weights = w }
+
+// translationClip creates a clip moving n along the X axis from 0 to x in 1 second.
+func translationClip(name string, n *scene.Node, x float32) *Clip {
+	return &Clip{
+		Name:     name,
+		Duration: 1,
+		Channels: []Channel{{
+			Node: n,
+			Path: Translation,
+			Sampler: &Sampler{
+				Input:  []float32{0, 1},
+				Output: []float32{0, 0, 0, x, 0, 0},
+				Width:  3,
+			},
+		}},
+	}
+}
+
+func TestClip_Blend(t *testing.T) {
+	s := scene.New()
+	n := s.Root.NewChild("n")
+	n.SetPosition(mgl.Vec3{2, 0, 0})
+
+	c := translationClip("move", n, 10)
+	c.Blend(0.5, 0.5)
+	if p := n.Position(); !p.ApproxEqual(mgl.Vec3{3.5, 0, 0}) {
+		t.Errorf("wrong position. got %v, expected %v", p, mgl.Vec3{3.5, 0, 0})
+	}
+
+	c.Apply(1)
+	if p := n.Position(); !p.ApproxEqual(mgl.Vec3{10, 0, 0}) {
+		t.Errorf("wrong position. got %v, expected %v", p, mgl.Vec3{10, 0, 0})
+	}
+}
+
+func TestClip_Blend_weights(t *testing.T) {
+	m := &testMorph{weights: []float32{1, 0}}
+	c := &Clip{
+		Duration: 1,
+		Channels: []Channel{{
+			Path:  Weights,
+			Morph: m,
+			Sampler: &Sampler{
+				Input:  []float32{0, 1},
+				Output: []float32{0, 0, 0, 1},
+				Width:  2,
+			},
+		}},
+	}
+
+	c.Blend(1, 0.5)
+	expected := []float32{0.5, 0.5}
+	for i := range expected {
+		if !mgl.FloatEqual(m.weights[i], expected[i]) {
+			t.Fatalf("wrong weights. got %v, expected %v", m.weights, expected)
+		}
+	}
+}
+
+func TestAnimator_CrossFade(t *testing.T) {
+	s := scene.New()
+	n := s.Root.NewChild("n")
+
+	a := &Animator{Loop: true, Speed: 1}
+	a.SetClips([]*Clip{
+		translationClip("a", n, 10),
+		translationClip("b", n, -10),
+	})
+	if a.Playing() != "a" {
+		t.Fatalf("wrong clip playing. got %v, expected %v", a.Playing(), "a")
+	}
+
+	a.step(0.5)
+	a.CrossFade("b", 1)
+	if a.Playing() != "b" {
+		t.Fatalf("wrong clip playing. got %v, expected %v", a.Playing(), "b")
+	}
+
+	// A quarter through the fade, "a" is at 7.5 and "b" is at -2.5
+	a.step(0.25)
+	if p := n.Position(); !p.ApproxEqual(mgl.Vec3{5, 0, 0}) {
+		t.Errorf("wrong position. got %v, expected %v", p, mgl.Vec3{5, 0, 0})
+	}
+
+	// After the fade only "b" is applied
+	a.step(1)
+	if p := n.Position(); !p.ApproxEqual(mgl.Vec3{-2.5, 0, 0}) {
+		t.Errorf("wrong position. got %v, expected %v", p, mgl.Vec3{-2.5, 0, 0})
+	}
+}
+
+func TestAnimator_Pause(t *testing.T) {
+	s := scene.New()
+	n := s.Root.NewChild("n")
+
+	a := &Animator{Loop: true, Speed: 1}
+	a.SetClips([]*Clip{translationClip("a", n, 10)})
+	a.Pause()
+	a.Update(0.5)
+	a.Update(0.5)
+	if a.Time() != 0 {
+		t.Errorf("time advanced while paused. got %v", a.Time())
+	}
+}
+
+func TestAnimationPlayer(t *testing.T) {
+	p := &AnimationPlayer{}
+	if e := json.Unmarshal([]byte(`{"Clip": "a"}`), p); e != nil {
+		t.Fatal(e)
+	}
+	if p.Clip != "a" || !p.Loop || p.Speed != 1 {
+		t.Errorf("wrong defaults. got %+v", p.Animator)
+	}
+
+	s := scene.New()
+	n := s.Root.NewChild("n")
+	var c ClipSetter = p
+	c.SetClips([]*Clip{translationClip("a", n, 10)})
+	if p.Playing() != "a" {
+		t.Errorf("wrong clip. got %q, expected %q", p.Playing(), "a")
+	}
+}
@@ -0,0 +1,186 @@
+package animation
+
+import (
+	"fmt"
+
+	mgl "github.com/go-gl/mathgl/mgl32"
+	"github.com/patrick-jessen/goplay/engine/model/gltf"
+	"github.com/patrick-jessen/goplay/engine/scene"
+)
+
+// Path is the node property targeted by a channel.
+type Path int
+
+const (
+	Translation Path = iota
+	Rotation
+	Scale
+	Weights
+)
+
+// ParsePath parses a glTF target path.
+func ParsePath(s string) (Path, error) {
+	switch s {
+	case "translation":
+		return Translation, nil
+	case "rotation":
+		return Rotation, nil
+	case "scale":
+		return Scale, nil
+	case "weights":
+		return Weights, nil
+	default:
+		return Translation, fmt.Errorf("invalid path %v", s)
+	}
+}
+
+// Morphable is implemented by components which can be deformed by morph targets.
+type Morphable interface {
+	MorphWeights() []float32
+	SetMorphWeights([]float32)
+}
+
+// Channel animates a property of a node.
+type Channel struct {
+	Node    *scene.Node
+	Path    Path
+	Sampler *Sampler
+	Morph   Morphable // The target of a Weights channel.
+}
+
+// Clip is a named set of channels which are played together.
+type Clip struct {
+	Name     string
+	Channels []Channel
+	Duration float32
+}
+
+// Apply poses the nodes of the clip at time t.
+func (c *Clip) Apply(t float32) {
+	c.Blend(t, 1)
+}
+
+// Blend blends the pose of the clip at time t into the current pose
+// of its nodes. A weight of 0 leaves the nodes unchanged, while a
+// weight of 1 fully applies the clip.
+func (c *Clip) Blend(t, weight float32) {
+	var v mgl.Vec3
+	for _, ch := range c.Channels {
+		switch ch.Path {
+		case Translation:
+			ch.Sampler.Sample(t, v[:])
+			ch.Node.SetPosition(lerp(ch.Node.Position(), v, weight))
+		case Rotation:
+			ch.Node.SetRotation(slerp(ch.Node.Rotation(), ch.Sampler.SampleQuat(t), weight))
+		case Scale:
+			ch.Sampler.Sample(t, v[:])
+			ch.Node.SetScale(lerp(ch.Node.Scale(), v, weight))
+		case Weights:
+			cur := ch.Morph.MorphWeights()
+			w := make([]float32, ch.Sampler.Width)
+			ch.Sampler.Sample(t, w)
+			for i := range w {
+				if i < len(cur) {
+					w[i] = cur[i] + (w[i]-cur[i])*weight
+				} else {
+					w[i] *= weight
+				}
+			}
+			ch.Morph.SetMorphWeights(w)
+		}
+	}
+}
+
+// lerp linearly interpolates between two vectors.
+func lerp(a, b mgl.Vec3, f float32) mgl.Vec3 {
+	return a.Add(b.Sub(a).Mul(f))
+}
+
+// FromGlTF creates clips from the animations of a glTF file.
+// nodes maps glTF node indices to the scene nodes they are mounted onto.
+// Channels targeting unmounted nodes are ignored.
+func FromGlTF(g *gltf.File, nodes map[uint]*scene.Node) ([]*Clip, error) {
+	var clips []*Clip
+	for ai, a := range g.GlTF.Animations {
+		clip := &Clip{Name: a.Name}
+		if len(clip.Name) == 0 {
+			clip.Name = fmt.Sprint(ai)
+		}
+
+		samplers := make([]*Sampler, len(a.Samplers))
+		for si, s := range a.Samplers {
+			interp, e := ParseInterpolation(s.Interpolation)
+			if e != nil {
+				return nil, fmt.Errorf("animations[%v].samplers[%v]: %v", ai, si, e)
+			}
+			input, e := gltf.ReadFloats(g, s.Input)
+			if e != nil {
+				return nil, fmt.Errorf("animations[%v].samplers[%v].input: %v", ai, si, e)
+			}
+			output, e := gltf.ReadFloats(g, s.Output)
+			if e != nil {
+				return nil, fmt.Errorf("animations[%v].samplers[%v].output: %v", ai, si, e)
+			}
+			samplers[si] = &Sampler{
+				Input:         input,
+				Output:        output,
+				Interpolation: interp,
+			}
+		}
+
+		for ci, c := range a.Channels {
+			path, e := ParsePath(c.Target.Path)
+			if e != nil {
+				return nil, fmt.Errorf("animations[%v].channels[%v]: %v", ai, ci, e)
+			}
+			if c.Sampler >= uint(len(samplers)) {
+				return nil, fmt.Errorf("animations[%v].channels[%v]: sampler %v does not exist", ai, ci, c.Sampler)
+			}
+			node, ok := nodes[c.Target.Node]
+			if !ok {
+				continue
+			}
+			var morph Morphable
+			if path == Weights {
+				if morph, ok = node.Component("MeshRenderer").(Morphable); !ok {
+					continue
+				}
+			}
+
+			// Channels of different paths may share a sampler, so each
+			// channel gets a copy holding the width of its path
+			s := *samplers[c.Sampler]
+			s.Width = width(path, &s)
+			if s.Width == 0 || len(s.Output) < s.Width*len(s.Input)*s.valuesPerKeyframe() {
+				return nil, fmt.Errorf("animations[%v].samplers[%v]: too few output values", ai, c.Sampler)
+			}
+
+			clip.Channels = append(clip.Channels, Channel{
+				Node:    node,
+				Path:    path,
+				Sampler: &s,
+				Morph:   morph,
+			})
+			if d := s.Duration(); d > clip.Duration {
+				clip.Duration = d
+			}
+		}
+		clips = append(clips, clip)
+	}
+	return clips, nil
+}
+
+// width returns the number of floats in a value of the given path.
+func width(p Path, s *Sampler) int {
+	switch p {
+	case Translation, Scale:
+		return 3
+	case Rotation:
+		return 4
+	}
+	// Weights contain a value per morph target
+	if len(s.Input) == 0 {
+		return 0
+	}
+	return len(s.Output) / len(s.Input) / s.valuesPerKeyframe()
+}
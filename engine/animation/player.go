@@ -0,0 +1,21 @@
+package animation
+
+import "github.com/patrick-jessen/goplay/engine/scene"
+
+func init() {
+	scene.RegisterComponent(&AnimationPlayer{})
+}
+
+// ClipSetter is implemented by components which play the clips of the
+// model mounted onto their node.
+type ClipSetter interface {
+	SetClips(clips []*Clip)
+}
+
+// AnimationPlayer plays the animation clips of the model mounted onto its
+// node. It is kept such that scenes using it still load.
+//
+// Deprecated: Use Animator.
+type AnimationPlayer struct {
+	Animator
+}
@@ -0,0 +1,164 @@
+// Package animation implements keyframe animation of scene nodes.
+package animation
+
+import (
+	"fmt"
+	"sort"
+
+	mgl "github.com/go-gl/mathgl/mgl32"
+)
+
+// Interpolation is a keyframe interpolation algorithm.
+type Interpolation int
+
+const (
+	Linear Interpolation = iota
+	Step
+	CubicSpline
+)
+
+// ParseInterpolation parses a glTF interpolation name.
+func ParseInterpolation(s string) (Interpolation, error) {
+	switch s {
+	case "LINEAR":
+		return Linear, nil
+	case "STEP":
+		return Step, nil
+	case "CUBICSPLINE":
+		return CubicSpline, nil
+	default:
+		return Linear, fmt.Errorf("invalid interpolation %v", s)
+	}
+}
+
+// Sampler combines keyframe times and values with an interpolation algorithm.
+type Sampler struct {
+	Input         []float32 // Keyframe times in seconds.
+	Output        []float32 // Keyframe values.
+	Interpolation Interpolation
+	Width         int // The number of floats in a single value.
+}
+
+// Duration returns the time of the last keyframe.
+func (s *Sampler) Duration() float32 {
+	if len(s.Input) == 0 {
+		return 0
+	}
+	return s.Input[len(s.Input)-1]
+}
+
+// valuesPerKeyframe returns the number of values stored per keyframe.
+// Cubic splines store an in-tangent, a value and an out-tangent.
+func (s *Sampler) valuesPerKeyframe() int {
+	if s.Interpolation == CubicSpline {
+		return 3
+	}
+	return 1
+}
+
+// value returns the value of keyframe k.
+// For cubic splines, the tangents are skipped.
+func (s *Sampler) value(k int) []float32 {
+	if s.Interpolation == CubicSpline {
+		off := (3*k + 1) * s.Width
+		return s.Output[off : off+s.Width]
+	}
+	return s.Output[k*s.Width : (k+1)*s.Width]
+}
+
+// inTangent returns the in-tangent of keyframe k of a cubic spline.
+func (s *Sampler) inTangent(k int) []float32 {
+	off := 3 * k * s.Width
+	return s.Output[off : off+s.Width]
+}
+
+// outTangent returns the out-tangent of keyframe k of a cubic spline.
+func (s *Sampler) outTangent(k int) []float32 {
+	off := (3*k + 2) * s.Width
+	return s.Output[off : off+s.Width]
+}
+
+// keyframe finds the keyframe preceding time t, and the normalized time
+// between it and the following keyframe.
+// If t is outside the keyframes, the first or last keyframe is returned with
+// an interpolation factor of 0.
+func (s *Sampler) keyframe(t float32) (k int, f float32) {
+	n := len(s.Input)
+	if n == 0 || t <= s.Input[0] {
+		return 0, 0
+	}
+	if t >= s.Input[n-1] {
+		return n - 1, 0
+	}
+
+	// Index of the first keyframe after t
+	next := sort.Search(n, func(i int) bool { return s.Input[i] > t })
+	k = next - 1
+	f = (t - s.Input[k]) / (s.Input[next] - s.Input[k])
+	return
+}
+
+// Sample evaluates the sampler at time t and stores the value in dst.
+// dst must have a length of Width.
+func (s *Sampler) Sample(t float32, dst []float32) {
+	if len(s.Input) == 0 {
+		return
+	}
+	k, f := s.keyframe(t)
+
+	switch {
+	case f == 0 || s.Interpolation == Step:
+		copy(dst, s.value(k))
+
+	case s.Interpolation == Linear:
+		v0, v1 := s.value(k), s.value(k+1)
+		for i := range dst {
+			dst[i] = v0[i] + (v1[i]-v0[i])*f
+		}
+
+	case s.Interpolation == CubicSpline:
+		dt := s.Input[k+1] - s.Input[k]
+		v0, b0 := s.value(k), s.outTangent(k)
+		v1, a1 := s.value(k+1), s.inTangent(k+1)
+
+		f2 := f * f
+		f3 := f2 * f
+		h00 := 2*f3 - 3*f2 + 1
+		h10 := f3 - 2*f2 + f
+		h01 := -2*f3 + 3*f2
+		h11 := f3 - f2
+		for i := range dst {
+			dst[i] = h00*v0[i] + h10*dt*b0[i] + h01*v1[i] + h11*dt*a1[i]
+		}
+	}
+}
+
+// SampleQuat evaluates a rotation sampler at time t.
+// Linear interpolation uses spherical linear interpolation, as mandated by glTF.
+func (s *Sampler) SampleQuat(t float32) mgl.Quat {
+	if len(s.Input) == 0 {
+		return mgl.QuatIdent()
+	}
+
+	var v [4]float32
+	k, f := s.keyframe(t)
+	if s.Interpolation == Linear && f != 0 {
+		return slerp(quat(s.value(k)), quat(s.value(k+1)), f)
+	}
+
+	s.Sample(t, v[:])
+	return quat(v[:]).Normalize()
+}
+
+// slerp spherically interpolates between two rotations along the shortest path.
+func slerp(q0, q1 mgl.Quat, f float32) mgl.Quat {
+	if q0.Dot(q1) < 0 {
+		q1 = q1.Scale(-1)
+	}
+	return mgl.QuatSlerp(q0, q1, f).Normalize()
+}
+
+// quat converts a glTF quaternion [x, y, z, w] to a mgl.Quat.
+func quat(v []float32) mgl.Quat {
+	return mgl.Quat{W: v[3], V: mgl.Vec3{v[0], v[1], v[2]}}
+}
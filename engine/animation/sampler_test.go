@@ -0,0 +1,85 @@
+package animation
+
+import (
+	"testing"
+
+	mgl "github.com/go-gl/mathgl/mgl32"
+)
+
+func TestSampler_Sample(t *testing.T) {
+	s := Sampler{
+		Input:  []float32{0, 1, 3},
+		Output: []float32{0, 10, 30},
+		Width:  1,
+	}
+	tests := []struct {
+		interp   Interpolation
+		t        float32
+		expected float32
+	}{
+		{Linear, -1, 0},
+		{Linear, 0.5, 5},
+		{Linear, 2, 20},
+		{Linear, 5, 30},
+		{Step, 0.5, 0},
+		{Step, 2.9, 10},
+		{Step, 3, 30},
+	}
+
+	var v [1]float32
+	for _, test := range tests {
+		s.Interpolation = test.interp
+		s.Sample(test.t, v[:])
+		if !mgl.FloatEqual(v[0], test.expected) {
+			t.Errorf("wrong value for interpolation %v at %v. got %v, expected %v",
+				test.interp, test.t, v[0], test.expected)
+		}
+	}
+}
+
+func TestSampler_Sample_cubicSpline(t *testing.T) {
+	// Keyframes: [inTangent, value, outTangent]
+	s := Sampler{
+		Input:         []float32{0, 2},
+		Output:        []float32{0, 0, 1, 1, 2, 0},
+		Interpolation: CubicSpline,
+		Width:         1,
+	}
+
+	var v [1]float32
+	s.Sample(0, v[:])
+	if v[0] != 0 {
+		t.Errorf("wrong value at first keyframe. got %v, expected %v", v[0], 0)
+	}
+	s.Sample(2, v[:])
+	if v[0] != 2 {
+		t.Errorf("wrong value at last keyframe. got %v, expected %v", v[0], 2)
+	}
+	// Tangents of 1 and a slope of 1 yield a straight line
+	s.Sample(0.5, v[:])
+	if !mgl.FloatEqual(v[0], 0.5) {
+		t.Errorf("wrong interpolated value. got %v, expected %v", v[0], 0.5)
+	}
+}
+
+func TestSampler_SampleQuat(t *testing.T) {
+	q0 := mgl.QuatIdent()
+	q1 := mgl.QuatRotate(mgl.DegToRad(90), mgl.Vec3{0, 1, 0})
+	// glTF stores quaternions as [x, y, z, w]. q1 is negated to test
+	// that the shortest path is taken.
+	s := Sampler{
+		Input: []float32{0, 1},
+		Output: []float32{
+			q0.V[0], q0.V[1], q0.V[2], q0.W,
+			-q1.V[0], -q1.V[1], -q1.V[2], -q1.W,
+		},
+		Interpolation: Linear,
+		Width:         4,
+	}
+
+	expected := mgl.QuatRotate(mgl.DegToRad(45), mgl.Vec3{0, 1, 0})
+	got := s.SampleQuat(0.5)
+	if !got.OrientationEqualThreshold(expected, 1e-4) {
+		t.Errorf("wrong rotation. got %v, expected %v", got, expected)
+	}
+}
@@ -0,0 +1,23 @@
+// Package asset contains functionality shared by the asset loaders.
+package asset
+
+// Error describes a failure to load an asset.
+type Error struct {
+	File  string // Path to the asset.
+	Field string // The offending field or chunk. Empty if unknown.
+	Err   error  // The underlying error.
+}
+
+// Error returns the error message.
+func (e *Error) Error() string {
+	msg := e.File
+	if len(e.Field) > 0 {
+		msg += ": " + e.Field
+	}
+	return msg + ": " + e.Err.Error()
+}
+
+// Unwrap returns the underlying error.
+func (e *Error) Unwrap() error {
+	return e.Err
+}
@@ -0,0 +1,147 @@
+// Package bounds implements bounding volumes used for visibility tests.
+package bounds
+
+import (
+	"math"
+
+	mgl "github.com/go-gl/mathgl/mgl32"
+)
+
+// AABB is an axis-aligned bounding box.
+// A box is empty if Min is greater than Max on any axis.
+type AABB struct {
+	Min, Max mgl.Vec3
+}
+
+// Empty returns a box containing nothing.
+func Empty() AABB {
+	inf := float32(math.Inf(1))
+	return AABB{
+		Min: mgl.Vec3{inf, inf, inf},
+		Max: mgl.Vec3{-inf, -inf, -inf},
+	}
+}
+
+// Infinite returns a box containing everything. It is used for objects
+// whose extent is unknown, such that they are never culled.
+func Infinite() AABB {
+	inf := float32(math.Inf(1))
+	return AABB{
+		Min: mgl.Vec3{-inf, -inf, -inf},
+		Max: mgl.Vec3{inf, inf, inf},
+	}
+}
+
+// IsEmpty returns whether the box contains nothing.
+func (b AABB) IsEmpty() bool {
+	return b.Min[0] > b.Max[0] || b.Min[1] > b.Max[1] || b.Min[2] > b.Max[2]
+}
+
+// IsInfinite returns whether the box is unbounded on any axis.
+func (b AABB) IsInfinite() bool {
+	for i := 0; i < 3; i++ {
+		if math.IsInf(float64(b.Min[i]), -1) || math.IsInf(float64(b.Max[i]), 1) {
+			return true
+		}
+	}
+	return false
+}
+
+// Center returns the center of the box.
+func (b AABB) Center() mgl.Vec3 {
+	return b.Min.Add(b.Max).Mul(0.5)
+}
+
+// Extend returns the box grown to contain p.
+func (b AABB) Extend(p mgl.Vec3) AABB {
+	for i := 0; i < 3; i++ {
+		b.Min[i] = float32(math.Min(float64(b.Min[i]), float64(p[i])))
+		b.Max[i] = float32(math.Max(float64(b.Max[i]), float64(p[i])))
+	}
+	return b
+}
+
+// Union returns the smallest box containing both b and o.
+func (b AABB) Union(o AABB) AABB {
+	if o.IsEmpty() {
+		return b
+	}
+	if b.IsEmpty() {
+		return o
+	}
+	return b.Extend(o.Min).Extend(o.Max)
+}
+
+// Transform returns the smallest axis-aligned box containing b
+// transformed by m.
+func (b AABB) Transform(m mgl.Mat4) AABB {
+	if b.IsEmpty() || b.IsInfinite() {
+		return b
+	}
+	// Each axis of the result is the translation plus the extremes of
+	// the rotated and scaled extents (Arvo, Graphics Gems 1990).
+	out := AABB{Min: m.Col(3).Vec3(), Max: m.Col(3).Vec3()}
+	for i := 0; i < 3; i++ {
+		for j := 0; j < 3; j++ {
+			e := m.At(i, j) * b.Min[j]
+			f := m.At(i, j) * b.Max[j]
+			if e < f {
+				out.Min[i] += e
+				out.Max[i] += f
+			} else {
+				out.Min[i] += f
+				out.Max[i] += e
+			}
+		}
+	}
+	return out
+}
+
+// Frustum is a convex volume bounded by six planes. Each plane is
+// given by (a, b, c, d), where points with ax+by+cz+d >= 0 are inside.
+type Frustum [6]mgl.Vec4
+
+// FrustumFromMatrix returns the frustum of a view-projection matrix.
+// Its planes are extracted as described by Gribb and Hartmann.
+func FrustumFromMatrix(m mgl.Mat4) Frustum {
+	r0, r1, r2, r3 := m.Row(0), m.Row(1), m.Row(2), m.Row(3)
+	f := Frustum{
+		r3.Add(r0), // Left
+		r3.Sub(r0), // Right
+		r3.Add(r1), // Bottom
+		r3.Sub(r1), // Top
+		r3.Add(r2), // Near
+		r3.Sub(r2), // Far
+	}
+	for i, p := range f {
+		f[i] = p.Mul(1 / p.Vec3().Len())
+	}
+	return f
+}
+
+// Intersects returns whether b is at least partially inside the frustum.
+// The test is conservative: boxes near the corners of the frustum may
+// be reported as intersecting even if they are outside.
+func (f *Frustum) Intersects(b AABB) bool {
+	if b.IsEmpty() {
+		return false
+	}
+	if b.IsInfinite() {
+		return true
+	}
+	for _, p := range f {
+		// The corner furthest along the plane normal
+		var v mgl.Vec3
+		for i := 0; i < 3; i++ {
+			if p[i] >= 0 {
+				v[i] = b.Max[i]
+			} else {
+				v[i] = b.Min[i]
+			}
+		}
+		if p.Vec3().Dot(v)+p[3] < 0 {
+			return false
+		}
+	}
+	return true
+}
@@ -0,0 +1,64 @@
+package bounds
+
+import (
+	"testing"
+
+	mgl "github.com/go-gl/mathgl/mgl32"
+)
+
+func TestAABB_Union(t *testing.T) {
+	a := AABB{Min: mgl.Vec3{0, 0, 0}, Max: mgl.Vec3{1, 1, 1}}
+	b := AABB{Min: mgl.Vec3{-1, 0.5, 0}, Max: mgl.Vec3{0, 2, 0.5}}
+
+	expected := AABB{Min: mgl.Vec3{-1, 0, 0}, Max: mgl.Vec3{1, 2, 1}}
+	if u := a.Union(b); u != expected {
+		t.Errorf("wrong union. got %v, expected %v", u, expected)
+	}
+	if u := Empty().Union(a); u != a {
+		t.Errorf("wrong union with empty box. got %v, expected %v", u, a)
+	}
+	if u := a.Union(Infinite()); !u.IsInfinite() {
+		t.Errorf("union with infinite box should be infinite. got %v", u)
+	}
+}
+
+func TestAABB_Transform(t *testing.T) {
+	b := AABB{Min: mgl.Vec3{-1, -1, -1}, Max: mgl.Vec3{1, 1, 1}}
+	m := mgl.Translate3D(10, 0, 0).Mul4(mgl.HomogRotate3DZ(mgl.DegToRad(45))).Mul4(mgl.Scale3D(2, 1, 1))
+
+	out := b.Transform(m)
+	// The corners (±2, ±1) rotated by 45 degrees reach 3/sqrt(2) on x and y
+	e := float32(3 / 1.41421356)
+	expected := AABB{Min: mgl.Vec3{10 - e, -e, -1}, Max: mgl.Vec3{10 + e, e, 1}}
+	if !out.Min.ApproxEqualThreshold(expected.Min, 1e-4) || !out.Max.ApproxEqualThreshold(expected.Max, 1e-4) {
+		t.Errorf("wrong box. got %v, expected %v", out, expected)
+	}
+}
+
+func TestFrustum_Intersects(t *testing.T) {
+	proj := mgl.Perspective(mgl.DegToRad(90), 1, 0.1, 100)
+	view := mgl.LookAtV(mgl.Vec3{}, mgl.Vec3{0, 0, -1}, mgl.Vec3{0, 1, 0})
+	f := FrustumFromMatrix(proj.Mul4(view))
+
+	box := func(x, y, z float32) AABB {
+		c := mgl.Vec3{x, y, z}
+		return AABB{Min: c.Sub(mgl.Vec3{1, 1, 1}), Max: c.Add(mgl.Vec3{1, 1, 1})}
+	}
+	tests := []struct {
+		box      AABB
+		expected bool
+	}{
+		{box(0, 0, -10), true},   // In front
+		{box(0, 0, 10), false},   // Behind
+		{box(20, 0, -10), false}, // Right of
+		{box(10, 0, -10), true},  // On the edge
+		{box(0, 0, -200), false}, // Beyond far plane
+		{Empty(), false},
+		{Infinite(), true},
+	}
+	for i, test := range tests {
+		if got := f.Intersects(test.box); got != test.expected {
+			t.Errorf("test %v: got %v, expected %v", i, got, test.expected)
+		}
+	}
+}
@@ -0,0 +1,150 @@
+// Package clock keeps track of game time.
+//
+// Each frame the clock is advanced by the real time elapsed, scaled by
+// the time scale. Simulation is stepped in fixed increments of game time,
+// such that it behaves the same at any frame rate. Frame dependent work is
+// given the variable delta time of the frame, and may interpolate between
+// the last two fixed steps using Alpha.
+package clock
+
+import (
+	"time"
+)
+
+// DefaultFixedDelta is the default duration of a fixed step in seconds.
+const DefaultFixedDelta = 1.0 / 60
+
+// maxDelta limits the game time of a single frame, such that a long stall
+// (e.g. loading or a breakpoint) does not require simulating many steps.
+const maxDelta = 0.25
+
+var (
+	fixedDelta  = float32(DefaultFixedDelta)
+	scale       = float32(1)
+	paused      bool
+	delta       float32 // Scaled duration of the last frame.
+	realDelta   float32 // Unscaled duration of the last frame.
+	accumulator float32 // Game time not yet simulated by fixed steps.
+	elapsed     float64 // Game time since start.
+	frame       uint64
+	fixedFrame  uint64
+	last        time.Time
+)
+
+// Tick advances the clock by the real time elapsed since the last tick.
+// Returns the number of fixed steps to simulate in this frame.
+func Tick() int {
+	now := time.Now()
+	var dt float32
+	if !last.IsZero() {
+		dt = float32(now.Sub(last).Seconds())
+	}
+	last = now
+	return Advance(dt)
+}
+
+// Advance advances the clock by dt seconds of real time.
+// Returns the number of fixed steps to simulate in this frame.
+// Tick should be used in most cases, whereas Advance is useful for
+// deterministic playback, e.g. when rendering offscreen.
+func Advance(dt float32) int {
+	if dt > maxDelta {
+		dt = maxDelta
+	}
+	realDelta = dt
+	frame++
+
+	delta = dt * scale
+	if paused {
+		delta = 0
+	}
+	elapsed += float64(delta)
+
+	accumulator += delta
+	steps := 0
+	for accumulator >= fixedDelta {
+		accumulator -= fixedDelta
+		steps++
+	}
+	fixedFrame += uint64(steps)
+	return steps
+}
+
+// Reset resets the clock to its initial state.
+// Time scale, pause and the fixed delta are kept.
+func Reset() {
+	delta, realDelta, accumulator, elapsed = 0, 0, 0, 0
+	frame, fixedFrame = 0, 0
+	last = time.Time{}
+}
+
+// Delta returns the game time in seconds of the current frame.
+// It is 0 while paused.
+func Delta() float32 {
+	return delta
+}
+
+// RealDelta returns the real time in seconds of the current frame,
+// unaffected by time scale and pause.
+func RealDelta() float32 {
+	return realDelta
+}
+
+// FixedDelta returns the duration of a fixed step in seconds.
+func FixedDelta() float32 {
+	return fixedDelta
+}
+
+// SetFixedDelta sets the duration of a fixed step in seconds.
+func SetFixedDelta(dt float32) {
+	if dt > 0 {
+		fixedDelta = dt
+	}
+}
+
+// Alpha returns how far the current frame is between the last fixed step
+// and the next, in the range [0;1). It is used to interpolate state which
+// is simulated in fixed steps.
+func Alpha() float32 {
+	return accumulator / fixedDelta
+}
+
+// Time returns the game time in seconds since start.
+func Time() float64 {
+	return elapsed
+}
+
+// Scale returns the time scale.
+func Scale() float32 {
+	return scale
+}
+
+// SetScale sets the time scale, e.g. 0.5 for slow motion.
+// Negative scales are treated as 0.
+func SetScale(s float32) {
+	if s < 0 {
+		s = 0
+	}
+	scale = s
+}
+
+// Paused returns whether game time is paused.
+func Paused() bool {
+	return paused
+}
+
+// SetPaused sets whether game time is paused. While paused, the delta time
+// is 0 and no fixed steps are simulated.
+func SetPaused(p bool) {
+	paused = p
+}
+
+// Frame returns the number of frames since start.
+func Frame() uint64 {
+	return frame
+}
+
+// FixedFrame returns the number of fixed steps since start.
+func FixedFrame() uint64 {
+	return fixedFrame
+}
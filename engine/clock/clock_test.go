@@ -0,0 +1,48 @@
+package clock
+
+import (
+	"testing"
+)
+
+func TestAdvance(t *testing.T) {
+	Reset()
+	SetFixedDelta(0.125)
+	defer SetFixedDelta(DefaultFixedDelta)
+	defer SetScale(1)
+	defer SetPaused(false)
+
+	tests := []struct {
+		dt     float32
+		scale  float32
+		paused bool
+		steps  int
+		delta  float32
+	}{
+		{0.0625, 1, false, 0, 0.0625},
+		{0.125, 1, false, 1, 0.125},
+		{0.25, 1, false, 2, 0.25},
+		{1, 1, false, 2, 0.25}, // Long frames are limited
+		{0.125, 1, true, 0, 0},
+		{0.0625, 2, false, 1, 0.125},
+	}
+	for i, test := range tests {
+		SetScale(test.scale)
+		SetPaused(test.paused)
+		steps := Advance(test.dt)
+		if steps != test.steps {
+			t.Errorf("test %v: wrong number of steps. got %v, expected %v", i, steps, test.steps)
+		}
+		if Delta() != test.delta {
+			t.Errorf("test %v: wrong delta. got %v, expected %v", i, Delta(), test.delta)
+		}
+		if Alpha() != 0.5 {
+			t.Errorf("test %v: wrong alpha. got %v, expected %v", i, Alpha(), 0.5)
+		}
+	}
+	if Frame() != 6 || FixedFrame() != 6 {
+		t.Errorf("wrong frame counters. got %v and %v", Frame(), FixedFrame())
+	}
+	if Time() != 0.8125 {
+		t.Errorf("wrong time. got %v, expected %v", Time(), 0.8125)
+	}
+}
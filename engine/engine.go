@@ -3,9 +3,12 @@
 package engine
 
 import (
+	"time"
+
 	// Include components
 	_ "github.com/patrick-jessen/goplay/components"
 	"github.com/patrick-jessen/goplay/editor"
+	"github.com/patrick-jessen/goplay/engine/clock"
 	"github.com/patrick-jessen/goplay/engine/renderer"
 	"github.com/patrick-jessen/goplay/engine/resource"
 	"github.com/patrick-jessen/goplay/engine/scene"
@@ -13,6 +16,10 @@ import (
 	"github.com/patrick-jessen/goplay/engine/worker"
 )
 
+// workBudget is the time spent per frame running work scheduled for the
+// main thread, such as texture uploads.
+const workBudget = 4 * time.Millisecond
+
 // Start starts the engine using the given application.
 func Start() {
 	go editor.Start()
@@ -24,18 +31,22 @@ func Start() {
 	defer renderer.Deinitialize()
 
 	resource.LoadScene("main").MakeCurrent()
+	clock.Reset()
 
 	for !window.ShouldClose() {
 		window.Update()
-		scene.Current().Update()
+		update(clock.Tick())
 		renderer.Render()
+		worker.Run(workBudget)
+	}
+}
 
-		select {
-		case ef := <-editor.Channel:
-			ef()
-		case work := <-worker.Channel:
-			work()
-		default:
-		}
+// update simulates the given number of fixed steps of the current scene,
+// and updates it for the frame.
+func update(steps int) {
+	sc := scene.Current()
+	for i := 0; i < steps; i++ {
+		sc.FixedUpdate(clock.FixedDelta())
 	}
+	sc.Update(clock.Delta())
 }
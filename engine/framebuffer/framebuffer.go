@@ -1,6 +1,8 @@
 package framebuffer
 
 import (
+	"image"
+
 	"github.com/go-gl/gl/v3.2-core/gl"
 	"github.com/patrick-jessen/goplay/engine/log"
 )
@@ -13,6 +15,7 @@ type FrameBuffer struct {
 	handle        uint32
 	color         []uint32
 	depth         uint32
+	depthTarget   uint32 // The texture target of the depth texture.
 	width, height int32
 }
 
@@ -42,6 +45,15 @@ func New(width int, height int, numCols int, msLevel int) *FrameBuffer {
 		}
 	}
 
+	// Render to all color attachments
+	if numCols > 1 {
+		bufs := make([]uint32, numCols)
+		for i := range bufs {
+			bufs[i] = gl.COLOR_ATTACHMENT0 + uint32(i)
+		}
+		gl.DrawBuffers(int32(numCols), &bufs[0])
+	}
+
 	var depth uint32
 	gl.GenTextures(1, &depth)
 	if msLevel == 0 {
@@ -65,12 +77,84 @@ func New(width int, height int, numCols int, msLevel int) *FrameBuffer {
 	gl.BindFramebuffer(gl.FRAMEBUFFER, 0)
 
 	return &FrameBuffer{
-		handle: fbo,
-		color:  colors,
-		depth:  depth,
-		width:  w,
-		height: h,
+		handle:      fbo,
+		color:       colors,
+		depth:       depth,
+		depthTarget: gl.TEXTURE_2D,
+		width:       w,
+		height:      h,
+	}
+}
+
+// NewDepth creates a depth-only frame buffer for shadow mapping.
+// The depth texture is set up for depth comparison.
+func NewDepth(size int) *FrameBuffer {
+	fbo := newDepth(size, gl.TEXTURE_2D)
+	gl.TexImage2D(gl.TEXTURE_2D, 0, gl.DEPTH_COMPONENT24, fbo.width, fbo.height, 0, gl.DEPTH_COMPONENT, gl.FLOAT, nil)
+	gl.TexParameteri(gl.TEXTURE_2D, gl.TEXTURE_WRAP_S, gl.CLAMP_TO_BORDER)
+	gl.TexParameteri(gl.TEXTURE_2D, gl.TEXTURE_WRAP_T, gl.CLAMP_TO_BORDER)
+	border := [4]float32{1, 1, 1, 1}
+	gl.TexParameterfv(gl.TEXTURE_2D, gl.TEXTURE_BORDER_COLOR, &border[0])
+	gl.FramebufferTexture2D(gl.FRAMEBUFFER, gl.DEPTH_ATTACHMENT, gl.TEXTURE_2D, fbo.depth, 0)
+	return fbo.complete()
+}
+
+// NewDepthCube creates a depth-only frame buffer with a cube map for
+// shadow mapping. Use BindFace to select the face to render to.
+func NewDepthCube(size int) *FrameBuffer {
+	fbo := newDepth(size, gl.TEXTURE_CUBE_MAP)
+	for i := uint32(0); i < 6; i++ {
+		gl.TexImage2D(gl.TEXTURE_CUBE_MAP_POSITIVE_X+i, 0, gl.DEPTH_COMPONENT24, fbo.width, fbo.height, 0, gl.DEPTH_COMPONENT, gl.FLOAT, nil)
+	}
+	gl.TexParameteri(gl.TEXTURE_CUBE_MAP, gl.TEXTURE_WRAP_S, gl.CLAMP_TO_EDGE)
+	gl.TexParameteri(gl.TEXTURE_CUBE_MAP, gl.TEXTURE_WRAP_T, gl.CLAMP_TO_EDGE)
+	gl.TexParameteri(gl.TEXTURE_CUBE_MAP, gl.TEXTURE_WRAP_R, gl.CLAMP_TO_EDGE)
+	gl.FramebufferTexture2D(gl.FRAMEBUFFER, gl.DEPTH_ATTACHMENT, gl.TEXTURE_CUBE_MAP_POSITIVE_X, fbo.depth, 0)
+	return fbo.complete()
+}
+
+// newDepth creates a depth-only frame buffer and a depth texture, which
+// is left bound to target. The texture must be allocated and attached.
+func newDepth(size int, target uint32) *FrameBuffer {
+	fbo := &FrameBuffer{
+		depthTarget: target,
+		width:       int32(size),
+		height:      int32(size),
+	}
+	gl.GenFramebuffers(1, &fbo.handle)
+	gl.BindFramebuffer(gl.FRAMEBUFFER, fbo.handle)
+	gl.DrawBuffer(gl.NONE)
+	gl.ReadBuffer(gl.NONE)
+
+	gl.GenTextures(1, &fbo.depth)
+	gl.BindTexture(target, fbo.depth)
+	gl.TexParameteri(target, gl.TEXTURE_MIN_FILTER, gl.LINEAR)
+	gl.TexParameteri(target, gl.TEXTURE_MAG_FILTER, gl.LINEAR)
+	gl.TexParameteri(target, gl.TEXTURE_COMPARE_MODE, gl.COMPARE_REF_TO_TEXTURE)
+	gl.TexParameteri(target, gl.TEXTURE_COMPARE_FUNC, gl.LEQUAL)
+	return fbo
+}
+
+// complete checks that the frame buffer is complete and unbinds it.
+func (fbo *FrameBuffer) complete() *FrameBuffer {
+	gl.BindTexture(fbo.depthTarget, 0)
+	if gl.CheckFramebufferStatus(gl.FRAMEBUFFER) != gl.FRAMEBUFFER_COMPLETE {
+		log.Panic("framebuffer not complete", "result", gl.CheckFramebufferStatus(gl.FRAMEBUFFER))
 	}
+	gl.BindFramebuffer(gl.FRAMEBUFFER, 0)
+	return fbo
+}
+
+// BindFace binds the frame buffer for rendering to the given face of
+// its cube map.
+func (fbo *FrameBuffer) BindFace(face int) {
+	fbo.Bind()
+	gl.FramebufferTexture2D(gl.FRAMEBUFFER, gl.DEPTH_ATTACHMENT, gl.TEXTURE_CUBE_MAP_POSITIVE_X+uint32(face), fbo.depth, 0)
+}
+
+// Size returns the size of the frame buffer.
+func (fbo *FrameBuffer) Size() (int, int) {
+	return int(fbo.width), int(fbo.height)
 }
 
 func (fbo *FrameBuffer) Bind() {
@@ -78,7 +162,7 @@ func (fbo *FrameBuffer) Bind() {
 }
 func (fbo *FrameBuffer) BindDepthTexture(target int) {
 	gl.ActiveTexture(gl.TEXTURE0 + uint32(target))
-	gl.BindTexture(gl.TEXTURE_2D, fbo.depth)
+	gl.BindTexture(fbo.depthTarget, fbo.depth)
 }
 func (fbo *FrameBuffer) BindColorTexture(idx int, target int) {
 	gl.ActiveTexture(gl.TEXTURE0 + uint32(target))
@@ -98,6 +182,30 @@ func (fbo *FrameBuffer) Blit(dst *FrameBuffer, w, h int, depth bool) {
 	}
 	gl.BlitFramebuffer(0, 0, fbo.width, fbo.height, 0, 0, fbo.width, fbo.height, mask, gl.NEAREST)
 }
+
+// ReadColor reads back the given color attachment.
+// Only works for frame buffers without multisampling.
+func (fbo *FrameBuffer) ReadColor(idx int) *image.RGBA {
+	w, h := int(fbo.width), int(fbo.height)
+	img := image.NewRGBA(image.Rect(0, 0, w, h))
+
+	gl.BindFramebuffer(gl.READ_FRAMEBUFFER, fbo.handle)
+	gl.ReadBuffer(gl.COLOR_ATTACHMENT0 + uint32(idx))
+	gl.PixelStorei(gl.PACK_ALIGNMENT, 1)
+	gl.ReadPixels(0, 0, fbo.width, fbo.height, gl.RGBA, gl.UNSIGNED_BYTE, gl.Ptr(img.Pix))
+	gl.BindFramebuffer(gl.READ_FRAMEBUFFER, 0)
+
+	// OpenGL stores rows bottom-up
+	row := make([]byte, img.Stride)
+	for y := 0; y < h/2; y++ {
+		top := img.Pix[y*img.Stride : (y+1)*img.Stride]
+		bottom := img.Pix[(h-1-y)*img.Stride : (h-y)*img.Stride]
+		copy(row, top)
+		copy(top, bottom)
+		copy(bottom, row)
+	}
+	return img
+}
 func (fbo *FrameBuffer) Free() {
 	for _, v := range fbo.color {
 		gl.DeleteTextures(1, &v)
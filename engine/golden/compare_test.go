@@ -0,0 +1,63 @@
+package golden
+
+import (
+	"image"
+	"image/color"
+	"testing"
+)
+
+func uniform(w, h int, c color.RGBA) *image.RGBA {
+	img := image.NewRGBA(image.Rect(0, 0, w, h))
+	for i := 0; i < len(img.Pix); i += 4 {
+		img.Pix[i], img.Pix[i+1], img.Pix[i+2], img.Pix[i+3] = c.R, c.G, c.B, c.A
+	}
+	return img
+}
+
+func TestCompare(t *testing.T) {
+	opts := Options{Tolerance: 2, Threshold: 0.1}
+	ref := uniform(4, 4, color.RGBA{100, 100, 100, 255})
+
+	// Identical
+	res := Compare(uniform(4, 4, color.RGBA{100, 100, 100, 255}), ref, opts)
+	if res.Mismatched != 0 || res.Total != 16 {
+		t.Errorf("identical images mismatch. got %v of %v", res.Mismatched, res.Total)
+	}
+
+	// Within tolerance
+	res = Compare(uniform(4, 4, color.RGBA{102, 98, 100, 255}), ref, opts)
+	if res.Mismatched != 0 {
+		t.Errorf("images within tolerance mismatch. got %v", res.Mismatched)
+	}
+
+	// Perceptually similar
+	res = Compare(uniform(4, 4, color.RGBA{105, 105, 105, 255}), ref, opts)
+	if res.Mismatched != 0 {
+		t.Errorf("perceptually similar images mismatch. got %v", res.Mismatched)
+	}
+
+	// Single differing pixel
+	img := uniform(4, 4, color.RGBA{100, 100, 100, 255})
+	img.SetRGBA(1, 2, color.RGBA{255, 0, 0, 255})
+	res = Compare(img, ref, opts)
+	if res.Mismatched != 1 {
+		t.Errorf("wrong number of mismatches. got %v, expected %v", res.Mismatched, 1)
+	}
+	if res.Diff.RGBAAt(1, 2) != (color.RGBA{255, 0, 0, 255}) {
+		t.Errorf("mismatch not marked in diff. got %v", res.Diff.RGBAAt(1, 2))
+	}
+	if res.OK(opts) {
+		t.Error("result is OK despite mismatch")
+	}
+	opts.MaxMismatch = 0.1
+	if !res.OK(opts) {
+		t.Error("result is not OK despite being below max mismatch")
+	}
+
+	// Different size
+	res = Compare(uniform(2, 2, color.RGBA{100, 100, 100, 255}), ref, opts)
+	if res.Mismatched != res.Total {
+		t.Errorf("differently sized images should mismatch entirely. got %v of %v",
+			res.Mismatched, res.Total)
+	}
+}
@@ -0,0 +1,143 @@
+// Package golden compares rendered images against reference images.
+// The render regression tests of the package render scenes offscreen and
+// compare them against the references stored in testdata/.
+//
+// On machines without a GPU, the tests can be run using Mesa's software
+// rasterizer, e.g.:
+//
+//	LIBGL_ALWAYS_SOFTWARE=1 xvfb-run go test ./engine/golden
+//
+// Reference images are (re)created by passing -update. A scene without a
+// reference image fails the test. Renderer and texture settings are
+// pinned by the test, such that changing their defaults does not change
+// the images.
+package golden
+
+import (
+	"image"
+	"image/color"
+	"image/png"
+	"os"
+)
+
+// maxDelta is the largest possible perceptual delta between two colors.
+const maxDelta = 35215
+
+// Options controls how images are compared.
+type Options struct {
+	Tolerance   uint8   // Per-channel difference which is always accepted.
+	Threshold   float64 // Perceptual difference threshold in the range [0;1].
+	MaxMismatch float64 // Fraction of pixels which may mismatch.
+}
+
+// DefaultOptions are suitable for comparing renders across GL drivers.
+var DefaultOptions = Options{
+	Tolerance:   2,
+	Threshold:   0.1,
+	MaxMismatch: 0.001,
+}
+
+// Result is the result of a comparison.
+type Result struct {
+	Mismatched int         // The number of mismatched pixels.
+	Total      int         // The total number of pixels.
+	Diff       *image.RGBA // Visualization of the mismatched pixels.
+}
+
+// OK returns whether the images are considered equal.
+func (r Result) OK(opts Options) bool {
+	return float64(r.Mismatched) <= opts.MaxMismatch*float64(r.Total)
+}
+
+// Compare compares an image against a reference image.
+// Images of different sizes are considered entirely mismatched.
+func Compare(img, ref image.Image, opts Options) Result {
+	b := ref.Bounds()
+	res := Result{
+		Total: b.Dx() * b.Dy(),
+		Diff:  image.NewRGBA(image.Rect(0, 0, b.Dx(), b.Dy())),
+	}
+	if img.Bounds().Size() != b.Size() {
+		res.Mismatched = res.Total
+		return res
+	}
+	off := img.Bounds().Min.Sub(b.Min)
+
+	for y := b.Min.Y; y < b.Max.Y; y++ {
+		for x := b.Min.X; x < b.Max.X; x++ {
+			c1 := color.RGBAModel.Convert(img.At(x+off.X, y+off.Y)).(color.RGBA)
+			c2 := color.RGBAModel.Convert(ref.At(x, y)).(color.RGBA)
+
+			dx, dy := x-b.Min.X, y-b.Min.Y
+			if withinTolerance(c1, c2, opts.Tolerance) ||
+				perceptualDelta(c1, c2) <= maxDelta*opts.Threshold*opts.Threshold {
+				// Draw matching pixels as faded gray
+				l := uint8(255 - (255-luma(c2))/10)
+				res.Diff.SetRGBA(dx, dy, color.RGBA{l, l, l, 255})
+				continue
+			}
+
+			res.Mismatched++
+			res.Diff.SetRGBA(dx, dy, color.RGBA{255, 0, 0, 255})
+		}
+	}
+	return res
+}
+
+// Load loads a PNG image.
+func Load(file string) (image.Image, error) {
+	f, err := os.Open(file)
+	if err != nil {
+		return nil, err
+	}
+	defer f.Close()
+
+	return png.Decode(f)
+}
+
+// withinTolerance returns whether all channels differ by at most tol.
+func withinTolerance(c1, c2 color.RGBA, tol uint8) bool {
+	return absDiff(c1.R, c2.R) <= tol &&
+		absDiff(c1.G, c2.G) <= tol &&
+		absDiff(c1.B, c2.B) <= tol &&
+		absDiff(c1.A, c2.A) <= tol
+}
+
+// perceptualDelta returns the squared YIQ distance between two colors.
+// See "Measuring perceived color difference using YIQ NTSC transmission
+// color space in mobile applications" by Kotsarenko and Ramos.
+func perceptualDelta(c1, c2 color.RGBA) float64 {
+	r1, g1, b1 := blendWhite(c1)
+	r2, g2, b2 := blendWhite(c2)
+
+	y := rgb2y(r1, g1, b1) - rgb2y(r2, g2, b2)
+	i := rgb2i(r1, g1, b1) - rgb2i(r2, g2, b2)
+	q := rgb2q(r1, g1, b1) - rgb2q(r2, g2, b2)
+
+	return 0.5053*y*y + 0.299*i*i + 0.1957*q*q
+}
+
+// blendWhite blends a color with a white background.
+func blendWhite(c color.RGBA) (r, g, b float64) {
+	a := float64(c.A) / 255
+	r = 255 + (float64(c.R)-255)*a
+	g = 255 + (float64(c.G)-255)*a
+	b = 255 + (float64(c.B)-255)*a
+	return
+}
+
+func rgb2y(r, g, b float64) float64 { return r*0.29889531 + g*0.58662247 + b*0.11448223 }
+func rgb2i(r, g, b float64) float64 { return r*0.59597799 - g*0.27417610 - b*0.32180189 }
+func rgb2q(r, g, b float64) float64 { return r*0.21147017 - g*0.52261711 + b*0.31114694 }
+
+func luma(c color.RGBA) uint8 {
+	r, g, b := blendWhite(c)
+	return uint8(rgb2y(r, g, b))
+}
+
+func absDiff(a, b uint8) uint8 {
+	if a > b {
+		return a - b
+	}
+	return b - a
+}
@@ -0,0 +1,115 @@
+package golden
+
+import (
+	"flag"
+	"fmt"
+	"image"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/patrick-jessen/goplay/engine"
+	"github.com/patrick-jessen/goplay/engine/renderer"
+	"github.com/patrick-jessen/goplay/engine/texture"
+	"github.com/patrick-jessen/goplay/engine/window"
+)
+
+var update = flag.Bool("update", false, "update reference images")
+
+// scenes are the scenes under test.
+// Reference images are stored as testdata/{scene}.png.
+var scenes = []string{"main"}
+
+const (
+	frames     = 5
+	width      = 320
+	height     = 240
+	failedDir  = "testdata/failed"
+	refPattern = "testdata/%v.png"
+)
+
+var (
+	renders   = make(map[string]*image.RGBA)
+	skipCause string
+)
+
+// TestMain renders all scenes up front, since OpenGL calls must be made
+// from the main thread.
+func TestMain(m *testing.M) {
+	flag.Parse()
+
+	wd, _ := os.Getwd()
+	// Assets are loaded relative to the repository root
+	os.Chdir("../..")
+
+	window.Settings.SetSize(width, height)
+	if err := engine.InitializeHeadless(); err != nil {
+		skipCause = "no GL context available: " + err.Error()
+	} else {
+		pinSettings()
+		for _, s := range scenes {
+			renders[s] = engine.RenderHeadless(s, frames)
+		}
+		engine.DeinitializeHeadless()
+	}
+
+	os.Chdir(wd)
+	os.Exit(m.Run())
+}
+
+// pinSettings applies the settings which reference images are rendered
+// with, such that they do not depend on defaults or on the GPU's limits.
+func pinSettings() {
+	renderer.Settings.SetType(renderer.Forward)
+	renderer.Settings.SetAntialising(renderer.NoAA)
+	renderer.Settings.SetShadowResolution(1024)
+	renderer.Settings.SetShadowFilter(1)
+	renderer.Settings.SetShadowBias(0.0005, 0.002)
+	renderer.Settings.SetPostProcessing(nil)
+	renderer.Settings.Apply()
+
+	texture.Settings.SetResolution(1)
+	texture.Settings.SetFilter(texture.Trilinear, 1)
+	texture.Settings.Apply()
+}
+
+func TestRender(t *testing.T) {
+	if len(skipCause) > 0 {
+		t.Skip(skipCause)
+	}
+
+	for _, s := range scenes {
+		t.Run(s, func(t *testing.T) {
+			img := renders[s]
+			refFile := fmt.Sprintf(refPattern, s)
+
+			if *update {
+				if err := engine.SavePNG(refFile, img); err != nil {
+					t.Fatal(err)
+				}
+				return
+			}
+
+			ref, err := Load(refFile)
+			if os.IsNotExist(err) {
+				t.Fatalf("no reference image; run with -update to create %v", refFile)
+			} else if err != nil {
+				t.Fatal(err)
+			}
+
+			res := Compare(img, ref, DefaultOptions)
+			if res.OK(DefaultOptions) {
+				return
+			}
+
+			os.MkdirAll(failedDir, 0755)
+			actualFile := filepath.Join(failedDir, s+".actual.png")
+			diffFile := filepath.Join(failedDir, s+".diff.png")
+			engine.SavePNG(actualFile, img)
+			engine.SavePNG(diffFile, res.Diff)
+
+			t.Errorf("%v of %v pixels mismatch. see %v and %v",
+				res.Mismatched, res.Total, actualFile, diffFile)
+		})
+	}
+}
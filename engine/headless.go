@@ -0,0 +1,91 @@
+package engine
+
+import (
+	"image"
+	"image/png"
+	"os"
+
+	"github.com/patrick-jessen/goplay/engine/clock"
+	"github.com/patrick-jessen/goplay/engine/framebuffer"
+	"github.com/patrick-jessen/goplay/engine/model"
+	"github.com/patrick-jessen/goplay/engine/renderer"
+	"github.com/patrick-jessen/goplay/engine/resource"
+	"github.com/patrick-jessen/goplay/engine/texture"
+	"github.com/patrick-jessen/goplay/engine/window"
+	"github.com/patrick-jessen/goplay/engine/worker"
+)
+
+var headlessTarget *framebuffer.FrameBuffer
+
+// StartHeadless renders a scene offscreen for a fixed number of frames,
+// and saves the final frame as a PNG file.
+// The resolution is taken from window.Settings.
+func StartHeadless(sceneName string, frames int, file string) error {
+	if err := InitializeHeadless(); err != nil {
+		return err
+	}
+	defer DeinitializeHeadless()
+
+	return SavePNG(file, RenderHeadless(sceneName, frames))
+}
+
+// InitializeHeadless creates a hidden window and an offscreen render target.
+// Must be called from the main thread.
+func InitializeHeadless() error {
+	if err := window.CreateHeadless(); err != nil {
+		return err
+	}
+	renderer.Initialize()
+
+	w, h := window.Settings.Size()
+	headlessTarget = framebuffer.New(w, h, 1, 0)
+	renderer.SetTarget(headlessTarget)
+	return nil
+}
+
+// DeinitializeHeadless frees the resources created by InitializeHeadless.
+func DeinitializeHeadless() {
+	renderer.SetTarget(nil)
+	headlessTarget.Free()
+	headlessTarget = nil
+
+	renderer.Deinitialize()
+	window.Destroy()
+}
+
+// RenderHeadless loads a scene, renders it for a number of frames and
+// returns the final frame.
+// Models and textures are fully loaded before the first frame, and each
+// frame lasts one fixed step, such that the output is deterministic.
+func RenderHeadless(sceneName string, frames int) *image.RGBA {
+	resource.LoadScene(sceneName).MakeCurrent()
+
+	for model.Loading() || texture.Loading() {
+		worker.RunNext()
+	}
+
+	clock.Reset()
+	for i := 0; i < frames; i++ {
+		window.Update()
+		update(clock.Advance(clock.FixedDelta()))
+		renderer.Render()
+	}
+
+	// The shading pass does not write alpha
+	img := headlessTarget.ReadColor(0)
+	for i := 3; i < len(img.Pix); i += 4 {
+		img.Pix[i] = 255
+	}
+	return img
+}
+
+// SavePNG writes an image to a PNG file.
+func SavePNG(file string, img image.Image) error {
+	f, err := os.Create(file)
+	if err != nil {
+		return err
+	}
+	defer f.Close()
+
+	return png.Encode(f, img)
+}
@@ -0,0 +1,148 @@
+// Package light provides components which light the scene.
+//
+// Lights follow the conventions of glTF KHR_lights_punctual: they are
+// located at the origin of their node and point along the node's -Z axis.
+package light
+
+import (
+	"encoding/json"
+	"math"
+
+	mgl "github.com/go-gl/mathgl/mgl32"
+
+	"github.com/patrick-jessen/goplay/engine/scene"
+	"github.com/patrick-jessen/goplay/engine/shader"
+)
+
+func init() {
+	scene.RegisterComponent(&DirectionalLight{})
+	scene.RegisterComponent(&PointLight{})
+	scene.RegisterComponent(&SpotLight{})
+}
+
+// Light is a component which emits light.
+type Light interface {
+	scene.Component
+	// Data returns the light in world space.
+	// Shadow is 0 if the light casts shadows and -1 otherwise. The
+	// renderer replaces it with the index of the assigned shadow map.
+	Data() shader.Light
+}
+
+// Gather returns the lights of n and its descendants.
+func Gather(n *scene.Node) []shader.Light {
+	var out []shader.Light
+	for _, l := range scene.FindAll[Light](n) {
+		out = append(out, l.Data())
+	}
+	return out
+}
+
+// base holds the properties shared by all lights.
+type base struct {
+	Color       mgl.Vec3
+	Intensity   float32
+	CastShadows bool
+
+	node *scene.Node
+}
+
+func (b *base) Initialize(n *scene.Node) {
+	b.node = n
+}
+func (b *base) Update(float32) {}
+func (b *base) Render()        {}
+
+// data returns the light data shared by all lights.
+func (b *base) data(t shader.LightType) shader.Light {
+	m := b.node.WorldTransform()
+	shadow := int32(-1)
+	if b.CastShadows {
+		shadow = 0
+	}
+	return shader.Light{
+		Type:      t,
+		Position:  m.Col(3).Vec3(),
+		Direction: m.Mul4x1(mgl.Vec4{0, 0, -1, 0}).Vec3().Normalize(),
+		Color:     b.Color.Mul(b.Intensity),
+		Shadow:    shadow,
+	}
+}
+
+// defaultBase returns the default light properties.
+func defaultBase() base {
+	return base{
+		Color:     mgl.Vec3{1, 1, 1},
+		Intensity: 1,
+	}
+}
+
+// DirectionalLight is a light infinitely far away, such as the sun.
+type DirectionalLight struct {
+	base
+}
+
+// UnmarshalJSON sets default values for DirectionalLight.
+func (l *DirectionalLight) UnmarshalJSON(d []byte) error {
+	type alias DirectionalLight
+	out := &alias{base: defaultBase()}
+	e := json.Unmarshal(d, out)
+	*l = DirectionalLight(*out)
+	return e
+}
+
+// Data returns the light in world space.
+func (l *DirectionalLight) Data() shader.Light {
+	return l.data(shader.LightDirectional)
+}
+
+// PointLight is a light emitting in all directions.
+type PointLight struct {
+	base
+	Range float32 // Distance at which the light reaches zero. 0 means infinite.
+}
+
+// UnmarshalJSON sets default values for PointLight.
+func (l *PointLight) UnmarshalJSON(d []byte) error {
+	type alias PointLight
+	out := &alias{base: defaultBase()}
+	e := json.Unmarshal(d, out)
+	*l = PointLight(*out)
+	return e
+}
+
+// Data returns the light in world space.
+func (l *PointLight) Data() shader.Light {
+	d := l.data(shader.LightPoint)
+	d.Range = l.Range
+	return d
+}
+
+// SpotLight is a light emitting in a cone.
+type SpotLight struct {
+	base
+	Range      float32 // Distance at which the light reaches zero. 0 means infinite.
+	InnerAngle float32 // Angle in degrees at which the light starts to fall off.
+	OuterAngle float32 // Angle in degrees at which the light reaches zero.
+}
+
+// UnmarshalJSON sets default values for SpotLight.
+func (l *SpotLight) UnmarshalJSON(d []byte) error {
+	type alias SpotLight
+	out := &alias{
+		base:       defaultBase(),
+		OuterAngle: 45,
+	}
+	e := json.Unmarshal(d, out)
+	*l = SpotLight(*out)
+	return e
+}
+
+// Data returns the light in world space.
+func (l *SpotLight) Data() shader.Light {
+	d := l.data(shader.LightSpot)
+	d.Range = l.Range
+	d.InnerCone = float32(math.Cos(float64(mgl.DegToRad(l.InnerAngle))))
+	d.OuterCone = float32(math.Cos(float64(mgl.DegToRad(l.OuterAngle))))
+	return d
+}
@@ -0,0 +1,54 @@
+package light
+
+import (
+	"encoding/json"
+	"math"
+	"testing"
+
+	mgl "github.com/go-gl/mathgl/mgl32"
+
+	"github.com/patrick-jessen/goplay/engine/scene"
+	"github.com/patrick-jessen/goplay/engine/shader"
+)
+
+func TestSpotLight_UnmarshalJSON(t *testing.T) {
+	var l SpotLight
+	if e := json.Unmarshal([]byte(`{"Intensity": 2, "InnerAngle": 60, "OuterAngle": 90}`), &l); e != nil {
+		t.Fatal(e)
+	}
+	if l.Color != (mgl.Vec3{1, 1, 1}) || l.Intensity != 2 {
+		t.Errorf("wrong properties. got %+v", l)
+	}
+
+	s := scene.New()
+	l.Initialize(s.Root)
+	d := l.Data()
+	if d.Type != shader.LightSpot || d.Color != (mgl.Vec3{2, 2, 2}) {
+		t.Errorf("wrong data. got %+v", d)
+	}
+	if math.Abs(float64(d.InnerCone-0.5)) > 1e-6 || math.Abs(float64(d.OuterCone)) > 1e-6 {
+		t.Errorf("wrong cone. got %v %v, expected 0.5 0", d.InnerCone, d.OuterCone)
+	}
+	if d.Direction != (mgl.Vec3{0, 0, -1}) {
+		t.Errorf("wrong direction. got %v, expected %v", d.Direction, mgl.Vec3{0, 0, -1})
+	}
+}
+
+func TestGather(t *testing.T) {
+	s := scene.New()
+	s.Root.NewChild("a").AddComponent(&PointLight{})
+	b := s.Root.NewChild("b")
+	b.AddComponent(&DirectionalLight{})
+	b.NewChild("c").AddComponent(&SpotLight{})
+
+	lights := Gather(s.Root)
+	expected := []shader.LightType{shader.LightPoint, shader.LightDirectional, shader.LightSpot}
+	if len(lights) != len(expected) {
+		t.Fatalf("wrong number of lights. got %v, expected %v", len(lights), len(expected))
+	}
+	for i, l := range lights {
+		if l.Type != expected[i] {
+			t.Errorf("light %v: wrong type. got %v, expected %v", i, l.Type, expected[i])
+		}
+	}
+}
@@ -1,6 +1,9 @@
 package material
 
 import (
+	"github.com/go-gl/gl/v3.2-core/gl"
+	mgl "github.com/go-gl/mathgl/mgl32"
+
 	"github.com/patrick-jessen/goplay/engine/shader"
 	"github.com/patrick-jessen/goplay/engine/texture"
 )
@@ -9,29 +12,203 @@ type Material interface {
 	Apply()
 }
 
-type pbrMaterial struct {
-	Shader     shader.Shader
-	DiffuseTex *texture.Texture
-	NormalTex  *texture.Texture
+// Pass selects which materials are rendered.
+type Pass int
+
+const (
+	PassAll         Pass = iota // All materials.
+	PassOpaque                  // Materials which are not blended.
+	PassTransparent             // Blended materials.
+)
+
+var pass = PassAll
+
+// SetPass sets which materials are rendered.
+func SetPass(p Pass) {
+	pass = p
+}
+
+// blender is implemented by materials which may be blended.
+type blender interface {
+	Blended() bool
+}
+
+// Blended returns whether m is blended with what is behind it.
+func Blended(m Material) bool {
+	b, ok := m.(blender)
+	return ok && b.Blended()
+}
+
+// InPass returns whether m is rendered in the current pass.
+func InPass(m Material) bool {
+	if pass == PassAll {
+		return true
+	}
+	return Blended(m) == (pass == PassTransparent)
 }
 
-func NewPBRMaterial() pbrMaterial {
-	return pbrMaterial{
-		Shader:     shader.Load("pbr"),
-		DiffuseTex: texture.Load("default_diff.jpg"),
-		NormalTex:  texture.Load("default_norm.jpg"),
+// AlphaMode determines how the alpha value of a material is interpreted.
+type AlphaMode int
+
+const (
+	AlphaOpaque AlphaMode = iota // Alpha is ignored.
+	AlphaMask                    // Fragments with alpha below the cutoff are discarded.
+	AlphaBlend                   // Fragments are blended with the background.
+)
+
+// ParseAlphaMode parses a glTF alpha mode.
+// Unknown modes are treated as opaque.
+func ParseAlphaMode(s string) AlphaMode {
+	switch s {
+	case "MASK":
+		return AlphaMask
+	case "BLEND":
+		return AlphaBlend
+	default:
+		return AlphaOpaque
 	}
 }
 
-func (m pbrMaterial) Apply() {
+// Texture units used by PBRMaterial.
+const (
+	unitBaseColor = iota
+	unitNormal
+	unitMetallicRoughness
+	unitOcclusion
+	unitEmissive
+)
+
+// PBRMaterial is a physically based material using the glTF
+// metallic-roughness model. Textures are optional.
+type PBRMaterial struct {
+	Shader shader.Shader
+
+	BaseColorFactor mgl.Vec4
+	BaseColorTex    *texture.Texture // sRGB color in RGB, alpha in A.
+
+	MetallicFactor       float32
+	RoughnessFactor      float32
+	MetallicRoughnessTex *texture.Texture // Roughness in G, metalness in B.
+
+	NormalScale float32
+	NormalTex   *texture.Texture
+
+	OcclusionStrength float32
+	OcclusionTex      *texture.Texture // Occlusion in R.
+
+	EmissiveFactor mgl.Vec3
+	EmissiveTex    *texture.Texture // sRGB color in RGB.
+
+	AlphaMode   AlphaMode
+	AlphaCutoff float32
+	DoubleSided bool
+}
+
+// NewPBRMaterial creates a PBR material with the default glTF values.
+func NewPBRMaterial() PBRMaterial {
+	return PBRMaterial{
+		Shader:            shader.Load("pbr"),
+		BaseColorFactor:   mgl.Vec4{1, 1, 1, 1},
+		MetallicFactor:    1,
+		RoughnessFactor:   1,
+		NormalScale:       1,
+		OcclusionStrength: 1,
+		AlphaCutoff:       0.5,
+	}
+}
+
+func (m PBRMaterial) Apply() {
 	m.Shader.Use()
-	m.DiffuseTex.Bind(0)
-	m.NormalTex.Bind(1)
+	s := m.Shader
+	if o := shader.Override(); o != nil {
+		s = *o
+	}
+	u := locations(s)
+
+	gl.Uniform4fv(u.baseColorFactor, 1, &m.BaseColorFactor[0])
+	gl.Uniform1f(u.metallicFactor, m.MetallicFactor)
+	gl.Uniform1f(u.roughnessFactor, m.RoughnessFactor)
+	gl.Uniform1f(u.normalScale, m.NormalScale)
+	gl.Uniform1f(u.occlusionStrength, m.OcclusionStrength)
+	gl.Uniform3fv(u.emissiveFactor, 1, &m.EmissiveFactor[0])
+	gl.Uniform1i(u.alphaMode, int32(m.AlphaMode))
+	gl.Uniform1f(u.alphaCutoff, m.AlphaCutoff)
+
+	// Bind textures and tell the shader which are present. Textures being
+	// loaded are treated as absent, such that only the factors apply.
+	var flags int32
+	for i, t := range []*texture.Texture{
+		unitBaseColor:         m.BaseColorTex,
+		unitNormal:            m.NormalTex,
+		unitMetallicRoughness: m.MetallicRoughnessTex,
+		unitOcclusion:         m.OcclusionTex,
+		unitEmissive:          m.EmissiveTex,
+	} {
+		if t != nil {
+			t.Bind(uint32(i))
+			if !t.Pending() {
+				flags |= 1 << uint(i)
+			}
+		}
+	}
+	gl.Uniform1i(u.textures, flags)
+
+	if m.AlphaMode == AlphaBlend {
+		gl.Enable(gl.BLEND)
+		gl.BlendFunc(gl.SRC_ALPHA, gl.ONE_MINUS_SRC_ALPHA)
+	} else {
+		gl.Disable(gl.BLEND)
+	}
+	if m.DoubleSided {
+		gl.Disable(gl.CULL_FACE)
+	} else {
+		gl.Enable(gl.CULL_FACE)
+	}
+}
+
+// pbrLocations holds the uniform locations of a PBR shader.
+type pbrLocations struct {
+	baseColorFactor   int32
+	metallicFactor    int32
+	roughnessFactor   int32
+	normalScale       int32
+	occlusionStrength int32
+	emissiveFactor    int32
+	alphaMode         int32
+	alphaCutoff       int32
+	textures          int32
+}
+
+var locationCache = make(map[shader.Shader]*pbrLocations)
+
+// locations returns the uniform locations of a PBR shader.
+func locations(s shader.Shader) *pbrLocations {
+	if l, ok := locationCache[s]; ok {
+		return l
+	}
+	l := &pbrLocations{
+		baseColorFactor:   s.GetUniform("baseColorFactor"),
+		metallicFactor:    s.GetUniform("metallicFactor"),
+		roughnessFactor:   s.GetUniform("roughnessFactor"),
+		normalScale:       s.GetUniform("normalScale"),
+		occlusionStrength: s.GetUniform("occlusionStrength"),
+		emissiveFactor:    s.GetUniform("emissiveFactor"),
+		alphaMode:         s.GetUniform("alphaMode"),
+		alphaCutoff:       s.GetUniform("alphaCutoff"),
+		textures:          s.GetUniform("textures"),
+	}
+	locationCache[s] = l
+	return l
 }
 
-type defaultMaterial struct{}
+// Blended returns whether the material is blended with the background.
+func (m PBRMaterial) Blended() bool {
+	return m.AlphaMode == AlphaBlend
+}
 
+// NewDefaultMaterial returns the material used by primitives
+// without a material.
 func NewDefaultMaterial() Material {
-	return defaultMaterial{}
+	m := NewPBRMaterial()
+	return &m
 }
-func (defaultMaterial) Apply() {}
@@ -0,0 +1,169 @@
+package model
+
+import (
+	"fmt"
+
+	mgl "github.com/go-gl/mathgl/mgl32"
+
+	"github.com/patrick-jessen/goplay/engine/bounds"
+	"github.com/patrick-jessen/goplay/engine/log"
+	"github.com/patrick-jessen/goplay/engine/model/gltf"
+	"github.com/patrick-jessen/goplay/engine/scene"
+	"github.com/patrick-jessen/goplay/engine/worker"
+)
+
+// pending maps the names of models being loaded asynchronously to the
+// callbacks awaiting them. It is only accessed from the main thread.
+var pending = make(map[string][]func(Model, error))
+
+// Loading returns whether any models are being loaded asynchronously.
+func Loading() bool {
+	return len(pending) > 0
+}
+
+// LoadAsync loads a model without blocking the main thread. The file is
+// parsed and its geometries decoded on a separate goroutine, whereas the
+// geometries are uploaded when the model is first mounted.
+// fn is called on the main thread once the model is loaded or has failed
+// to load. If the model is cached, fn is called immediately. If the model
+// is loaded by TryLoad in the meantime, fn receives the cached model.
+// Must be called from the main thread.
+func LoadAsync(name string, fn func(Model, error)) {
+	if m, ok := cache[name]; ok {
+		fn(m, nil)
+		return
+	}
+	if fns, ok := pending[name]; ok {
+		pending[name] = append(fns, fn)
+		return
+	}
+	pending[name] = []func(Model, error){fn}
+
+	go func() {
+		m, e := loadModel(name)
+		if e == nil {
+			m.decode()
+		}
+		worker.CallSynchronized(func() {
+			if cached, ok := cache[name]; ok {
+				m, e = cached, nil
+			} else if e == nil {
+				cache[name] = m
+			}
+			fns := pending[name]
+			delete(pending, name)
+			for _, fn := range fns {
+				fn(m, e)
+			}
+		})
+	}()
+}
+
+// decode decodes the geometries of all primitives in advance, such that
+// mounting only needs to upload them. Primitives which fail to decode are
+// reported when mounted.
+// Makes no OpenGL calls, and must be called before the model is shared.
+func (m Model) decode() {
+	meshes := m.file.GlTF.Meshes
+	for mi := range meshes {
+		for pi := range meshes[mi].Primitives {
+			geom, e := gltf.DecodePrimitive(m.file, &meshes[mi].Primitives[pi])
+			if e == nil {
+				m.shared.geoms[[2]int{mi, pi}] = geom
+			}
+		}
+	}
+}
+
+// Mounting is a model being mounted asynchronously.
+type Mounting struct {
+	done chan struct{}
+	inst *Instance
+	err  error
+}
+
+// Done returns a channel which is closed once the model is mounted, or
+// has failed to load.
+func (mg *Mounting) Done() <-chan struct{} {
+	return mg.done
+}
+
+// Mounted returns whether the model is mounted, or has failed to load.
+func (mg *Mounting) Mounted() bool {
+	select {
+	case <-mg.done:
+		return true
+	default:
+		return false
+	}
+}
+
+// Instance returns the mounted model.
+// Returns nil until mounted, or if the model failed to load.
+func (mg *Mounting) Instance() *Instance {
+	return mg.inst
+}
+
+// Err returns the error which occurred when loading the model, if any.
+// Errors are of type *asset.Error.
+func (mg *Mounting) Err() error {
+	return mg.err
+}
+
+// loadingNode is the name of the child holding the placeholder bounds of
+// a model being mounted. If a node already has such a child, a number is
+// appended.
+const loadingNode = "loading"
+
+// loadingBounds is the component of a placeholder, which occupies a unit
+// box at the origin of its node.
+type loadingBounds struct {
+	node *scene.Node
+}
+
+func (l *loadingBounds) Initialize(n *scene.Node) {
+	l.node = n
+}
+func (l *loadingBounds) Update(float32) {}
+func (l *loadingBounds) Render()        {}
+
+func (l *loadingBounds) Bounds() bounds.AABB {
+	unit := bounds.AABB{Min: mgl.Vec3{-1, -1, -1}, Max: mgl.Vec3{1, 1, 1}}
+	return unit.Transform(l.node.WorldTransform())
+}
+
+// MountAsync loads a model using LoadAsync, and mounts it onto sn once
+// loaded. Until then, sn has a placeholder child which occupies a unit box.
+// If sn is destroyed before the model is loaded, the model is not mounted.
+// fn is called on the main thread when done, unless it is nil.
+// Errors are also logged.
+// Must be called from the main thread.
+func MountAsync(sn *scene.Node, name string, fn func(*Instance, error)) *Mounting {
+	mg := &Mounting{done: make(chan struct{})}
+	child := loadingNode
+	for i := 1; sn.Child(child) != nil; i++ {
+		child = fmt.Sprintf("%v_%v", loadingNode, i)
+	}
+	loading := sn.MountChild(child)
+	loading.AddComponent(&loadingBounds{})
+
+	LoadAsync(name, func(m Model, e error) {
+		destroyed := loading.Parent() != sn
+		loading.Destroy()
+		if destroyed {
+			close(mg.done)
+			return
+		}
+		if e != nil {
+			log.Error("could not load model", "model", name, "error", e)
+			mg.err = e
+		} else {
+			mg.inst = m.Mount(sn)
+		}
+		close(mg.done)
+		if fn != nil {
+			fn(mg.inst, mg.err)
+		}
+	})
+	return mg
+}
@@ -0,0 +1,133 @@
+package model
+
+import (
+	"os"
+	"testing"
+
+	"github.com/patrick-jessen/goplay/engine/asset"
+	"github.com/patrick-jessen/goplay/engine/scene"
+	"github.com/patrick-jessen/goplay/engine/worker"
+)
+
+func TestLoadAsync(t *testing.T) {
+	wd, _ := os.Getwd()
+	defer os.Chdir(wd)
+	os.Chdir("../..")
+	defer delete(cache, "cube")
+
+	var models []Model
+	for i := 0; i < 2; i++ {
+		LoadAsync("cube", func(m Model, e error) {
+			if e != nil {
+				t.Error(e)
+			}
+			models = append(models, m)
+		})
+	}
+	var err error
+	LoadAsync("doesNotExist", func(m Model, e error) {
+		err = e
+	})
+	if !Loading() || len(pending) != 2 {
+		t.Fatalf("wrong number of pending models. got %v, expected %v", len(pending), 2)
+	}
+
+	for Loading() {
+		worker.RunNext()
+	}
+	if len(models) != 2 || models[0].file != models[1].file {
+		t.Fatal("model was not loaded once for both callbacks")
+	}
+	if len(models[0].shared.geoms) == 0 {
+		t.Error("geometries were not decoded")
+	}
+	if _, ok := err.(*asset.Error); !ok {
+		t.Errorf("wrong error for missing model. got %v", err)
+	}
+
+	// Cached models are returned immediately
+	called := false
+	LoadAsync("cube", func(Model, error) { called = true })
+	if !called {
+		t.Error("callback was not called for cached model")
+	}
+}
+
+func TestLoadAsync_tryLoad(t *testing.T) {
+	wd, _ := os.Getwd()
+	defer os.Chdir(wd)
+	os.Chdir("../..")
+	defer delete(cache, "cube")
+
+	var async Model
+	LoadAsync("cube", func(m Model, e error) {
+		async = m
+	})
+	m, e := TryLoad("cube")
+	if e != nil {
+		t.Fatal(e)
+	}
+	for Loading() {
+		worker.RunNext()
+	}
+	if async.file != m.file || cache["cube"].file != m.file {
+		t.Error("model was loaded twice")
+	}
+}
+
+func TestMountAsync(t *testing.T) {
+	s := scene.New()
+	failing := s.Root.NewChild("failing")
+	destroyed := s.Root.NewChild("destroyed")
+
+	var err error
+	mg := MountAsync(failing, "doesNotExist", func(inst *Instance, e error) {
+		err = e
+	})
+	MountAsync(destroyed, "doesNotExist", func(*Instance, error) {
+		t.Error("callback was called for destroyed node")
+	})
+	if failing.Child(loadingNode) == nil {
+		t.Fatal("placeholder was not added")
+	}
+	if b := failing.Child(loadingNode).Component("loadingBounds").(scene.Bounded).Bounds(); b.Max[0] != 1 {
+		t.Errorf("wrong placeholder bounds. got %v", b)
+	}
+	destroyed.Destroy()
+
+	for Loading() {
+		worker.RunNext()
+	}
+	if !mg.Mounted() || err == nil || mg.Err() != err {
+		t.Errorf("wrong result. got %v", err)
+	}
+	if failing.Child(loadingNode) != nil {
+		t.Error("placeholder was not removed")
+	}
+}
+
+func TestMountAsync_twice(t *testing.T) {
+	s := scene.New()
+	n := s.Root.NewChild("n")
+	n.NewChild(loadingNode)
+
+	calls := 0
+	for i := 0; i < 2; i++ {
+		MountAsync(n, "doesNotExist", func(*Instance, error) {
+			calls++
+		})
+	}
+	if len(n.Children()) != 3 {
+		t.Fatalf("wrong number of placeholders. got %v children", len(n.Children()))
+	}
+
+	for Loading() {
+		worker.RunNext()
+	}
+	if calls != 2 {
+		t.Errorf("wrong number of callbacks. got %v", calls)
+	}
+	if len(n.Children()) != 1 || n.Child(loadingNode) == nil {
+		t.Errorf("wrong children after loading. got %v", n.Children())
+	}
+}
@@ -0,0 +1,345 @@
+package model
+
+import (
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"io/ioutil"
+	"math"
+	"path/filepath"
+	"strings"
+
+	"github.com/go-gl/gl/v3.2-core/gl"
+
+	"github.com/patrick-jessen/goplay/engine/material"
+	"github.com/patrick-jessen/goplay/engine/model/geometry"
+	"github.com/patrick-jessen/goplay/engine/model/gltf"
+	"github.com/patrick-jessen/goplay/engine/scene"
+	"github.com/patrick-jessen/goplay/engine/texture"
+)
+
+// exporter holds the state of a node hierarchy being exported.
+type exporter struct {
+	g         gltf.GlTF
+	bin       []byte
+	dir       string // Directory of the exported file.
+	embed     bool   // Whether images are embedded in the binary buffer.
+	materials map[material.Material]int
+	textures  map[string]int  // Maps image files to texture indices.
+	images    map[string]bool // Names of the images copied next to the file.
+}
+
+// Export writes the node hierarchy rooted at root to a .gltf or .glb file.
+// Mesh renderers are exported with their geometry, PBR materials and
+// textures. Skins and animations are not exported, and neither are the
+// joints and weights of skinned geometries.
+// For .gltf files, images are copied next to the file. Images with the
+// same name are renamed.
+func Export(root *scene.Node, file string) error {
+	ext := filepath.Ext(file)
+	if ext != ".gltf" && ext != ".glb" {
+		return errors.New("invalid file extension")
+	}
+
+	ex := &exporter{
+		dir:       filepath.Dir(file),
+		embed:     ext == ".glb",
+		materials: make(map[material.Material]int),
+		textures:  make(map[string]int),
+		images:    make(map[string]bool),
+	}
+	ex.g.Scene = 0
+	ex.g.Asset = gltf.Asset{Version: "2.0", Generator: "goplay"}
+
+	idx, e := ex.node(root)
+	if e != nil {
+		return e
+	}
+	ex.g.Scenes = []gltf.Scene{{Nodes: []uint{idx}}}
+
+	f := &gltf.File{GlTF: ex.g}
+	if len(ex.bin) > 0 {
+		f.GlTF.Buffers = []gltf.Buffer{{ByteLength: uint(len(ex.bin))}}
+		f.Chunks = [][]byte{ex.bin}
+	}
+	return f.Save(file)
+}
+
+// node exports a node and its children.
+// Returns the index of the node.
+func (ex *exporter) node(n *scene.Node) (uint, error) {
+	pos := n.Position()
+	rot := n.Rotation()
+	scal := n.Scale()
+	gn := gltf.Node{
+		Name:        n.Name(),
+		Camera:      -1,
+		Mesh:        -1,
+		Skin:        -1,
+		Translation: pos[:],
+		Rotation:    []float32{rot.V.X(), rot.V.Y(), rot.V.Z(), rot.W},
+		Scale:       scal[:],
+	}
+
+	if mr := scene.GetComponent[*MeshRenderer](n); mr != nil && len(mr.geoms) > 0 {
+		mi, e := ex.mesh(n.Name(), mr)
+		if e != nil {
+			return 0, e
+		}
+		gn.Mesh = mi
+	}
+
+	idx := uint(len(ex.g.Nodes))
+	ex.g.Nodes = append(ex.g.Nodes, gn)
+
+	for _, c := range n.Children() {
+		ci, e := ex.node(c)
+		if e != nil {
+			return 0, e
+		}
+		ex.g.Nodes[idx].Children = append(ex.g.Nodes[idx].Children, ci)
+	}
+	return idx, nil
+}
+
+// mesh exports the geometries and material of a mesh renderer.
+// Returns the index of the mesh.
+func (ex *exporter) mesh(name string, mr *MeshRenderer) (int, error) {
+	mat, e := ex.material(mr.Mat)
+	if e != nil {
+		return 0, e
+	}
+
+	mesh := gltf.Mesh{Name: name, Weights: mr.weights}
+	for _, geom := range mr.geoms {
+		p := gltf.MeshPrimitive{
+			Attributes: make(map[string]uint),
+			Indices:    -1,
+			Material:   mat,
+			Mode:       uint(geom.PrimType),
+		}
+		if len(geom.IndexBuffer.Data) > 0 {
+			p.Indices = int(ex.accessor(&geom.IndexBuffer, gl.ELEMENT_ARRAY_BUFFER, false))
+		}
+
+		attribs := []struct {
+			name string
+			buf  *geometry.Buffer
+		}{
+			{"POSITION", &geom.PositionBuffer},
+			{"NORMAL", &geom.NormalBuffer},
+			{"TANGENT", &geom.TangentBuffer},
+			{"TEXCOORD_0", &geom.TexCoordBuffer},
+		}
+		for _, a := range attribs {
+			if len(a.buf.Data) > 0 {
+				p.Attributes[a.name] = ex.accessor(a.buf, gl.ARRAY_BUFFER, a.name == "POSITION")
+			}
+		}
+
+		for i := range geom.MorphPositionBuffers {
+			target := make(map[string]uint)
+			if b := &geom.MorphPositionBuffers[i]; len(b.Data) > 0 {
+				target["POSITION"] = ex.accessor(b, gl.ARRAY_BUFFER, true)
+			}
+			if b := &geom.MorphNormalBuffers[i]; len(b.Data) > 0 {
+				target["NORMAL"] = ex.accessor(b, gl.ARRAY_BUFFER, false)
+			}
+			if len(target) == 0 {
+				break
+			}
+			p.Targets = append(p.Targets, target)
+		}
+		mesh.Primitives = append(mesh.Primitives, p)
+	}
+
+	ex.g.Meshes = append(ex.g.Meshes, mesh)
+	return len(ex.g.Meshes) - 1, nil
+}
+
+// accessor exports the data of a buffer.
+// Bounds are required for positions.
+// Returns the index of the accessor.
+func (ex *exporter) accessor(b *geometry.Buffer, target uint, bounds bool) uint {
+	a := gltf.Accessor{
+		BufferView:    int(ex.bufferView(b.Data, target)),
+		ComponentType: uint(b.ComponentType),
+		Normalized:    b.Normalized,
+		Count:         uint(b.Count),
+		Type:          accessorType(b.NumComponents),
+	}
+
+	if bounds && b.ComponentType == gl.FLOAT {
+		a.Min, a.Max = minMax(b.Data, int(b.NumComponents))
+	}
+
+	ex.g.Accessors = append(ex.g.Accessors, a)
+	return uint(len(ex.g.Accessors) - 1)
+}
+
+// bufferView appends data to the binary buffer.
+// Returns the index of the bufferView.
+func (ex *exporter) bufferView(data []byte, target uint) uint {
+	// Align data to 4 bytes
+	for len(ex.bin)%4 != 0 {
+		ex.bin = append(ex.bin, 0)
+	}
+	ex.g.BufferViews = append(ex.g.BufferViews, gltf.BufferView{
+		ByteOffset: uint(len(ex.bin)),
+		ByteLength: uint(len(data)),
+		Target:     target,
+	})
+	ex.bin = append(ex.bin, data...)
+	return uint(len(ex.g.BufferViews) - 1)
+}
+
+// material exports a material.
+// Returns the index of the material, or -1 if it cannot be exported.
+func (ex *exporter) material(m material.Material) (int, error) {
+	if idx, ok := ex.materials[m]; ok {
+		return idx, nil
+	}
+
+	var pm material.PBRMaterial
+	switch v := m.(type) {
+	case material.PBRMaterial:
+		pm = v
+	case *material.PBRMaterial:
+		pm = *v
+	default:
+		return -1, nil
+	}
+
+	// Export textures in a fixed order
+	var tex [5]int
+	for i, t := range []*texture.Texture{
+		pm.BaseColorTex,
+		pm.MetallicRoughnessTex,
+		pm.NormalTex,
+		pm.OcclusionTex,
+		pm.EmissiveTex,
+	} {
+		var e error
+		if tex[i], e = ex.texture(t); e != nil {
+			return 0, e
+		}
+	}
+
+	alphaMode := "OPAQUE"
+	switch pm.AlphaMode {
+	case material.AlphaMask:
+		alphaMode = "MASK"
+	case material.AlphaBlend:
+		alphaMode = "BLEND"
+	}
+
+	ex.g.Materials = append(ex.g.Materials, gltf.Material{
+		PbrMetallicRoughness: gltf.MaterialPbrMetallicRoughness{
+			BaseColorFactor:          pm.BaseColorFactor[:],
+			BaseColorTexture:         gltf.TextureInfo{Index: tex[0]},
+			MetallicFactor:           pm.MetallicFactor,
+			RoughnessFactor:          pm.RoughnessFactor,
+			MetallicRoughnessTexture: gltf.TextureInfo{Index: tex[1]},
+		},
+		NormalTexture:    gltf.MaterialNormalTextureInfo{Index: tex[2], Scale: pm.NormalScale},
+		OcclusionTexture: gltf.MaterialOcclusionTextureInfo{Index: tex[3], Strength: pm.OcclusionStrength},
+		EmissiveTexture:  gltf.TextureInfo{Index: tex[4]},
+		EmissiveFactor:   pm.EmissiveFactor[:],
+		AlphaMode:        alphaMode,
+		AlphaCutoff:      pm.AlphaCutoff,
+		DoubleSided:      pm.DoubleSided,
+	})
+	idx := len(ex.g.Materials) - 1
+	ex.materials[m] = idx
+	return idx, nil
+}
+
+// texture exports a texture and its image.
+// Returns the index of the texture, or -1 if t is nil.
+func (ex *exporter) texture(t *texture.Texture) (int, error) {
+	if t == nil {
+		return -1, nil
+	}
+	file := t.File()
+	if idx, ok := ex.textures[file]; ok {
+		return idx, nil
+	}
+
+	data, e := ioutil.ReadFile(file)
+	if e != nil {
+		return 0, e
+	}
+
+	img := gltf.Image{BufferView: -1}
+	if ex.embed {
+		img.BufferView = int(ex.bufferView(data, 0))
+		img.MimeType = "image/png"
+		if ext := strings.ToLower(filepath.Ext(file)); ext == ".jpg" || ext == ".jpeg" {
+			img.MimeType = "image/jpeg"
+		}
+	} else {
+		img.URI = ex.imageName(file)
+		if e := ioutil.WriteFile(filepath.Join(ex.dir, img.URI), data, 0644); e != nil {
+			return 0, e
+		}
+	}
+
+	ex.g.Images = append(ex.g.Images, img)
+	ex.g.Textures = append(ex.g.Textures, gltf.Texture{
+		Sampler: -1,
+		Source:  len(ex.g.Images) - 1,
+	})
+	idx := len(ex.g.Textures) - 1
+	ex.textures[file] = idx
+	return idx, nil
+}
+
+// imageName returns a name for the copy of an image file, which is unique
+// among the images of the exported file.
+func (ex *exporter) imageName(file string) string {
+	base := filepath.Base(file)
+	ext := filepath.Ext(base)
+	name := base
+	for i := 1; ex.images[name]; i++ {
+		name = fmt.Sprintf("%v_%v%v", strings.TrimSuffix(base, ext), i, ext)
+	}
+	ex.images[name] = true
+	return name
+}
+
+// accessorType returns the accessor type with the given number of components.
+func accessorType(n int32) string {
+	switch n {
+	case 2:
+		return "VEC2"
+	case 3:
+		return "VEC3"
+	case 4:
+		return "VEC4"
+	default:
+		return "SCALAR"
+	}
+}
+
+// minMax returns the minimum and maximum of each component of float data.
+func minMax(data []byte, n int) (min, max []float32) {
+	vals := make([]float32, len(data)/4)
+	for i := range vals {
+		vals[i] = math.Float32frombits(binary.LittleEndian.Uint32(data[i*4:]))
+	}
+	if len(vals) < n {
+		return nil, nil
+	}
+	min = append([]float32(nil), vals[:n]...)
+	max = append([]float32(nil), vals[:n]...)
+	for i, v := range vals {
+		c := i % n
+		if v < min[c] {
+			min[c] = v
+		}
+		if v > max[c] {
+			max[c] = v
+		}
+	}
+	return min, max
+}
@@ -0,0 +1,115 @@
+package model
+
+import (
+	"encoding/binary"
+	"math"
+	"path/filepath"
+	"testing"
+
+	"github.com/go-gl/gl/v3.2-core/gl"
+	mgl "github.com/go-gl/mathgl/mgl32"
+
+	"github.com/patrick-jessen/goplay/engine/material"
+	"github.com/patrick-jessen/goplay/engine/model/geometry"
+	"github.com/patrick-jessen/goplay/engine/model/gltf"
+	"github.com/patrick-jessen/goplay/engine/scene"
+)
+
+func TestExport(t *testing.T) {
+	pos := make([]byte, 36)
+	for i, v := range []float32{0, 0, 0, 1, 0, 0, 0, 2, -1} {
+		binary.LittleEndian.PutUint32(pos[i*4:], math.Float32bits(v))
+	}
+
+	s := scene.New()
+	n := s.Root.NewChild("tri")
+	n.SetPosition(mgl.Vec3{1, 2, 3})
+	n.AddComponent(&MeshRenderer{
+		geoms: []*geometry.Geometry{{
+			PrimType: gl.TRIANGLES,
+			PositionBuffer: geometry.Buffer{
+				ComponentType: gl.FLOAT,
+				NumComponents: 3,
+				Count:         3,
+				Data:          pos,
+			},
+			IndexBuffer: geometry.Buffer{
+				ComponentType: gl.UNSIGNED_BYTE,
+				NumComponents: 1,
+				Count:         3,
+				Data:          []byte{0, 1, 2},
+			},
+			JointBuffer: geometry.Buffer{
+				ComponentType: gl.UNSIGNED_BYTE,
+				NumComponents: 4,
+				Count:         3,
+				Data:          make([]byte, 12),
+			},
+		}},
+		Mat: &material.PBRMaterial{
+			BaseColorFactor: mgl.Vec4{1, 0, 0, 0.5},
+			MetallicFactor:  0.25,
+			RoughnessFactor: 0.75,
+			EmissiveFactor:  mgl.Vec3{0, 1, 0},
+			AlphaMode:       material.AlphaBlend,
+			DoubleSided:     true,
+		},
+	})
+
+	for _, name := range []string{"out.gltf", "out.glb"} {
+		file := filepath.Join(t.TempDir(), name)
+		if e := Export(s.Root, file); e != nil {
+			t.Fatalf("%v: %v", name, e)
+		}
+		f, e := gltf.TryLoad(file)
+		if e != nil {
+			t.Fatalf("%v: %v", name, e)
+		}
+		if issues := gltf.Validate(f); gltf.HasErrors(issues) {
+			t.Errorf("%v: invalid file %v", name, issues)
+		}
+
+		g := f.GlTF
+		if len(g.Nodes) != 2 || g.Nodes[1].Name != "tri" || g.Nodes[1].Mesh != 0 {
+			t.Fatalf("%v: wrong nodes. got %+v", name, g.Nodes)
+		}
+		if tr := g.Nodes[1].Translation; tr[0] != 1 || tr[1] != 2 || tr[2] != 3 {
+			t.Errorf("%v: wrong translation. got %v", name, tr)
+		}
+		prim := g.Meshes[0].Primitives[0]
+		if prim.Material != 0 || prim.Indices < 0 {
+			t.Errorf("%v: wrong primitive. got %+v", name, prim)
+		}
+		if _, ok := prim.Attributes["JOINTS_0"]; ok {
+			t.Errorf("%v: joints exported without a skin", name)
+		}
+		m := g.Materials[0]
+		if m.PbrMetallicRoughness.BaseColorFactor[3] != 0.5 || m.PbrMetallicRoughness.MetallicFactor != 0.25 ||
+			m.PbrMetallicRoughness.RoughnessFactor != 0.75 || m.EmissiveFactor[1] != 1 ||
+			m.AlphaMode != "BLEND" || !m.DoubleSided {
+			t.Errorf("%v: wrong material. got %+v", name, m)
+		}
+		acc := g.Accessors[prim.Attributes["POSITION"]]
+		if acc.Min[1] != 0 || acc.Max[1] != 2 || acc.Min[2] != -1 {
+			t.Errorf("%v: wrong bounds. got %v %v", name, acc.Min, acc.Max)
+		}
+	}
+}
+
+func Test_exporter_imageName(t *testing.T) {
+	ex := &exporter{images: make(map[string]bool)}
+	tests := []struct {
+		file     string
+		expected string
+	}{
+		{"a/color.png", "color.png"},
+		{"b/color.png", "color_1.png"},
+		{"c/color.png", "color_2.png"},
+		{"a/normal.png", "normal.png"},
+	}
+	for _, test := range tests {
+		if got := ex.imageName(test.file); got != test.expected {
+			t.Errorf("wrong name for %v. got %v, expected %v", test.file, got, test.expected)
+		}
+	}
+}
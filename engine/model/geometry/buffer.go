@@ -13,14 +13,16 @@ type Buffer struct {
 	ByteStride    int32  // The stride, in bytes, between vertex attributes.
 	ComponentType uint32 // The datatype of components in the attribute.
 	Normalized    bool   // Specifies whether integer data values should be normalized.
+	Integer       bool   // Specifies whether the attribute should be read as integers by the shader.
 	NumComponents int32  // The number of components per vertex attribute.
+	Count         int32  // The number of elements in the buffer.
 	Data          []byte // The buffer data
 }
 
 // initialize uploads data to the GPU.
-// After calling this function, b.Data will be nil.
+// b.Data is kept as a CPU copy of the data, e.g. for exporting.
 func (b *Buffer) initialize() {
-	if b.Data == nil {
+	if len(b.Data) == 0 {
 		return
 	}
 
@@ -31,8 +33,6 @@ func (b *Buffer) initialize() {
 	gl.BindBuffer(b.target, b.handle)
 	gl.BufferData(b.target, len(b.Data), gl.Ptr(b.Data), gl.STATIC_DRAW)
 	gl.BindBuffer(b.target, 0)
-
-	b.Data = nil
 }
 
 // enable binds the buffer and enables vertex attribute.
@@ -43,14 +43,24 @@ func (b *Buffer) enable(vertexAttribIndex uint32) {
 	}
 
 	gl.BindBuffer(b.target, b.handle)
-	gl.VertexAttribPointer(
-		vertexAttribIndex,
-		b.NumComponents,
-		b.ComponentType,
-		b.Normalized,
-		b.ByteStride,
-		gl.PtrOffset(b.ByteOffset),
-	)
+	if b.Integer {
+		gl.VertexAttribIPointer(
+			vertexAttribIndex,
+			b.NumComponents,
+			b.ComponentType,
+			b.ByteStride,
+			gl.PtrOffset(b.ByteOffset),
+		)
+	} else {
+		gl.VertexAttribPointer(
+			vertexAttribIndex,
+			b.NumComponents,
+			b.ComponentType,
+			b.Normalized,
+			b.ByteStride,
+			gl.PtrOffset(b.ByteOffset),
+		)
+	}
 	gl.EnableVertexAttribArray(vertexAttribIndex)
 }
 
@@ -2,41 +2,31 @@ package geometry
 
 import (
 	"github.com/go-gl/gl/v3.2-core/gl"
+
+	"github.com/patrick-jessen/goplay/engine/bounds"
 )
 
+// MaxMorphTargets is the maximum number of morph targets of a geometry.
+const MaxMorphTargets = 4
+
 // Geometry represents renderable geometry.
 type Geometry struct {
 	handle     uint32 // Handle to OpenGL VertexArray.
 	numIndices int32  // The number of indices.
 
-	PrimType       uint32 // The type of primitives to render.
+	PrimType       uint32      // The type of primitives to render.
+	Bounds         bounds.AABB // Bounds of the vertex positions in model space.
 	IndexBuffer    Buffer
 	PositionBuffer Buffer
 	TexCoordBuffer Buffer
 	NormalBuffer   Buffer
 	TangentBuffer  Buffer
-	hasIndices     bool
-}
+	JointBuffer    Buffer // Indices of the joints affecting each vertex.
+	WeightBuffer   Buffer // Weights of the joints affecting each vertex.
 
-// componentSizeFromType gets the size of the component type.
-// e.g. UNSIGNED_INT is 4 bytes.
-func componentSizeFromType(compType uint32) int32 {
-	switch compType {
-	case gl.BYTE:
-		fallthrough
-	case gl.UNSIGNED_BYTE:
-		return 1
-	case gl.SHORT:
-		fallthrough
-	case gl.UNSIGNED_SHORT:
-		return 2
-	case gl.UNSIGNED_INT:
-		return 4
-	case gl.FLOAT:
-		return 4
-	default:
-		panic("Unexpected componentType: " + string(compType))
-	}
+	MorphPositionBuffers [MaxMorphTargets]Buffer // Position displacements of each morph target.
+	MorphNormalBuffers   [MaxMorphTargets]Buffer // Normal displacements of each morph target.
+	hasIndices           bool
 }
 
 // Initialize initializes the geometry and uploads data to the GPU.
@@ -52,11 +42,9 @@ func (g *Geometry) Initialize() {
 
 	// Calculate number of indices
 	if g.hasIndices {
-		compSize := componentSizeFromType(g.IndexBuffer.ComponentType)
-		g.numIndices = int32(len(g.IndexBuffer.Data)) / compSize
+		g.numIndices = g.IndexBuffer.Count
 	} else {
-		compSize := componentSizeFromType(g.PositionBuffer.ComponentType)
-		g.numIndices = int32(len(g.PositionBuffer.Data)) / compSize
+		g.numIndices = g.PositionBuffer.Count
 	}
 
 	// Set buffer targets
@@ -65,6 +53,12 @@ func (g *Geometry) Initialize() {
 	g.NormalBuffer.target = gl.ARRAY_BUFFER
 	g.TexCoordBuffer.target = gl.ARRAY_BUFFER
 	g.TangentBuffer.target = gl.ARRAY_BUFFER
+	g.JointBuffer.target = gl.ARRAY_BUFFER
+	g.WeightBuffer.target = gl.ARRAY_BUFFER
+	for i := range g.MorphPositionBuffers {
+		g.MorphPositionBuffers[i].target = gl.ARRAY_BUFFER
+		g.MorphNormalBuffers[i].target = gl.ARRAY_BUFFER
+	}
 
 	// Initialize buffers
 	g.IndexBuffer.initialize()
@@ -72,6 +66,12 @@ func (g *Geometry) Initialize() {
 	g.NormalBuffer.initialize()
 	g.TexCoordBuffer.initialize()
 	g.TangentBuffer.initialize()
+	g.JointBuffer.initialize()
+	g.WeightBuffer.initialize()
+	for i := range g.MorphPositionBuffers {
+		g.MorphPositionBuffers[i].initialize()
+		g.MorphNormalBuffers[i].initialize()
+	}
 
 	// Create and bind VertexArray
 	gl.GenVertexArrays(1, &g.handle)
@@ -83,6 +83,12 @@ func (g *Geometry) Initialize() {
 	g.NormalBuffer.enable(1)
 	g.TexCoordBuffer.enable(2)
 	g.TangentBuffer.enable(3)
+	g.JointBuffer.enable(4)
+	g.WeightBuffer.enable(5)
+	for i := range g.MorphPositionBuffers {
+		g.MorphPositionBuffers[i].enable(6 + uint32(i))
+		g.MorphNormalBuffers[i].enable(6 + MaxMorphTargets + uint32(i))
+	}
 
 	gl.BindVertexArray(0)
 }
@@ -94,6 +100,13 @@ func (g *Geometry) Free() {
 	g.PositionBuffer.free()
 	g.NormalBuffer.free()
 	g.TexCoordBuffer.free()
+	g.TangentBuffer.free()
+	g.JointBuffer.free()
+	g.WeightBuffer.free()
+	for i := range g.MorphPositionBuffers {
+		g.MorphPositionBuffers[i].free()
+		g.MorphNormalBuffers[i].free()
+	}
 }
 
 // Draw draws the geometry
@@ -110,3 +123,19 @@ func (g *Geometry) Draw() {
 		gl.DrawArrays(g.PrimType, 0, g.numIndices)
 	}
 }
+
+// DrawInstanced draws count instances of the geometry.
+func (g *Geometry) DrawInstanced(count int32) {
+	gl.BindVertexArray(g.handle)
+	if g.hasIndices {
+		gl.DrawElementsInstanced(
+			g.PrimType,
+			g.numIndices,
+			g.IndexBuffer.ComponentType,
+			gl.PtrOffset(g.IndexBuffer.ByteOffset),
+			count,
+		)
+	} else {
+		gl.DrawArraysInstanced(g.PrimType, 0, g.numIndices, count)
+	}
+}
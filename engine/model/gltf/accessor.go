@@ -0,0 +1,300 @@
+package gltf
+
+import (
+	"encoding/binary"
+	"fmt"
+	"math"
+
+	mgl "github.com/go-gl/mathgl/mgl32"
+)
+
+// Component types.
+const (
+	componentByte          = 5120
+	componentUnsignedByte  = 5121
+	componentShort         = 5122
+	componentUnsignedShort = 5123
+	componentUnsignedInt   = 5125
+	componentFloat         = 5126
+)
+
+// componentSize returns the size in bytes of a component type.
+func componentSize(compType uint) int {
+	switch compType {
+	case componentByte, componentUnsignedByte:
+		return 1
+	case componentShort, componentUnsignedShort:
+		return 2
+	case componentUnsignedInt, componentFloat:
+		return 4
+	default:
+		return 0
+	}
+}
+
+// elementSize returns the size in bytes of an element of an accessor,
+// excluding padding.
+func elementSize(a *Accessor) int {
+	return componentSize(a.ComponentType) * int(numComponentsInType(a.Type))
+}
+
+// columns returns the number of columns of an element of an accessor, and
+// the size in bytes of a column including padding. Matrix columns start on
+// 4-byte boundaries, so MAT2 of bytes and MAT3 of bytes or shorts are
+// padded. Elements of other types are a single column without padding.
+func columns(a *Accessor) (n, size int) {
+	switch a.Type {
+	case "MAT2":
+		n = 2
+	case "MAT3":
+		n = 3
+	case "MAT4":
+		n = 4
+	default:
+		return 1, elementSize(a)
+	}
+	return n, (n*componentSize(a.ComponentType) + 3) &^ 3
+}
+
+// accessorData returns the data of an accessor, tightly packed.
+// Byte strides and matrix column padding are removed and sparse values
+// are substituted, so the i'th element is located at i*elementSize(a).
+func accessorData(g *File, idx uint) ([]byte, error) {
+	if idx >= uint(len(g.GlTF.Accessors)) {
+		return nil, fmt.Errorf("accessor %v does not exist", idx)
+	}
+	a := &g.GlTF.Accessors[idx]
+
+	if componentSize(a.ComponentType) == 0 {
+		return nil, fmt.Errorf("accessor %v: invalid componentType %v", idx, a.ComponentType)
+	}
+	if numComponentsInType(a.Type) == 0 {
+		return nil, fmt.Errorf("accessor %v: invalid type %v", idx, a.Type)
+	}
+	elemSize := elementSize(a)
+	out := make([]byte, int(a.Count)*elemSize)
+
+	// Accessors without a bufferView are initialized with zeros
+	if a.BufferView >= 0 {
+		data, e := viewData(g, uint(a.BufferView))
+		if e != nil {
+			return nil, fmt.Errorf("accessor %v: %v", idx, e)
+		}
+		numCols, colSize := columns(a)
+		colData := elemSize / numCols
+		stride := int(g.GlTF.BufferViews[a.BufferView].ByteStride)
+		if stride == 0 {
+			stride = numCols * colSize
+		}
+		last := (numCols-1)*colSize + colData
+		if a.Count > 0 && int(a.ByteOffset)+stride*(int(a.Count)-1)+last > len(data) {
+			return nil, fmt.Errorf("accessor %v: exceeds bufferView %v", idx, a.BufferView)
+		}
+		for i := 0; i < int(a.Count); i++ {
+			for c := 0; c < numCols; c++ {
+				src := int(a.ByteOffset) + i*stride + c*colSize
+				dst := i*elemSize + c*colData
+				copy(out[dst:dst+colData], data[src:src+colData])
+			}
+		}
+	}
+
+	if a.Sparse.Count > 0 {
+		if e := applySparse(g, a, out, elemSize); e != nil {
+			return nil, fmt.Errorf("accessor %v: sparse: %v", idx, e)
+		}
+	}
+	return out, nil
+}
+
+// applySparse substitutes the sparse values of an accessor into out.
+func applySparse(g *File, a *Accessor, out []byte, elemSize int) error {
+	s := &a.Sparse
+	count := int(s.Count)
+
+	// Read indices
+	switch s.Indices.ComponentType {
+	case componentUnsignedByte, componentUnsignedShort, componentUnsignedInt:
+	default:
+		return fmt.Errorf("invalid indices componentType %v", s.Indices.ComponentType)
+	}
+	idxSize := componentSize(s.Indices.ComponentType)
+	idxData, e := viewData(g, s.Indices.BufferView)
+	if e != nil {
+		return fmt.Errorf("indices: %v", e)
+	}
+	idxData, e = subslice(idxData, s.Indices.ByteOffset, count*idxSize)
+	if e != nil {
+		return fmt.Errorf("indices: %v", e)
+	}
+
+	// Read values
+	valData, e := viewData(g, s.Values.BufferView)
+	if e != nil {
+		return fmt.Errorf("values: %v", e)
+	}
+	valData, e = subslice(valData, s.Values.ByteOffset, count*elemSize)
+	if e != nil {
+		return fmt.Errorf("values: %v", e)
+	}
+
+	for i := 0; i < count; i++ {
+		var target int
+		switch s.Indices.ComponentType {
+		case componentUnsignedByte:
+			target = int(idxData[i])
+		case componentUnsignedShort:
+			target = int(binary.LittleEndian.Uint16(idxData[i*2:]))
+		case componentUnsignedInt:
+			target = int(binary.LittleEndian.Uint32(idxData[i*4:]))
+		}
+		if target >= int(a.Count) {
+			return fmt.Errorf("index %v is out of range", target)
+		}
+		copy(out[target*elemSize:(target+1)*elemSize], valData[i*elemSize:])
+	}
+	return nil
+}
+
+// viewData returns the data of a bufferView.
+func viewData(g *File, idx uint) ([]byte, error) {
+	if idx >= uint(len(g.GlTF.BufferViews)) {
+		return nil, fmt.Errorf("bufferView %v does not exist", idx)
+	}
+	return dataFromBufferView(g, &g.GlTF.BufferViews[idx])
+}
+
+// subslice returns n bytes of data starting at offset.
+func subslice(data []byte, offset uint, n int) ([]byte, error) {
+	if int(offset)+n > len(data) {
+		return nil, fmt.Errorf("exceeds bufferView")
+	}
+	return data[offset : int(offset)+n], nil
+}
+
+// ReadFloats reads the data of an accessor as floats.
+// Integer components are normalized if the accessor is normalized.
+func ReadFloats(g *File, idx uint) ([]float32, error) {
+	data, e := accessorData(g, idx)
+	if e != nil {
+		return nil, e
+	}
+	a := &g.GlTF.Accessors[idx]
+	compSize := componentSize(a.ComponentType)
+
+	out := make([]float32, len(data)/compSize)
+	for i := range out {
+		out[i] = readComponent(data[i*compSize:], a.ComponentType, a.Normalized)
+	}
+	return out, nil
+}
+
+// ReadUints reads the data of an integer accessor, such as indices or joints.
+func ReadUints(g *File, idx uint) ([]uint32, error) {
+	data, e := accessorData(g, idx)
+	if e != nil {
+		return nil, e
+	}
+	a := &g.GlTF.Accessors[idx]
+	if a.ComponentType == componentFloat {
+		return nil, fmt.Errorf("accessor %v: expected integer components", idx)
+	}
+	compSize := componentSize(a.ComponentType)
+
+	out := make([]uint32, len(data)/compSize)
+	for i := range out {
+		switch a.ComponentType {
+		case componentByte, componentUnsignedByte:
+			out[i] = uint32(data[i])
+		case componentShort, componentUnsignedShort:
+			out[i] = uint32(binary.LittleEndian.Uint16(data[i*2:]))
+		case componentUnsignedInt:
+			out[i] = binary.LittleEndian.Uint32(data[i*4:])
+		}
+	}
+	return out, nil
+}
+
+// readTyped reads the data of an accessor of the given type as floats.
+func readTyped(g *File, idx uint, typ string) ([]float32, error) {
+	if idx < uint(len(g.GlTF.Accessors)) && g.GlTF.Accessors[idx].Type != typ {
+		return nil, fmt.Errorf("accessor %v: expected type %v, got %v", idx, typ, g.GlTF.Accessors[idx].Type)
+	}
+	return ReadFloats(g, idx)
+}
+
+// ReadVec2s reads the data of a VEC2 accessor.
+func ReadVec2s(g *File, idx uint) ([]mgl.Vec2, error) {
+	f, e := readTyped(g, idx, "VEC2")
+	if e != nil {
+		return nil, e
+	}
+	out := make([]mgl.Vec2, len(f)/2)
+	for i := range out {
+		copy(out[i][:], f[i*2:])
+	}
+	return out, nil
+}
+
+// ReadVec3s reads the data of a VEC3 accessor.
+func ReadVec3s(g *File, idx uint) ([]mgl.Vec3, error) {
+	f, e := readTyped(g, idx, "VEC3")
+	if e != nil {
+		return nil, e
+	}
+	out := make([]mgl.Vec3, len(f)/3)
+	for i := range out {
+		copy(out[i][:], f[i*3:])
+	}
+	return out, nil
+}
+
+// ReadVec4s reads the data of a VEC4 accessor.
+func ReadVec4s(g *File, idx uint) ([]mgl.Vec4, error) {
+	f, e := readTyped(g, idx, "VEC4")
+	if e != nil {
+		return nil, e
+	}
+	out := make([]mgl.Vec4, len(f)/4)
+	for i := range out {
+		copy(out[i][:], f[i*4:])
+	}
+	return out, nil
+}
+
+// ReadMat4s reads the data of a MAT4 accessor.
+func ReadMat4s(g *File, idx uint) ([]mgl.Mat4, error) {
+	f, e := readTyped(g, idx, "MAT4")
+	if e != nil {
+		return nil, e
+	}
+	out := make([]mgl.Mat4, len(f)/16)
+	for i := range out {
+		copy(out[i][:], f[i*16:])
+	}
+	return out, nil
+}
+
+// readComponent reads a single component as float.
+func readComponent(d []byte, compType uint, normalized bool) float32 {
+	var v, max float32
+	switch compType {
+	case componentFloat:
+		return math.Float32frombits(binary.LittleEndian.Uint32(d))
+	case componentByte:
+		v, max = float32(int8(d[0])), 127
+	case componentUnsignedByte:
+		v, max = float32(d[0]), 255
+	case componentShort:
+		v, max = float32(int16(binary.LittleEndian.Uint16(d))), 32767
+	case componentUnsignedShort:
+		v, max = float32(binary.LittleEndian.Uint16(d)), 65535
+	case componentUnsignedInt:
+		v, max = float32(binary.LittleEndian.Uint32(d)), 4294967295
+	}
+	if !normalized {
+		return v
+	}
+	// Signed values are clamped to -1
+	return float32(math.Max(float64(v/max), -1))
+}
@@ -0,0 +1,161 @@
+package gltf
+
+import (
+	"encoding/base64"
+	"encoding/binary"
+	"encoding/json"
+	"fmt"
+	"math"
+	"testing"
+
+	mgl "github.com/go-gl/mathgl/mgl32"
+)
+
+// testFile creates a file with a single data URI buffer and the given
+// bufferViews and accessors.
+func testFile(t *testing.T, data []byte, views, accessors string) *File {
+	uri := "data:application/octet-stream;base64," + encode(data)
+	doc := fmt.Sprintf(`{"buffers":[{"uri":%q,"byteLength":%v}],"bufferViews":%v,"accessors":%v}`,
+		uri, len(data), views, accessors)
+
+	f := &File{}
+	if e := json.Unmarshal([]byte(doc), &f.GlTF); e != nil {
+		t.Fatal(e)
+	}
+	return f
+}
+
+// encode encodes data as base64.
+func encode(data []byte) string {
+	return base64.StdEncoding.EncodeToString(data)
+}
+
+// floats encodes floats as little endian bytes.
+func floats(v ...float32) []byte {
+	out := make([]byte, 4*len(v))
+	for i, f := range v {
+		binary.LittleEndian.PutUint32(out[i*4:], math.Float32bits(f))
+	}
+	return out
+}
+
+func TestReadVec3s_stride(t *testing.T) {
+	// Positions interleaved with a padding float
+	data := floats(1, 2, 3, -1, 4, 5, 6, -1)
+	f := testFile(t, data,
+		`[{"buffer":0,"byteLength":32,"byteStride":16}]`,
+		`[{"bufferView":0,"componentType":5126,"count":2,"type":"VEC3"}]`)
+
+	v, e := ReadVec3s(f, 0)
+	if e != nil {
+		t.Fatal(e)
+	}
+	expected := []mgl.Vec3{{1, 2, 3}, {4, 5, 6}}
+	if len(v) != len(expected) || v[0] != expected[0] || v[1] != expected[1] {
+		t.Errorf("wrong values. got %v, expected %v", v, expected)
+	}
+}
+
+func TestReadFloats_matrixPadding(t *testing.T) {
+	// Columns of a MAT3 of bytes are padded to 4 bytes
+	data := []byte{1, 2, 3, 0, 4, 5, 6, 0, 7, 8, 9, 0, 10, 11, 12, 0, 13, 14, 15, 0, 16, 17, 18, 0}
+	f := testFile(t, data,
+		`[{"buffer":0,"byteLength":24}]`,
+		`[{"bufferView":0,"componentType":5121,"count":2,"type":"MAT3"}]`)
+
+	v, e := ReadFloats(f, 0)
+	if e != nil {
+		t.Fatal(e)
+	}
+	for i := range v {
+		if v[i] != float32(i+1) {
+			t.Errorf("wrong values. got %v", v)
+			break
+		}
+	}
+}
+
+func TestReadFloats_normalized(t *testing.T) {
+	data := []byte{0, 255, 0x01, 0x80, 0xff, 0x7f}
+	f := testFile(t, data,
+		`[{"buffer":0,"byteLength":2},{"buffer":0,"byteOffset":2,"byteLength":4}]`,
+		`[{"bufferView":0,"componentType":5121,"normalized":true,"count":2,"type":"SCALAR"},
+		  {"bufferView":1,"componentType":5122,"normalized":true,"count":2,"type":"SCALAR"}]`)
+
+	tests := []struct {
+		accessor uint
+		expected []float32
+	}{
+		{0, []float32{0, 1}},
+		{1, []float32{-1, 1}},
+	}
+	for _, test := range tests {
+		v, e := ReadFloats(f, test.accessor)
+		if e != nil {
+			t.Fatal(e)
+		}
+		for i := range test.expected {
+			if !mgl.FloatEqual(v[i], test.expected[i]) {
+				t.Errorf("accessor %v: wrong values. got %v, expected %v", test.accessor, v, test.expected)
+				break
+			}
+		}
+	}
+}
+
+func TestReadFloats_sparse(t *testing.T) {
+	// Base values, followed by sparse indices (ubyte) and values
+	data := append(floats(1, 2, 3, 4), 1, 3, 0, 0)
+	data = append(data, floats(20, 40)...)
+	views := `[{"buffer":0,"byteLength":16},{"buffer":0,"byteOffset":16,"byteLength":4},{"buffer":0,"byteOffset":20,"byteLength":8}]`
+	sparse := `"sparse":{"count":2,"indices":{"bufferView":1,"componentType":5121},"values":{"bufferView":2}}`
+
+	tests := []struct {
+		name     string
+		accessor string
+		expected []float32
+	}{
+		{"with bufferView", `{"bufferView":0,"componentType":5126,"count":4,"type":"SCALAR",` + sparse + `}`, []float32{1, 20, 3, 40}},
+		{"without bufferView", `{"componentType":5126,"count":4,"type":"SCALAR",` + sparse + `}`, []float32{0, 20, 0, 40}},
+	}
+	for _, test := range tests {
+		f := testFile(t, data, views, "["+test.accessor+"]")
+		v, e := ReadFloats(f, 0)
+		if e != nil {
+			t.Errorf("%v: unexpected error %v", test.name, e)
+			continue
+		}
+		for i := range test.expected {
+			if v[i] != test.expected[i] {
+				t.Errorf("%v: wrong values. got %v, expected %v", test.name, v, test.expected)
+				break
+			}
+		}
+	}
+}
+
+func TestReadFloats_invalid(t *testing.T) {
+	data := floats(1, 2)
+	tests := []struct {
+		name     string
+		accessor string
+	}{
+		{"bad type", `{"bufferView":0,"componentType":5126,"count":2,"type":"VEC5"}`},
+		{"bad componentType", `{"bufferView":0,"componentType":1234,"count":2,"type":"SCALAR"}`},
+		{"bad bufferView", `{"bufferView":3,"componentType":5126,"count":2,"type":"SCALAR"}`},
+		{"too long", `{"bufferView":0,"componentType":5126,"count":3,"type":"SCALAR"}`},
+		{"bad sparse index", `{"bufferView":0,"componentType":5126,"count":2,"type":"SCALAR",
+			"sparse":{"count":1,"indices":{"bufferView":0,"componentType":5126},"values":{"bufferView":0}}}`},
+	}
+	for _, test := range tests {
+		f := testFile(t, data, `[{"buffer":0,"byteLength":8}]`, "["+test.accessor+"]")
+		if _, e := ReadFloats(f, 0); e == nil {
+			t.Errorf("%v: expected error", test.name)
+		}
+	}
+
+	f := testFile(t, data, `[{"buffer":0,"byteLength":8}]`, `[{"bufferView":0,"componentType":5126,"count":2,"type":"SCALAR"}]`)
+	if _, e := ReadVec3s(f, 0); e == nil {
+		t.Errorf("wrong type: expected error")
+	}
+}
@@ -1,5 +1,6 @@
-// Package gltf implements a glTF 2.0 loader.
-// The package does NOT peform any validation.
+// Package gltf implements a glTF 2.0 loader and writer.
+// Loading does NOT perform any validation. Use Validate to check a file
+// before using it.
 package gltf
 
 import "encoding/json"
@@ -10,16 +11,27 @@ import "encoding/json"
 
 // Accessor is a typed view into a bufferView.
 type Accessor struct {
-	BufferView    uint           `json:"bufferView"`    // The index of the bufferView.
-	ByteOffset    uint           `json:"byteOffset"`    // The offset relative to the start of the bufferView in bytes.
-	ComponentType uint           `json:"componentType"` // The datatype of components in the attribute.
-	Normalized    bool           `json:"normalized"`    // Specifies whether integer data values should be normalized.
-	Count         uint           `json:"count"`         // The number of attributes referenced by this accessor.
-	Type          string         `json:"type"`          // Specifies if the attribute is a scalar, vector, or matrix.
-	Max           []uint         `json:"max"`           // Maximum value of each component in this attribute.
-	Min           []uint         `json:"min"`           // Minimum value of each component in this attribute.
-	Name          string         `json:"name"`          // The name of the accessor
-	Sparse        AccessorSparse `json:"sparse"`        // Sparse storage of attributes that deviate from their initialization value.
+	BufferView    int            `json:"bufferView,omitempty"` // The index of the bufferView.
+	ByteOffset    uint           `json:"byteOffset,omitempty"` // The offset relative to the start of the bufferView in bytes.
+	ComponentType uint           `json:"componentType"`        // The datatype of components in the attribute.
+	Normalized    bool           `json:"normalized,omitempty"` // Specifies whether integer data values should be normalized.
+	Count         uint           `json:"count"`                // The number of attributes referenced by this accessor.
+	Type          string         `json:"type"`                 // Specifies if the attribute is a scalar, vector, or matrix.
+	Max           []float32      `json:"max,omitempty"`        // Maximum value of each component in this attribute.
+	Min           []float32      `json:"min,omitempty"`        // Minimum value of each component in this attribute.
+	Name          string         `json:"name,omitempty"`       // The name of the accessor
+	Sparse        AccessorSparse `json:"sparse,omitempty"`     // Sparse storage of attributes that deviate from their initialization value.
+}
+
+// UnmarshalJSON sets default values for Accessor.
+func (a *Accessor) UnmarshalJSON(d []byte) error {
+	type alias Accessor
+	out := &alias{
+		BufferView: -1,
+	}
+	e := json.Unmarshal(d, out)
+	*a = Accessor(*out)
+	return e
 }
 
 // AccessorSparse is the sparse storage of attributes that deviate from their initialization value.
@@ -31,15 +43,15 @@ type AccessorSparse struct {
 
 // AccessorSparseIndices are indices of those attributes that deviate from their initialization value.
 type AccessorSparseIndices struct {
-	BufferView    uint `json:"bufferView"`    // The index of the bufferView with sparse indices.
-	ByteOffset    uint `json:"byteOffset"`    // The offset relative to the start of the bufferView in bytes.
-	ComponentType uint `json:"componentType"` // The indices data type.
+	BufferView    uint `json:"bufferView"`           // The index of the bufferView with sparse indices.
+	ByteOffset    uint `json:"byteOffset,omitempty"` // The offset relative to the start of the bufferView in bytes.
+	ComponentType uint `json:"componentType"`        // The indices data type.
 }
 
 // AccessorSparseValues stores the displaced accessor attributes.
 type AccessorSparseValues struct {
-	BufferView uint `json:"bufferView"` // The index of the bufferView with sparse values.
-	ByteOffset uint `json:"byteOffset"` // The offset relative to the start of the bufferView in bytes.
+	BufferView uint `json:"bufferView"`           // The index of the bufferView with sparse values.
+	ByteOffset uint `json:"byteOffset,omitempty"` // The offset relative to the start of the bufferView in bytes.
 }
 
 ////////////////////////////////////////////////////////////////////////////////
@@ -48,9 +60,9 @@ type AccessorSparseValues struct {
 
 // Animation is a keyframe animation.
 type Animation struct {
-	Channels []AnimationChannel `json:"channels"` // An array of channels, each of which targets an animation's sampler at a node's property.
-	Samplers []AnimationSampler `json:"samplers"` // An array of samplers that combines input and output accessors with an interpolation algorithm.
-	Name     string             `json:"name"`     // The name of the animation.
+	Channels []AnimationChannel `json:"channels"`       // An array of channels, each of which targets an animation's sampler at a node's property.
+	Samplers []AnimationSampler `json:"samplers"`       // An array of samplers that combines input and output accessors with an interpolation algorithm.
+	Name     string             `json:"name,omitempty"` // The name of the animation.
 }
 
 // AnimationChannel targets an animation's sampler at a node's property.
@@ -67,9 +79,9 @@ type AnimationChannelTarget struct {
 
 // AnimationSampler combines input and output accessors with an interpolation algorithm to define a keyframe graph.
 type AnimationSampler struct {
-	Input         uint   `json:"input"`         // The index of an accessor containing keyframe input values.
-	Interpolation string `json:"interpolation"` // Interpolation algorithm.
-	Output        uint   `json:"output"`        // The index of an accessor, containing keyframe output values.
+	Input         uint   `json:"input"`                   // The index of an accessor containing keyframe input values.
+	Interpolation string `json:"interpolation,omitempty"` // Interpolation algorithm.
+	Output        uint   `json:"output"`                  // The index of an accessor, containing keyframe output values.
 }
 
 // UnmarshalJSON sets default values for AnimationSampler.
@@ -89,10 +101,10 @@ func (as *AnimationSampler) UnmarshalJSON(d []byte) error {
 
 // Asset holds metadata about the glTF asset.
 type Asset struct {
-	Copyright  string `json:"copyright"`  // A copyright message suitable for display to credit the content creator.
-	Generator  string `json:"generator"`  // Tool that generated this glTF model.
-	Version    string `json:"version"`    // The glTF version that this asset targets.
-	MinVersion string `json:"minVersion"` // The minimum glTF version that this asset targets.
+	Copyright  string `json:"copyright,omitempty"`  // A copyright message suitable for display to credit the content creator.
+	Generator  string `json:"generator,omitempty"`  // Tool that generated this glTF model.
+	Version    string `json:"version"`              // The glTF version that this asset targets.
+	MinVersion string `json:"minVersion,omitempty"` // The minimum glTF version that this asset targets.
 }
 
 ////////////////////////////////////////////////////////////////////////////////
@@ -101,9 +113,9 @@ type Asset struct {
 
 // Buffer points to binary geometry, animation, or skins.
 type Buffer struct {
-	URI        string `json:"uri"`        // The uri of the buffer.
-	ByteLength uint   `json:"byteLength"` // The length of the buffer in bytes.
-	Name       string `json:"name"`       // The name of the buffer
+	URI        string `json:"uri,omitempty"`  // The uri of the buffer.
+	ByteLength uint   `json:"byteLength"`     // The length of the buffer in bytes.
+	Name       string `json:"name,omitempty"` // The name of the buffer
 }
 
 ////////////////////////////////////////////////////////////////////////////////
@@ -112,12 +124,12 @@ type Buffer struct {
 
 // BufferView is a view into a buffer generally representing a subset of the buffer
 type BufferView struct {
-	Buffer     uint   `json:"buffer"`     // The index of the buffer.
-	ByteOffset uint   `json:"byteOffset"` // The offset into the buffer in bytes.
-	ByteLength uint   `json:"byteLength"` // The length of the bufferView in bytes.
-	ByteStride uint   `json:"byteStride"` // The stride, in bytes.
-	Target     uint   `json:"target"`     // The target that the GPU buffer should be bound to.
-	Name       string `json:"name"`       // The name of the buffer view
+	Buffer     uint   `json:"buffer"`               // The index of the buffer.
+	ByteOffset uint   `json:"byteOffset,omitempty"` // The offset into the buffer in bytes.
+	ByteLength uint   `json:"byteLength"`           // The length of the bufferView in bytes.
+	ByteStride uint   `json:"byteStride,omitempty"` // The stride, in bytes.
+	Target     uint   `json:"target,omitempty"`     // The target that the GPU buffer should be bound to.
+	Name       string `json:"name,omitempty"`       // The name of the buffer view
 }
 
 ////////////////////////////////////////////////////////////////////////////////
@@ -126,10 +138,10 @@ type BufferView struct {
 
 // Camera holds a camera's projection.
 type Camera struct {
-	Orthographic CameraOrthographic `json:"orthographic"` // Properties for creating an orthographic projection matrix.
-	Perspective  CameraPerspective  `json:"perspective"`  // Properties for creating a perspective projection matrix.
-	Type         string             `json:"type"`         // Specifies if the camera uses a perspective or orthographic projection.
-	Name         string             `json:"name"`         // The name of the camera.
+	Orthographic CameraOrthographic `json:"orthographic,omitempty"` // Properties for creating an orthographic projection matrix.
+	Perspective  CameraPerspective  `json:"perspective,omitempty"`  // Properties for creating a perspective projection matrix.
+	Type         string             `json:"type"`                   // Specifies if the camera uses a perspective or orthographic projection.
+	Name         string             `json:"name,omitempty"`         // The name of the camera.
 }
 
 // CameraOrthographic is an orthographic camera containing properties to create an orthographic projection matrix.
@@ -142,10 +154,10 @@ type CameraOrthographic struct {
 
 // CameraPerspective is a perspective camera containing properties to create a perspective projection matrix.
 type CameraPerspective struct {
-	AspectRatio float32 `json:"aspectRatio"` // The floating-point aspect ratio of the field of view.
-	Yfov        float32 `json"yfov"`         // The floating-point vertical field of view in radians.
-	Zfar        float32 `json:"zfar"`        // The floating-point distance to the far clipping plane.
-	Znear       float32 `json:"znear"`       // The floating-point distance to the near clipping plane.
+	AspectRatio float32 `json:"aspectRatio,omitempty"` // The floating-point aspect ratio of the field of view.
+	Yfov        float32 `json:"yfov"`                  // The floating-point vertical field of view in radians.
+	Zfar        float32 `json:"zfar,omitempty"`        // The floating-point distance to the far clipping plane.
+	Znear       float32 `json:"znear"`                 // The floating-point distance to the near clipping plane.
 }
 
 ////////////////////////////////////////////////////////////////////////////////
@@ -154,23 +166,34 @@ type CameraPerspective struct {
 
 // GlTF is the root object for a glTF asset
 type GlTF struct {
-	ExtensionsUsed     []string     `json:"extensionsUsed"`     // Names of glTF extensions used somewhere in this asset.
-	ExtensionsRequired []string     `json:"extensionsRequired"` // Names of glTF extensions required to properly load this asset.
-	Accessors          []Accessor   `json:"accessors"`          // An array of accessors.
-	Animations         []Animation  `json"animations"`          // An array of keyframe animations.
-	Asset              Asset        `json:"asset"`              // Metadata about the glTF asset.
-	Buffers            []Buffer     `json:"buffers"`            // An array of buffers.
-	BufferViews        []BufferView `json:"bufferViews"`        // An array of bufferViews.
-	Cameras            []Camera     `json:"cameras"`            // An array of cameras.
-	Images             []Image      `json:"images"`             // An array of images.
-	Materials          []Material   `json:"materials"`          // An array of materials.
-	Meshes             []Mesh       `json:"meshes"`             // An array of meshes.
-	Nodes              []Node       `json:"nodes"`              // An array of nodes.
-	Samplers           []Sampler    `json:"samplers"`           // An array of samplers.
-	Scene              uint         `json:"scene"`              // The index of the default scene.
-	Scenes             []Scene      `json:"scenes"`             // An array of scenes.
-	Skins              []Skin       `json:"skins"`              // An array of skins.
-	Textures           []Texture    `json:"textures"`           // An array of textures.
+	ExtensionsUsed     []string     `json:"extensionsUsed,omitempty"`     // Names of glTF extensions used somewhere in this asset.
+	ExtensionsRequired []string     `json:"extensionsRequired,omitempty"` // Names of glTF extensions required to properly load this asset.
+	Accessors          []Accessor   `json:"accessors,omitempty"`          // An array of accessors.
+	Animations         []Animation  `json:"animations,omitempty"`         // An array of keyframe animations.
+	Asset              Asset        `json:"asset,omitempty"`              // Metadata about the glTF asset.
+	Buffers            []Buffer     `json:"buffers,omitempty"`            // An array of buffers.
+	BufferViews        []BufferView `json:"bufferViews,omitempty"`        // An array of bufferViews.
+	Cameras            []Camera     `json:"cameras,omitempty"`            // An array of cameras.
+	Images             []Image      `json:"images,omitempty"`             // An array of images.
+	Materials          []Material   `json:"materials,omitempty"`          // An array of materials.
+	Meshes             []Mesh       `json:"meshes,omitempty"`             // An array of meshes.
+	Nodes              []Node       `json:"nodes,omitempty"`              // An array of nodes.
+	Samplers           []Sampler    `json:"samplers,omitempty"`           // An array of samplers.
+	Scene              int          `json:"scene,omitempty"`              // The index of the default scene.
+	Scenes             []Scene      `json:"scenes,omitempty"`             // An array of scenes.
+	Skins              []Skin       `json:"skins,omitempty"`              // An array of skins.
+	Textures           []Texture    `json:"textures,omitempty"`           // An array of textures.
+}
+
+// UnmarshalJSON sets default values for GlTF.
+func (g *GlTF) UnmarshalJSON(d []byte) error {
+	type alias GlTF
+	out := &alias{
+		Scene: -1,
+	}
+	e := json.Unmarshal(d, out)
+	*g = GlTF(*out)
+	return e
 }
 
 ////////////////////////////////////////////////////////////////////////////////
@@ -179,10 +202,21 @@ type GlTF struct {
 
 // Image holds data used to create a texture.
 type Image struct {
-	URI        string `json:"uri"`        // The uri of the image.
-	MimeType   string `json:"mimeType"`   // The image's MIME type.
-	BufferView uint   `json:"bufferView"` // The index of the bufferView that contains the image.
-	Name       string `json:"name"`       // The name of the image.
+	URI        string `json:"uri,omitempty"`        // The uri of the image.
+	MimeType   string `json:"mimeType,omitempty"`   // The image's MIME type.
+	BufferView int    `json:"bufferView,omitempty"` // The index of the bufferView that contains the image.
+	Name       string `json:"name,omitempty"`       // The name of the image.
+}
+
+// UnmarshalJSON sets default values for Image.
+func (i *Image) UnmarshalJSON(d []byte) error {
+	type alias Image
+	out := &alias{
+		BufferView: -1,
+	}
+	e := json.Unmarshal(d, out)
+	*i = Image(*out)
+	return e
 }
 
 ////////////////////////////////////////////////////////////////////////////////
@@ -191,15 +225,15 @@ type Image struct {
 
 // Material describes the material appearance of a primitive.
 type Material struct {
-	Name                 string                       `json:"name"`                 // The name of the material.
-	PbrMetallicRoughness MaterialPbrMetallicRoughness `json:"pbrMetallicRoughness"` // A set of parameters used to define the metallic-roughness material model.
-	NormalTexture        MaterialNormalTextureInfo    `json:"normalTexture"`        // A tangent space normal map.
-	OcclusionTexture     MaterialOcclusionTextureInfo `json:"occlusionTexture"`     // The occlusion map texture.
-	EmissiveTexture      TextureInfo                  `json:"emissiveTexture"`      // The emissive map texture.
-	EmissiveFactor       []float32                    `json:"emissiveFactor"`       // The emissive color of the material.
-	AlphaMode            string                       `json:"alphaMode"`            // The alpha rendering mode of the material.
-	AlphaCutoff          float32                      `json:"alphaCutoff"`          // The alpha cutoff value of the material.
-	DoubleSided          bool                         `json:"doubleSided"`          // Specifies whether the material is double sided.
+	Name                 string                       `json:"name,omitempty"`                 // The name of the material.
+	PbrMetallicRoughness MaterialPbrMetallicRoughness `json:"pbrMetallicRoughness,omitempty"` // A set of parameters used to define the metallic-roughness material model.
+	NormalTexture        MaterialNormalTextureInfo    `json:"normalTexture,omitempty"`        // A tangent space normal map.
+	OcclusionTexture     MaterialOcclusionTextureInfo `json:"occlusionTexture,omitempty"`     // The occlusion map texture.
+	EmissiveTexture      TextureInfo                  `json:"emissiveTexture,omitempty"`      // The emissive map texture.
+	EmissiveFactor       []float32                    `json:"emissiveFactor,omitempty"`       // The emissive color of the material.
+	AlphaMode            string                       `json:"alphaMode,omitempty"`            // The alpha rendering mode of the material.
+	AlphaCutoff          float32                      `json:"alphaCutoff"`                    // The alpha cutoff value of the material.
+	DoubleSided          bool                         `json:"doubleSided,omitempty"`          // Specifies whether the material is double sided.
 }
 
 // UnmarshalJSON sets default values for Material.
@@ -214,6 +248,15 @@ func (m *Material) UnmarshalJSON(d []byte) error {
 			Index:    -1,
 			TexCoord: -1,
 		},
+		OcclusionTexture: MaterialOcclusionTextureInfo{
+			Strength: 1,
+			Index:    -1,
+			TexCoord: -1,
+		},
+		EmissiveTexture: TextureInfo{
+			Index:    -1,
+			TexCoord: -1,
+		},
 	}
 	e := json.Unmarshal(d, out)
 	*m = Material(*out)
@@ -222,11 +265,11 @@ func (m *Material) UnmarshalJSON(d []byte) error {
 
 // MaterialPbrMetallicRoughness describes a material PBR Metallic Roughness.
 type MaterialPbrMetallicRoughness struct {
-	BaseColorFactor          []float32   `json:"baseColorFactor"`
-	BaseColorTexture         TextureInfo `json:"baseColorTexture"`
+	BaseColorFactor          []float32   `json:"baseColorFactor,omitempty"`
+	BaseColorTexture         TextureInfo `json:"baseColorTexture,omitempty"`
 	MetallicFactor           float32     `json:"metallicFactor"`
 	RoughnessFactor          float32     `json:"roughnessFactor"`
-	MetallicRoughnessTexture TextureInfo `json:"metallicRoughnessTexture"`
+	MetallicRoughnessTexture TextureInfo `json:"metallicRoughnessTexture,omitempty"`
 }
 
 // UnmarshalJSON sets default values for MaterialPbrMetallicRoughness.
@@ -238,6 +281,10 @@ func (m *MaterialPbrMetallicRoughness) UnmarshalJSON(d []byte) error {
 			Index:    -1,
 			TexCoord: -1,
 		},
+		MetallicRoughnessTexture: TextureInfo{
+			Index:    -1,
+			TexCoord: -1,
+		},
 		MetallicFactor:  1,
 		RoughnessFactor: 1,
 	}
@@ -250,28 +297,26 @@ func (m *MaterialPbrMetallicRoughness) UnmarshalJSON(d []byte) error {
 type MaterialNormalTextureInfo struct {
 	Scale    float32 `json:"scale"` // The scalar multiplier applied to each normal vector of the normal texture.
 	Index    int     `json:"index"`
-	TexCoord int     `json:"texCoord"`
+	TexCoord int     `json:"texCoord,omitempty"`
 }
 
 // UnmarshalJSON sets default values for MaterialNormalTextureInfo.
-// func (m *MaterialNormalTextureInfo) UnmarshalJSON(d []byte) error {
-// 	type alias MaterialNormalTextureInfo
-// 	out := &alias{
-// 		Scale:    1,
-// 		Index:    -1,
-// 		TexCoord: -1,
-// 	}
-// 	e := json.Unmarshal(d, out)
-// 	*m = MaterialNormalTextureInfo(*out)
-// 	return e
-// }
+func (m *MaterialNormalTextureInfo) UnmarshalJSON(d []byte) error {
+	type alias MaterialNormalTextureInfo
+	out := &alias{
+		Scale: 1,
+		Index: -1,
+	}
+	e := json.Unmarshal(d, out)
+	*m = MaterialNormalTextureInfo(*out)
+	return e
+}
 
 // MaterialOcclusionTextureInfo holds material occlusion texture info
 type MaterialOcclusionTextureInfo struct {
-	Strength float32 `json:"strength"` // A scalar multiplier controlling the amount of occlusion applied.
-	// Ignored properties:
-	// * index
-	// * texCoord
+	Strength float32 `json:"strength"`           // A scalar multiplier controlling the amount of occlusion applied.
+	Index    int     `json:"index"`              // The index of the texture.
+	TexCoord int     `json:"texCoord,omitempty"` // The set index of texture's TEXCOORD attribute used for texture coordinate mapping.
 }
 
 // UnmarshalJSON sets default values for MaterialOcclusionTextureInfo.
@@ -279,6 +324,7 @@ func (m *MaterialOcclusionTextureInfo) UnmarshalJSON(d []byte) error {
 	type alias MaterialOcclusionTextureInfo
 	out := &alias{
 		Strength: 1,
+		Index:    -1,
 	}
 	e := json.Unmarshal(d, out)
 	*m = MaterialOcclusionTextureInfo(*out)
@@ -291,18 +337,18 @@ func (m *MaterialOcclusionTextureInfo) UnmarshalJSON(d []byte) error {
 
 // Mesh is a set of primitives to be rendered.
 type Mesh struct {
-	Primitives []MeshPrimitive `json:"primitives"` // An array of primitives, each defining geometry to be rendered with a material.
-	Weights    []float32       `json:"weights"`    // Array of weights to be applied to the Morph Targets.
-	Name       string          `json:"name"`       // The name of the mesh
+	Primitives []MeshPrimitive `json:"primitives"`        // An array of primitives, each defining geometry to be rendered with a material.
+	Weights    []float32       `json:"weights,omitempty"` // Array of weights to be applied to the Morph Targets.
+	Name       string          `json:"name,omitempty"`    // The name of the mesh
 }
 
 // MeshPrimitive is the geometry to be rendered with the given material.
 type MeshPrimitive struct {
-	Attributes map[string]uint   `json:"attributes"` // A dictionary object, where each key corresponds to mesh attribute semantic.
-	Indices    int               `json:"indices"`    // The index of the accessor that contains the indices.
-	Material   int               `json:"material"`   // The index of the material to apply to this primitive when rendering.
-	Mode       uint              `json:"mode"`       // The type of primitives to render.
-	Targets    []map[string]uint `json:"targets"`    // An array of Morph Targets
+	Attributes map[string]uint   `json:"attributes"`         // A dictionary object, where each key corresponds to mesh attribute semantic.
+	Indices    int               `json:"indices,omitempty"`  // The index of the accessor that contains the indices.
+	Material   int               `json:"material,omitempty"` // The index of the material to apply to this primitive when rendering.
+	Mode       uint              `json:"mode"`               // The type of primitives to render.
+	Targets    []map[string]uint `json:"targets,omitempty"`  // An array of Morph Targets
 }
 
 // UnmarshalJSON sets default values for MeshPrimitive.
@@ -324,23 +370,25 @@ func (m *MeshPrimitive) UnmarshalJSON(d []byte) error {
 
 // Node is a node in the node hierarchy.
 type Node struct {
-	Camera      uint      `json:"camera"`      // The index of the camera referenced by this node.
-	Children    []uint    `json:"children"`    // The indices of this node's children.
-	Skin        uint      `json:"skin"`        // The index of the skin referenced by this node.
-	Matrix      []float32 `json:"matrix"`      // A floating-point 4x4 transformation matrix stored in column-major order.
-	Mesh        int       `json:"mesh"`        // The index of the mesh in this node.
-	Rotation    []float32 `json:"rotation"`    // The node's unit quaternion rotation.
-	Scale       []float32 `json:"scale"`       // The node's non-uniform scale
-	Translation []float32 `json:"translation"` // The node's translation along the x, y, and z axes.
-	Weights     []float32 `json:"weights"`     // The weights of the instantiated Morph Target.
-	Name        string    `json:"name"`        // The name of the node
+	Camera      int       `json:"camera,omitempty"`      // The index of the camera referenced by this node.
+	Children    []uint    `json:"children,omitempty"`    // The indices of this node's children.
+	Skin        int       `json:"skin,omitempty"`        // The index of the skin referenced by this node.
+	Matrix      []float32 `json:"matrix,omitempty"`      // A floating-point 4x4 transformation matrix stored in column-major order.
+	Mesh        int       `json:"mesh,omitempty"`        // The index of the mesh in this node.
+	Rotation    []float32 `json:"rotation,omitempty"`    // The node's unit quaternion rotation.
+	Scale       []float32 `json:"scale,omitempty"`       // The node's non-uniform scale
+	Translation []float32 `json:"translation,omitempty"` // The node's translation along the x, y, and z axes.
+	Weights     []float32 `json:"weights,omitempty"`     // The weights of the instantiated Morph Target.
+	Name        string    `json:"name,omitempty"`        // The name of the node
 }
 
 // UnmarshalJSON sets default values for Node.
 func (n *Node) UnmarshalJSON(d []byte) error {
 	type alias Node
 	out := &alias{
+		Camera:      -1,
 		Mesh:        -1,
+		Skin:        -1,
 		Matrix:      []float32{1, 0, 0, 0, 0, 1, 0, 0, 0, 0, 1, 0, 0, 0, 0, 1},
 		Rotation:    []float32{0, 0, 0, 1},
 		Scale:       []float32{1, 1, 1},
@@ -357,11 +405,11 @@ func (n *Node) UnmarshalJSON(d []byte) error {
 
 // Sampler holds texture sampler properties for filtering and wrapping modes.
 type Sampler struct {
-	MagFilter uint   `json:"magFilter"` // Magnification filter.
-	MinFilter uint   `json:"minFilter"` // Minification filter.
-	WrapS     uint   `json:"wrapS"`     // s wrapping mode.
-	WrapT     uint   `json:"wrapT"`     // t wrapping mode.
-	Name      string `json:"name"`      // The name of the sampler.
+	MagFilter uint   `json:"magFilter,omitempty"` // Magnification filter.
+	MinFilter uint   `json:"minFilter,omitempty"` // Minification filter.
+	WrapS     uint   `json:"wrapS,omitempty"`     // s wrapping mode.
+	WrapT     uint   `json:"wrapT,omitempty"`     // t wrapping mode.
+	Name      string `json:"name,omitempty"`      // The name of the sampler.
 }
 
 // UnmarshalJSON sets default values for Sampler.
@@ -382,8 +430,8 @@ func (s *Sampler) UnmarshalJSON(d []byte) error {
 
 // Scene contains the root nodes of a scene.
 type Scene struct {
-	Nodes []uint `json:"nodes"` // The indices of each root node.
-	Name  string `json:"name"`  // The name of the scene.
+	Nodes []uint `json:"nodes,omitempty"` // The indices of each root node.
+	Name  string `json:"name,omitempty"`  // The name of the scene.
 }
 
 ////////////////////////////////////////////////////////////////////////////////
@@ -392,10 +440,22 @@ type Scene struct {
 
 // Skin holds joints and matrices defining a skin.
 type Skin struct {
-	InverseBindMatrices uint   `json:"inverseBindMatrices"` // The index of the accessor containing the floating-point 4x4 inverse-bind matrices.
-	Skeleton            uint   `json:"skeleton"`            // The index of the node used as a skeleton root.
-	Joints              []uint `json:"joints"`              // Indices of skeleton nodes, used as joints in this skin.
-	Name                string `json:"name"`                // The name of the skin.
+	InverseBindMatrices int    `json:"inverseBindMatrices,omitempty"` // The index of the accessor containing the floating-point 4x4 inverse-bind matrices.
+	Skeleton            int    `json:"skeleton,omitempty"`            // The index of the node used as a skeleton root.
+	Joints              []uint `json:"joints"`                        // Indices of skeleton nodes, used as joints in this skin.
+	Name                string `json:"name,omitempty"`                // The name of the skin.
+}
+
+// UnmarshalJSON sets default values for Skin.
+func (s *Skin) UnmarshalJSON(d []byte) error {
+	type alias Skin
+	out := &alias{
+		InverseBindMatrices: -1,
+		Skeleton:            -1,
+	}
+	e := json.Unmarshal(d, out)
+	*s = Skin(*out)
+	return e
 }
 
 ////////////////////////////////////////////////////////////////////////////////
@@ -404,9 +464,21 @@ type Skin struct {
 
 // Texture holds a texture and its sampler.
 type Texture struct {
-	Sampler uint   `json:"sampler"` // The index of the sampler used by this texture.
-	Source  uint   `json:"source"`  // The index of the image used by this texture.
-	Name    string `json:"name"`    // The name of the texture.
+	Sampler int    `json:"sampler,omitempty"` // The index of the sampler used by this texture.
+	Source  int    `json:"source,omitempty"`  // The index of the image used by this texture.
+	Name    string `json:"name,omitempty"`    // The name of the texture.
+}
+
+// UnmarshalJSON sets default values for Texture.
+func (t *Texture) UnmarshalJSON(d []byte) error {
+	type alias Texture
+	out := &alias{
+		Sampler: -1,
+		Source:  -1,
+	}
+	e := json.Unmarshal(d, out)
+	*t = Texture(*out)
+	return e
 }
 
 ////////////////////////////////////////////////////////////////////////////////
@@ -414,7 +486,19 @@ type Texture struct {
 ////////////////////////////////////////////////////////////////////////////////
 
 // TextureInfo holds a reference to a texture.
+// Texture infos which are not defined have a TexCoord of -1.
 type TextureInfo struct {
-	Index    int `json:"index"`    // The index of the texture.
-	TexCoord int `json:"texCoord"` // The set index of texture's TEXCOORD attribute used for texture coordinate mapping.
+	Index    int `json:"index"`              // The index of the texture.
+	TexCoord int `json:"texCoord,omitempty"` // The set index of texture's TEXCOORD attribute used for texture coordinate mapping.
+}
+
+// UnmarshalJSON sets default values for TextureInfo.
+func (t *TextureInfo) UnmarshalJSON(d []byte) error {
+	type alias TextureInfo
+	out := &alias{
+		Index: -1,
+	}
+	e := json.Unmarshal(d, out)
+	*t = TextureInfo(*out)
+	return e
 }
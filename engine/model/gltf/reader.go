@@ -1,15 +1,25 @@
-// Package gltf implements a glTF 2.0 loader.
-// The package does NOT peform any validation.
+// Package gltf implements a glTF 2.0 loader and writer.
+// Loading does NOT perform any validation. Use Validate to check a file
+// before using it.
 package gltf
 
 import (
 	"encoding/base64"
 	"encoding/binary"
 	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
 	"io/ioutil"
 	"path/filepath"
+	"strconv"
 	"strings"
+	"sync"
 
+	mgl "github.com/go-gl/mathgl/mgl32"
+
+	"github.com/patrick-jessen/goplay/engine/asset"
+	"github.com/patrick-jessen/goplay/engine/bounds"
 	"github.com/patrick-jessen/goplay/engine/model/geometry"
 )
 
@@ -19,37 +29,66 @@ type File struct {
 	Chunks   [][]byte // Only available when loading .glb
 	Location string   // Directory of the file.
 	File     string   // Path to the file.
+
+	mu      sync.Mutex
+	buffers map[uint][]byte // Data of the buffers which have been read, by index.
 }
 
 // Load loads a glTF file.
 // Accepts both .gltf and .glb.
+// Panics if the file cannot be loaded.
 func Load(file string) *File {
-	data := readFile(file)
+	f, e := TryLoad(file)
+	if e != nil {
+		panic(e)
+	}
+	return f
+}
+
+// TryLoad loads a glTF file.
+// Accepts both .gltf and .glb.
+// Errors are of type *asset.Error.
+func TryLoad(file string) (*File, error) {
+	data, e := ioutil.ReadFile(file)
+	if e != nil {
+		return nil, &asset.Error{File: file, Err: e}
+	}
 	out := &File{
 		Location: filepath.Dir(file),
 		File:     file,
 	}
 
 	if strings.HasSuffix(file, ".gltf") {
-		json.Unmarshal(data, &out.GlTF)
+		if e = json.Unmarshal(data, &out.GlTF); e != nil {
+			return nil, &asset.Error{File: file, Field: jsonField(e), Err: e}
+		}
 	} else if strings.HasSuffix(file, ".glb") {
 		iter := 0
-		readGlbHeader(&iter, data)
-		for iter < len(data) {
-			d, t := readGlbChunk(&iter, data)
+		if e = readGlbHeader(&iter, data); e != nil {
+			return nil, &asset.Error{File: file, Field: "header", Err: e}
+		}
+		for chunk := 0; iter < len(data); chunk++ {
+			field := fmt.Sprintf("chunk %v", chunk)
+
+			d, t, e := readGlbChunk(&iter, data)
+			if e != nil {
+				return nil, &asset.Error{File: file, Field: field, Err: e}
+			}
 			switch t {
 			case typeJSON:
-				json.Unmarshal(d, &out.GlTF)
+				if e = json.Unmarshal(d, &out.GlTF); e != nil {
+					return nil, &asset.Error{File: file, Field: field + ": " + jsonField(e), Err: e}
+				}
 			case typeBIN:
 				out.Chunks = append(out.Chunks, d)
 			default:
-				panic("Invalid chunk type")
+				return nil, &asset.Error{File: file, Field: field, Err: fmt.Errorf("invalid chunk type 0x%08X", t)}
 			}
 		}
 	} else {
-		panic("Invalid file format")
+		return nil, &asset.Error{File: file, Err: errors.New("invalid file format")}
 	}
-	return out
+	return out, nil
 }
 
 const (
@@ -59,70 +98,89 @@ const (
 	typeBIN      = 0x004E4942
 )
 
-// readFile reads all bytes from a file.
-func readFile(file string) []byte {
-	data, e := ioutil.ReadFile(file)
-	if e != nil {
-		panic(e)
+// jsonField returns the field causing a JSON error, if known.
+func jsonField(e error) string {
+	if te, ok := e.(*json.UnmarshalTypeError); ok {
+		return te.Field
 	}
-	return data
+	return "json"
 }
 
 // readInt reads a single 32 bit unsigned int from the file
-func readInt(iter *int, d []byte) (res uint) {
+func readInt(iter *int, d []byte) (res uint, e error) {
+	if *iter+4 > len(d) {
+		return 0, io.ErrUnexpectedEOF
+	}
 	res = uint(binary.LittleEndian.Uint32(d[*iter:]))
 	*iter += 4
 	return
 }
 
 // readBytes reads a number of bytes from the file
-func readBytes(iter *int, d []byte, len uint) (res []byte) {
-	res = d[*iter : *iter+int(len)]
-	*iter += int(len)
+func readBytes(iter *int, d []byte, n uint) (res []byte, e error) {
+	end := *iter + int(n)
+	if end > len(d) || end < *iter {
+		return nil, io.ErrUnexpectedEOF
+	}
+	res = d[*iter:end]
+	*iter = end
 	return
 }
 
 // readGlbHeader reads and verifies the .glb header
 // Header format: [Magic:u32, Version:u32, FileLen:u32]
-func readGlbHeader(iter *int, d []byte) {
+func readGlbHeader(iter *int, d []byte) error {
 	// Make sure this is glTF format
-	if readInt(iter, d) != magicValue {
-		panic("File is not of glTF format")
+	if v, e := readInt(iter, d); e != nil {
+		return e
+	} else if v != magicValue {
+		return errors.New("file is not of glTF format")
 	}
 	// Make sure this is the right version of the format
-	if readInt(iter, d) != versionValue {
-		panic("glTF file is not the right version")
+	if v, e := readInt(iter, d); e != nil {
+		return e
+	} else if v != versionValue {
+		return fmt.Errorf("unsupported glTF version %v", v)
 	}
 	// Read length
-	_ = readInt(iter, d)
+	_, e := readInt(iter, d)
+	return e
 }
 
 // readGlbChunk reads a data chunk
 // Chunk format: [dataLen:u32, type:u32, data:u8[]]
-func readGlbChunk(iter *int, d []byte) ([]byte, uint) {
-	length := readInt(iter, d)
-	ctype := readInt(iter, d)
-	data := readBytes(iter, d, length)
-	return data, ctype
+func readGlbChunk(iter *int, d []byte) ([]byte, uint, error) {
+	length, e := readInt(iter, d)
+	if e != nil {
+		return nil, 0, e
+	}
+	ctype, e := readInt(iter, d)
+	if e != nil {
+		return nil, 0, e
+	}
+	data, e := readBytes(iter, d, length)
+	return data, ctype, e
 }
 
 // bufferFromAccessor creates a buffer object from a gltf accessor.
-func bufferFromAccessor(g *File, a *Accessor) geometry.Buffer {
-	bufView := &g.GlTF.BufferViews[a.BufferView]
-	data := dataFromBufferView(g, bufView)
+func bufferFromAccessor(g *File, idx uint) (geometry.Buffer, error) {
+	data, e := accessorData(g, idx)
+	if e != nil {
+		return geometry.Buffer{}, e
+	}
+	a := &g.GlTF.Accessors[idx]
 
 	return geometry.Buffer{
-		ByteOffset:    int(a.ByteOffset),
-		ByteStride:    int32(bufView.ByteStride),
 		ComponentType: uint32(a.ComponentType),
 		Normalized:    a.Normalized,
 		NumComponents: numComponentsInType(a.Type),
+		Count:         int32(a.Count),
 		Data:          data,
-	}
+	}, nil
 }
 
 // numComponentsInType returns the number of components in a type.
-// e.g. VEC2 has 2 components.
+// e.g. VEC2 has 2 components. Returns 0 for invalid types.
 func numComponentsInType(t string) int32 {
 	switch t {
 	case "SCALAR":
@@ -140,75 +198,206 @@ func numComponentsInType(t string) int32 {
 	case "MAT4":
 		return 16
 	default:
-		panic("Invalid data type: " + t)
+		return 0
 	}
 }
 
-// dataFromBufferView returns the data associated with a buffer view.
-func dataFromBufferView(g *File, b *BufferView) []byte {
-	buffer := g.GlTF.Buffers[b.Buffer]
-	var data []byte
-	var e error
+// bufferData returns the data of a buffer.
+// Each buffer is read or decoded once, and then kept by the file.
+func bufferData(g *File, idx uint) ([]byte, error) {
+	g.mu.Lock()
+	defer g.mu.Unlock()
+
+	if data, ok := g.buffers[idx]; ok {
+		return data, nil
+	}
+	data, e := readBuffer(g, idx)
+	if e != nil {
+		return nil, e
+	}
+	if g.buffers == nil {
+		g.buffers = make(map[uint][]byte)
+	}
+	g.buffers[idx] = data
+	return data, nil
+}
+
+// readBuffer reads the data of a buffer.
+func readBuffer(g *File, idx uint) ([]byte, error) {
+	if idx >= uint(len(g.GlTF.Buffers)) {
+		return nil, fmt.Errorf("buffer %v does not exist", idx)
+	}
+	buffer := g.GlTF.Buffers[idx]
 
 	if len(buffer.URI) == 0 {
 		// Load from blob
-		data = g.Chunks[b.Buffer]
+		if idx >= uint(len(g.Chunks)) {
+			return nil, fmt.Errorf("buffer %v has no binary chunk", idx)
+		}
+		return g.Chunks[idx], nil
 
 	} else if strings.HasPrefix(buffer.URI, "data:") {
 		// Load from data URI
 		idx := strings.Index(buffer.URI, ";base64,") + len(";base64,")
-		data, e = base64.StdEncoding.DecodeString(buffer.URI[idx:])
-		if e != nil {
-			panic(e)
-		}
+		return base64.StdEncoding.DecodeString(buffer.URI[idx:])
+	}
 
-	} else {
-		// Load from file
-		data, e = ioutil.ReadFile(g.Location + "/" + buffer.URI)
-		if e != nil {
-			panic(e)
-		}
+	// Load from file
+	return ioutil.ReadFile(g.Location + "/" + buffer.URI)
+}
+
+// dataFromBufferView returns the data associated with a buffer view.
+func dataFromBufferView(g *File, b *BufferView) ([]byte, error) {
+	data, e := bufferData(g, b.Buffer)
+	if e != nil {
+		return nil, e
 	}
 
-	return data[b.ByteOffset : b.ByteOffset+b.ByteLength]
+	if b.ByteOffset+b.ByteLength > uint(len(data)) {
+		return nil, fmt.Errorf("bufferView exceeds buffer %v", b.Buffer)
+	}
+	return data[b.ByteOffset : b.ByteOffset+b.ByteLength], nil
 }
 
-// GeometryFromPrimitive creates a geometry object from a glTF primitive.
-func GeometryFromPrimitive(g *File, prim *MeshPrimitive) *geometry.Geometry {
+// GeometryFromPrimitive creates a geometry object from a glTF primitive,
+// and uploads it to the GPU.
+func GeometryFromPrimitive(g *File, prim *MeshPrimitive) (*geometry.Geometry, error) {
+	geom, e := DecodePrimitive(g, prim)
+	if e != nil {
+		return nil, e
+	}
+	geom.Initialize()
+	return geom, nil
+}
+
+// parseSemantic splits an attribute semantic into its name and set index,
+// such as TEXCOORD and 0 for TEXCOORD_0. The set index is -1 for semantics
+// without sets. Returns false if a semantic with sets has no valid index.
+func parseSemantic(key string) (string, int, bool) {
+	name, idx, _ := strings.Cut(key, "_")
+	switch name {
+	case "TEXCOORD", "COLOR", "JOINTS", "WEIGHTS":
+		set, e := strconv.Atoi(idx)
+		return name, set, e == nil && set >= 0
+	}
+	return key, -1, true
+}
+
+// DecodePrimitive creates a geometry object from a glTF primitive, without
+// uploading it. Unlike GeometryFromPrimitive, it makes no OpenGL calls and
+// may be called from any goroutine.
+func DecodePrimitive(g *File, prim *MeshPrimitive) (*geometry.Geometry, error) {
 	// Create geometry
 	geom := &geometry.Geometry{
 		PrimType: uint32(prim.Mode),
 	}
 
+	var e error
 	// Specify index buffer
 	if prim.Indices >= 0 {
-		indexAccessor := &g.GlTF.Accessors[prim.Indices]
-		geom.IndexBuffer = bufferFromAccessor(g, indexAccessor)
+		if geom.IndexBuffer, e = bufferFromAccessor(g, uint(prim.Indices)); e != nil {
+			return nil, fmt.Errorf("indices: %v", e)
+		}
 	}
 
 	// Specify attribute buffers
 	for key, val := range prim.Attributes {
-		accessor := &g.GlTF.Accessors[val]
-
-		strs := strings.Split(key, "_")
-		switch strs[0] {
-		case "POSITION":
-			geom.PositionBuffer = bufferFromAccessor(g, accessor)
-		case "NORMAL":
-			geom.NormalBuffer = bufferFromAccessor(g, accessor)
-		case "TANGENT":
-			geom.TangentBuffer = bufferFromAccessor(g, accessor)
-		case "TEXCOORD":
-			if strs[1] == "0" {
-				geom.TexCoordBuffer = bufferFromAccessor(g, accessor)
+		var buf *geometry.Buffer
+
+		name, set, ok := parseSemantic(key)
+		if !ok {
+			return nil, fmt.Errorf("attributes.%v: invalid semantic", key)
+		}
+		switch {
+		case name == "POSITION":
+			buf = &geom.PositionBuffer
+		case name == "NORMAL":
+			buf = &geom.NormalBuffer
+		case name == "TANGENT":
+			buf = &geom.TangentBuffer
+		case name == "TEXCOORD" && set == 0:
+			buf = &geom.TexCoordBuffer
+		case name == "JOINTS" && set == 0:
+			buf = &geom.JointBuffer
+		case name == "WEIGHTS" && set == 0:
+			buf = &geom.WeightBuffer
+		}
+		if buf == nil {
+			continue
+		}
+		if *buf, e = bufferFromAccessor(g, val); e != nil {
+			return nil, fmt.Errorf("attributes.%v: %v", key, e)
+		}
+	}
+	geom.JointBuffer.Integer = true
+
+	// Specify morph targets
+	for i, target := range prim.Targets {
+		if i >= geometry.MaxMorphTargets {
+			break
+		}
+		if val, ok := target["POSITION"]; ok {
+			if geom.MorphPositionBuffers[i], e = bufferFromAccessor(g, val); e != nil {
+				return nil, fmt.Errorf("targets[%v].POSITION: %v", i, e)
+			}
+		}
+		if val, ok := target["NORMAL"]; ok {
+			if geom.MorphNormalBuffers[i], e = bufferFromAccessor(g, val); e != nil {
+				return nil, fmt.Errorf("targets[%v].NORMAL: %v", i, e)
 			}
-		case "COLOR":
-		case "JOINTS":
-		case "WEIGHTS":
-		default:
 		}
 	}
 
-	geom.Initialize()
-	return geom
+	geom.Bounds = primitiveBounds(g, prim)
+	return geom, nil
+}
+
+// primitiveBounds returns the bounds of a primitive's positions, grown
+// to contain the displacements of its morph targets. Returns an infinite
+// box if the accessors do not specify their minimum and maximum.
+func primitiveBounds(g *File, prim *MeshPrimitive) bounds.AABB {
+	minMax := func(idx uint) (mgl.Vec3, mgl.Vec3, bool) {
+		if idx >= uint(len(g.GlTF.Accessors)) {
+			return mgl.Vec3{}, mgl.Vec3{}, false
+		}
+		a := &g.GlTF.Accessors[idx]
+		if len(a.Min) != 3 || len(a.Max) != 3 {
+			return mgl.Vec3{}, mgl.Vec3{}, false
+		}
+		return mgl.Vec3{a.Min[0], a.Min[1], a.Min[2]}, mgl.Vec3{a.Max[0], a.Max[1], a.Max[2]}, true
+	}
+
+	idx, ok := prim.Attributes["POSITION"]
+	if !ok {
+		return bounds.Empty()
+	}
+	lo, hi, ok := minMax(idx)
+	if !ok {
+		return bounds.Infinite()
+	}
+	b := bounds.AABB{Min: lo, Max: hi}
+
+	// Assumes morph weights between 0 and 1
+	for i, target := range prim.Targets {
+		if i >= geometry.MaxMorphTargets {
+			break
+		}
+		idx, ok := target["POSITION"]
+		if !ok {
+			continue
+		}
+		lo, hi, ok := minMax(idx)
+		if !ok {
+			return bounds.Infinite()
+		}
+		for j := 0; j < 3; j++ {
+			if lo[j] < 0 {
+				b.Min[j] += lo[j]
+			}
+			if hi[j] > 0 {
+				b.Max[j] += hi[j]
+			}
+		}
+	}
+	return b
 }
@@ -0,0 +1,136 @@
+package gltf
+
+import (
+	"encoding/binary"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"testing"
+
+	mgl "github.com/go-gl/mathgl/mgl32"
+
+	"github.com/patrick-jessen/goplay/engine/asset"
+	"github.com/patrick-jessen/goplay/engine/bounds"
+)
+
+// glb encodes chunks as a .glb file.
+func glb(chunks ...[]byte) []byte {
+	out := make([]byte, 12)
+	binary.LittleEndian.PutUint32(out[0:], magicValue)
+	binary.LittleEndian.PutUint32(out[4:], versionValue)
+	for _, c := range chunks {
+		out = append(out, c...)
+	}
+	return out
+}
+
+// chunk encodes a .glb chunk.
+func chunk(typ uint32, data string) []byte {
+	out := make([]byte, 8)
+	binary.LittleEndian.PutUint32(out[0:], uint32(len(data)))
+	binary.LittleEndian.PutUint32(out[4:], typ)
+	return append(out, data...)
+}
+
+func TestTryLoad(t *testing.T) {
+	dir := t.TempDir()
+
+	tests := []struct {
+		name    string
+		file    string
+		data    []byte
+		wantErr bool
+		field   string
+	}{
+		{"valid glb", "a.glb", glb(chunk(typeJSON, `{"scene":0}`)), false, ""},
+		{"valid gltf", "a.gltf", []byte(`{"scene":0}`), false, ""},
+		{"bad json", "b.gltf", []byte(`{"scene":"zero"}`), true, "scene"},
+		{"bad header", "c.glb", []byte("glTF"), true, "header"},
+		{"bad chunk type", "d.glb", glb(chunk(typeJSON, `{}`), chunk(0x1234, "")), true, "chunk 1"},
+		{"truncated chunk", "e.glb", glb(chunk(typeJSON, `{}`))[:20], true, "chunk 0"},
+		{"bad extension", "f.obj", []byte{}, true, ""},
+	}
+
+	for _, test := range tests {
+		file := filepath.Join(dir, test.file)
+		ioutil.WriteFile(file, test.data, 0644)
+
+		_, e := TryLoad(file)
+		if !test.wantErr {
+			if e != nil {
+				t.Errorf("%v: unexpected error %v", test.name, e)
+			}
+			continue
+		}
+
+		ae, ok := e.(*asset.Error)
+		if !ok {
+			t.Errorf("%v: expected *asset.Error, got %v", test.name, e)
+			continue
+		}
+		if ae.File != file {
+			t.Errorf("%v: wrong file. got %v, expected %v", test.name, ae.File, file)
+		}
+		if ae.Field != test.field {
+			t.Errorf("%v: wrong field. got %v, expected %v", test.name, ae.Field, test.field)
+		}
+	}
+
+	_, e := TryLoad(filepath.Join(dir, "missing.gltf"))
+	if _, ok := e.(*asset.Error); !ok {
+		t.Errorf("missing file: expected *asset.Error, got %v", e)
+	}
+}
+
+func Test_bufferData(t *testing.T) {
+	dir := t.TempDir()
+	ioutil.WriteFile(filepath.Join(dir, "a.bin"), []byte{1, 2, 3, 4}, 0644)
+	file := filepath.Join(dir, "a.gltf")
+	ioutil.WriteFile(file, []byte(`{"buffers":[{"uri":"a.bin","byteLength":4}]}`), 0644)
+
+	f, e := TryLoad(file)
+	if e != nil {
+		t.Fatal(e)
+	}
+	if _, e := bufferData(f, 0); e != nil {
+		t.Fatal(e)
+	}
+
+	// The buffer is not read again
+	os.Remove(filepath.Join(dir, "a.bin"))
+	if data, e := bufferData(f, 0); e != nil || len(data) != 4 {
+		t.Errorf("buffer was not cached. got %v (%v)", data, e)
+	}
+}
+
+func Test_primitiveBounds(t *testing.T) {
+	f := &File{GlTF: GlTF{Accessors: []Accessor{
+		{Min: []float32{-1, -2, -3}, Max: []float32{1, 2, 3}},
+		{Min: []float32{-1, 0, 0}, Max: []float32{0, 0, 2}},
+		{},
+	}}}
+
+	prim := &MeshPrimitive{
+		Attributes: map[string]uint{"POSITION": 0},
+		Targets:    []map[string]uint{{"POSITION": 1}},
+	}
+	b := primitiveBounds(f, prim)
+	expected := bounds.AABB{Min: mgl.Vec3{-2, -2, -3}, Max: mgl.Vec3{1, 2, 5}}
+	if b != expected {
+		t.Errorf("wrong bounds. got %v, expected %v", b, expected)
+	}
+
+	prim = &MeshPrimitive{Attributes: map[string]uint{"POSITION": 2}}
+	if b := primitiveBounds(f, prim); !b.IsInfinite() {
+		t.Errorf("bounds without min and max should be infinite. got %v", b)
+	}
+}
+
+func TestDecodePrimitive_badSemantic(t *testing.T) {
+	for _, key := range []string{"TEXCOORD", "JOINTS", "WEIGHTS_", "COLOR_a"} {
+		prim := &MeshPrimitive{Indices: -1, Attributes: map[string]uint{key: 0}}
+		if _, e := DecodePrimitive(&File{}, prim); e == nil {
+			t.Errorf("%v: expected error", key)
+		}
+	}
+}
@@ -0,0 +1,550 @@
+package gltf
+
+import (
+	"fmt"
+	"math"
+	"strings"
+)
+
+// Severity is the severity of a validation issue.
+type Severity int
+
+const (
+	SeverityError Severity = iota
+	SeverityWarning
+	SeverityInfo
+)
+
+func (s Severity) String() string {
+	switch s {
+	case SeverityError:
+		return "error"
+	case SeverityWarning:
+		return "warning"
+	default:
+		return "info"
+	}
+}
+
+// Issue is a problem found while validating a glTF file.
+// Codes follow those of the Khronos glTF validator where possible.
+type Issue struct {
+	Severity Severity
+	Pointer  string // JSON pointer to the offending property, e.g. /accessors/0/bufferView.
+	Code     string
+	Message  string
+}
+
+func (i Issue) String() string {
+	return fmt.Sprintf("%v %v: %v (%v)", i.Severity, i.Pointer, i.Message, i.Code)
+}
+
+// validator holds the state of a validation.
+type validator struct {
+	f      *File
+	g      *GlTF
+	issues []Issue
+}
+
+// Validate validates a glTF file.
+// Returns the issues found, or nil if the file is valid.
+func Validate(f *File) []Issue {
+	v := &validator{f: f, g: &f.GlTF}
+	v.validateAsset()
+	v.validateBuffers()
+	v.validateBufferViews()
+	v.validateAccessors()
+	v.validateScenes()
+	v.validateNodes()
+	v.validateMeshes()
+	v.validateMaterials()
+	v.validateTextures()
+	v.validateSkins()
+	v.validateAnimations()
+	return v.issues
+}
+
+// HasErrors returns whether any of the issues is an error.
+func HasErrors(issues []Issue) bool {
+	for _, i := range issues {
+		if i.Severity == SeverityError {
+			return true
+		}
+	}
+	return false
+}
+
+// report adds an issue.
+func (v *validator) report(sev Severity, ptr, code, format string, args ...interface{}) {
+	v.issues = append(v.issues, Issue{
+		Severity: sev,
+		Pointer:  ptr,
+		Code:     code,
+		Message:  fmt.Sprintf(format, args...),
+	})
+}
+
+// ref checks that idx refers to an element of an array of length n.
+// Returns whether the reference is valid.
+func (v *validator) ref(ptr string, idx, n int) bool {
+	if idx < 0 || idx >= n {
+		v.report(SeverityError, ptr, "UNRESOLVED_REFERENCE", "unresolved reference: %v", idx)
+		return false
+	}
+	return true
+}
+
+// length checks that an array has one of the given lengths.
+func (v *validator) length(ptr string, n int, valid ...int) {
+	for _, l := range valid {
+		if n == l {
+			return
+		}
+	}
+	v.report(SeverityError, ptr, "ARRAY_LENGTH_NOT_IN_LIST", "invalid array length %v. valid values are %v", n, valid)
+}
+
+func (v *validator) validateAsset() {
+	ver := v.g.Asset.Version
+	if len(ver) == 0 {
+		v.report(SeverityError, "/asset/version", "UNDEFINED_PROPERTY", "property 'version' must be defined")
+	} else if !strings.HasPrefix(ver, "2.") {
+		v.report(SeverityError, "/asset/version", "UNKNOWN_ASSET_MAJOR_VERSION", "unknown glTF major asset version: %v", ver)
+	}
+}
+
+func (v *validator) validateBuffers() {
+	for i, b := range v.g.Buffers {
+		ptr := fmt.Sprintf("/buffers/%v", i)
+		if len(b.URI) == 0 && i >= len(v.f.Chunks) {
+			v.report(SeverityError, ptr, "BUFFER_MISSING_GLB_DATA", "buffer refers to an unresolved GLB binary chunk")
+			continue
+		}
+		data, e := bufferData(v.f, uint(i))
+		if e != nil {
+			v.report(SeverityError, ptr+"/uri", "IO_ERROR", "%v", e)
+			continue
+		}
+		if uint(len(data)) < b.ByteLength {
+			v.report(SeverityError, ptr+"/byteLength", "BUFFER_BYTE_LENGTH_MISMATCH",
+				"actual data length %v is less than the declared buffer byteLength %v", len(data), b.ByteLength)
+		}
+	}
+}
+
+func (v *validator) validateBufferViews() {
+	for i, bv := range v.g.BufferViews {
+		ptr := fmt.Sprintf("/bufferViews/%v", i)
+		if !v.ref(ptr+"/buffer", int(bv.Buffer), len(v.g.Buffers)) {
+			continue
+		}
+		if bv.ByteLength == 0 {
+			v.report(SeverityError, ptr+"/byteLength", "VALUE_NOT_IN_RANGE", "byteLength must be at least 1")
+		}
+		if buf := v.g.Buffers[bv.Buffer]; bv.ByteOffset+bv.ByteLength > buf.ByteLength {
+			v.report(SeverityError, ptr, "BUFFER_VIEW_TOO_LONG",
+				"bufferView does not fit buffer %v byteLength %v", bv.Buffer, buf.ByteLength)
+		}
+		if bv.ByteStride != 0 && (bv.ByteStride < 4 || bv.ByteStride > 252 || bv.ByteStride%4 != 0) {
+			v.report(SeverityError, ptr+"/byteStride", "BUFFER_VIEW_INVALID_BYTE_STRIDE",
+				"byteStride %v must be a multiple of 4 between 4 and 252", bv.ByteStride)
+		}
+		if bv.ByteStride > bv.ByteLength {
+			v.report(SeverityError, ptr+"/byteStride", "BUFFER_VIEW_TOO_BIG_BYTE_STRIDE",
+				"byteStride %v exceeds byteLength %v", bv.ByteStride, bv.ByteLength)
+		}
+	}
+}
+
+func (v *validator) validateAccessors() {
+	for i := range v.g.Accessors {
+		a := &v.g.Accessors[i]
+		ptr := fmt.Sprintf("/accessors/%v", i)
+
+		compSize := componentSize(a.ComponentType)
+		if compSize == 0 {
+			v.report(SeverityError, ptr+"/componentType", "VALUE_NOT_IN_LIST", "invalid componentType %v", a.ComponentType)
+		}
+		numComps := int(numComponentsInType(a.Type))
+		if numComps == 0 {
+			v.report(SeverityError, ptr+"/type", "VALUE_NOT_IN_LIST", "invalid type %v", a.Type)
+		}
+		if a.Count == 0 {
+			v.report(SeverityError, ptr+"/count", "VALUE_NOT_IN_RANGE", "count must be at least 1")
+		}
+		if a.Normalized && (a.ComponentType == componentFloat || a.ComponentType == componentUnsignedInt) {
+			v.report(SeverityError, ptr+"/normalized", "ACCESSOR_NORMALIZED_INVALID",
+				"only (u)byte and (u)short accessors can be normalized")
+		}
+		if a.Min != nil && numComps > 0 {
+			v.length(ptr+"/min", len(a.Min), numComps)
+		}
+		if a.Max != nil && numComps > 0 {
+			v.length(ptr+"/max", len(a.Max), numComps)
+		}
+		if compSize == 0 || numComps == 0 {
+			continue
+		}
+		elemSize := compSize * numComps
+
+		if a.BufferView >= 0 && v.ref(ptr+"/bufferView", a.BufferView, len(v.g.BufferViews)) {
+			bv := &v.g.BufferViews[a.BufferView]
+			if a.ByteOffset%uint(compSize) != 0 {
+				v.report(SeverityError, ptr+"/byteOffset", "ACCESSOR_OFFSET_ALIGNMENT",
+					"offset %v is not a multiple of componentType length %v", a.ByteOffset, compSize)
+			}
+			stride := int(bv.ByteStride)
+			if stride == 0 {
+				stride = elemSize
+			} else if stride < elemSize {
+				v.report(SeverityError, ptr, "ACCESSOR_SMALL_BYTESTRIDE",
+					"referenced bufferView's byteStride %v is less than accessor element's length %v", stride, elemSize)
+			}
+			if a.Count > 0 && int(a.ByteOffset)+stride*(int(a.Count)-1)+elemSize > int(bv.ByteLength) {
+				v.report(SeverityError, ptr, "ACCESSOR_TOO_LONG",
+					"accessor does not fit referenced bufferView %v byteLength %v", a.BufferView, bv.ByteLength)
+			}
+		}
+
+		if a.Sparse.Count > 0 {
+			v.validateSparse(ptr+"/sparse", a, elemSize)
+		}
+	}
+}
+
+func (v *validator) validateSparse(ptr string, a *Accessor, elemSize int) {
+	s := &a.Sparse
+	if s.Count > a.Count {
+		v.report(SeverityError, ptr+"/count", "VALUE_NOT_IN_RANGE",
+			"sparse count %v exceeds accessor count %v", s.Count, a.Count)
+	}
+
+	var idxSize int
+	switch s.Indices.ComponentType {
+	case componentUnsignedByte, componentUnsignedShort, componentUnsignedInt:
+		idxSize = componentSize(s.Indices.ComponentType)
+	default:
+		v.report(SeverityError, ptr+"/indices/componentType", "VALUE_NOT_IN_LIST",
+			"invalid componentType %v", s.Indices.ComponentType)
+	}
+	if v.ref(ptr+"/indices/bufferView", int(s.Indices.BufferView), len(v.g.BufferViews)) && idxSize > 0 {
+		bv := &v.g.BufferViews[s.Indices.BufferView]
+		if s.Indices.ByteOffset+s.Count*uint(idxSize) > bv.ByteLength {
+			v.report(SeverityError, ptr+"/indices", "ACCESSOR_SPARSE_INDICES_TOO_LONG",
+				"sparse indices do not fit referenced bufferView %v", s.Indices.BufferView)
+		}
+	}
+	if v.ref(ptr+"/values/bufferView", int(s.Values.BufferView), len(v.g.BufferViews)) {
+		bv := &v.g.BufferViews[s.Values.BufferView]
+		if s.Values.ByteOffset+s.Count*uint(elemSize) > bv.ByteLength {
+			v.report(SeverityError, ptr+"/values", "ACCESSOR_SPARSE_VALUES_TOO_LONG",
+				"sparse values do not fit referenced bufferView %v", s.Values.BufferView)
+		}
+	}
+}
+
+func (v *validator) validateScenes() {
+	if v.g.Scene >= 0 {
+		v.ref("/scene", v.g.Scene, len(v.g.Scenes))
+	} else if len(v.g.Scenes) > 0 {
+		v.report(SeverityWarning, "/scene", "UNDEFINED_PROPERTY", "no default scene is defined")
+	}
+
+	// Determine which nodes are children
+	isChild := make(map[uint]bool)
+	for _, n := range v.g.Nodes {
+		for _, c := range n.Children {
+			isChild[c] = true
+		}
+	}
+
+	for i, s := range v.g.Scenes {
+		for j, n := range s.Nodes {
+			ptr := fmt.Sprintf("/scenes/%v/nodes/%v", i, j)
+			if v.ref(ptr, int(n), len(v.g.Nodes)) && isChild[n] {
+				v.report(SeverityError, ptr, "SCENE_NON_ROOT_NODE", "node %v is not a root node", n)
+			}
+		}
+	}
+}
+
+func (v *validator) validateNodes() {
+	parents := make(map[uint]int)
+	for i, n := range v.g.Nodes {
+		ptr := fmt.Sprintf("/nodes/%v", i)
+		for j, c := range n.Children {
+			cptr := fmt.Sprintf("%v/children/%v", ptr, j)
+			if !v.ref(cptr, int(c), len(v.g.Nodes)) {
+				continue
+			}
+			if p, ok := parents[c]; ok {
+				v.report(SeverityError, cptr, "NODE_PARENT_OVERRIDE",
+					"value overrides parent of node %v, which is already a child of node %v", c, p)
+			}
+			parents[c] = i
+		}
+		if n.Mesh >= 0 {
+			v.ref(ptr+"/mesh", n.Mesh, len(v.g.Meshes))
+		}
+		if n.Skin >= 0 {
+			v.ref(ptr+"/skin", n.Skin, len(v.g.Skins))
+		}
+		v.length(ptr+"/matrix", len(n.Matrix), 16)
+		v.length(ptr+"/rotation", len(n.Rotation), 4)
+		v.length(ptr+"/scale", len(n.Scale), 3)
+		v.length(ptr+"/translation", len(n.Translation), 3)
+
+		if len(n.Rotation) == 4 {
+			var l float64
+			for _, c := range n.Rotation {
+				l += float64(c * c)
+			}
+			if math.Abs(math.Sqrt(l)-1) > 0.00001 {
+				v.report(SeverityError, ptr+"/rotation", "ROTATION_NON_UNIT", "rotation quaternion must be normalized")
+			}
+		}
+	}
+
+	// Detect loops by walking up the parents of each node
+	for i := range v.g.Nodes {
+		seen := map[uint]bool{uint(i): true}
+		for n := uint(i); ; {
+			p, ok := parents[n]
+			if !ok {
+				break
+			}
+			if p == i {
+				v.report(SeverityError, fmt.Sprintf("/nodes/%v", i), "NODE_LOOP", "node is a part of a node loop")
+				break
+			}
+			if seen[uint(p)] {
+				// Loop not containing node i
+				break
+			}
+			seen[uint(p)] = true
+			n = uint(p)
+		}
+	}
+}
+
+func (v *validator) validateMeshes() {
+	for i, m := range v.g.Meshes {
+		for j, p := range m.Primitives {
+			ptr := fmt.Sprintf("/meshes/%v/primitives/%v", i, j)
+			v.validatePrimitive(ptr, &p)
+		}
+	}
+}
+
+// validSemantic returns whether name is a defined attribute semantic, or an
+// application-specific one beginning with an underscore.
+func validSemantic(name string) bool {
+	switch name {
+	case "POSITION", "NORMAL", "TANGENT", "TEXCOORD", "COLOR", "JOINTS", "WEIGHTS":
+		return true
+	}
+	return strings.HasPrefix(name, "_")
+}
+
+func (v *validator) validatePrimitive(ptr string, p *MeshPrimitive) {
+	if p.Mode > 6 {
+		v.report(SeverityError, ptr+"/mode", "VALUE_NOT_IN_LIST", "invalid mode %v", p.Mode)
+	}
+	if p.Material >= 0 {
+		v.ref(ptr+"/material", p.Material, len(v.g.Materials))
+	}
+
+	// Check attributes
+	count := -1
+	for k, a := range p.Attributes {
+		aptr := ptr + "/attributes/" + k
+		if name, _, ok := parseSemantic(k); !ok || !validSemantic(name) {
+			v.report(SeverityError, aptr, "MESH_PRIMITIVE_INVALID_ATTRIBUTE", "invalid attribute semantic %v", k)
+		}
+		if !v.ref(aptr, int(a), len(v.g.Accessors)) {
+			continue
+		}
+		acc := &v.g.Accessors[a]
+		if count >= 0 && int(acc.Count) != count {
+			v.report(SeverityError, aptr, "MESH_PRIMITIVE_UNEQUAL_ACCESSOR_COUNT",
+				"all accessors of the same primitive must have the same count")
+		}
+		count = int(acc.Count)
+
+		if k == "POSITION" && (acc.Type != "VEC3" || acc.ComponentType != componentFloat) {
+			v.report(SeverityError, aptr, "MESH_PRIMITIVE_ATTRIBUTES_ACCESSOR_INVALID_FORMAT",
+				"invalid accessor format for POSITION. must be VEC3 float")
+		}
+	}
+	if _, ok := p.Attributes["POSITION"]; !ok {
+		v.report(SeverityWarning, ptr+"/attributes", "MESH_PRIMITIVE_NO_POSITION", "no POSITION attribute found")
+	}
+
+	// Check indices
+	if p.Indices >= 0 && v.ref(ptr+"/indices", p.Indices, len(v.g.Accessors)) {
+		acc := &v.g.Accessors[p.Indices]
+		switch acc.ComponentType {
+		case componentUnsignedByte, componentUnsignedShort, componentUnsignedInt:
+		default:
+			v.report(SeverityError, ptr+"/indices", "MESH_PRIMITIVE_INDICES_ACCESSOR_INVALID_FORMAT",
+				"invalid indices accessor format. must be unsigned integers")
+		}
+		if count >= 0 && !v.hasErrorAt(fmt.Sprintf("/accessors/%v", p.Indices)) {
+			if idx, e := ReadUints(v.f, uint(p.Indices)); e == nil {
+				for i, x := range idx {
+					if int(x) >= count {
+						v.report(SeverityError, fmt.Sprintf("/accessors/%v", p.Indices), "ACCESSOR_INDEX_OOB",
+							"indices accessor element at index %v has value %v, which is greater than the vertex count %v", i, x, count)
+						break
+					}
+				}
+			}
+		}
+	}
+
+	// Check morph targets
+	for t, target := range p.Targets {
+		for k, a := range target {
+			v.ref(fmt.Sprintf("%v/targets/%v/%v", ptr, t, k), int(a), len(v.g.Accessors))
+		}
+	}
+}
+
+// hasErrorAt returns whether an error was reported for the given pointer
+// or any of its properties.
+func (v *validator) hasErrorAt(ptr string) bool {
+	for _, i := range v.issues {
+		if i.Severity == SeverityError && (i.Pointer == ptr || strings.HasPrefix(i.Pointer, ptr+"/")) {
+			return true
+		}
+	}
+	return false
+}
+
+func (v *validator) validateMaterials() {
+	for i, m := range v.g.Materials {
+		ptr := fmt.Sprintf("/materials/%v", i)
+		pbr := m.PbrMetallicRoughness
+		v.textureInfo(ptr+"/pbrMetallicRoughness/baseColorTexture", pbr.BaseColorTexture.Index, pbr.BaseColorTexture.TexCoord)
+		v.textureInfo(ptr+"/pbrMetallicRoughness/metallicRoughnessTexture", pbr.MetallicRoughnessTexture.Index, pbr.MetallicRoughnessTexture.TexCoord)
+		v.textureInfo(ptr+"/normalTexture", m.NormalTexture.Index, m.NormalTexture.TexCoord)
+		v.textureInfo(ptr+"/occlusionTexture", m.OcclusionTexture.Index, m.OcclusionTexture.TexCoord)
+		v.textureInfo(ptr+"/emissiveTexture", m.EmissiveTexture.Index, m.EmissiveTexture.TexCoord)
+		switch m.AlphaMode {
+		case "OPAQUE", "MASK", "BLEND":
+		default:
+			v.report(SeverityError, ptr+"/alphaMode", "VALUE_NOT_IN_LIST", "invalid alphaMode %v", m.AlphaMode)
+		}
+	}
+}
+
+// textureInfo checks the index of a texture info.
+// Texture infos which are not defined have a texCoord of -1.
+func (v *validator) textureInfo(ptr string, idx, texCoord int) {
+	switch {
+	case idx >= 0:
+		v.ref(ptr+"/index", idx, len(v.g.Textures))
+	case texCoord >= 0:
+		v.report(SeverityError, ptr+"/index", "UNDEFINED_PROPERTY", "property 'index' must be defined")
+	}
+}
+
+func (v *validator) validateTextures() {
+	for i, t := range v.g.Textures {
+		ptr := fmt.Sprintf("/textures/%v", i)
+		if t.Sampler >= 0 {
+			v.ref(ptr+"/sampler", t.Sampler, len(v.g.Samplers))
+		}
+		if t.Source >= 0 {
+			v.ref(ptr+"/source", t.Source, len(v.g.Images))
+		} else {
+			v.report(SeverityWarning, ptr, "UNDEFINED_PROPERTY", "property 'source' is not defined")
+		}
+	}
+	for i, img := range v.g.Images {
+		ptr := fmt.Sprintf("/images/%v", i)
+		if img.BufferView >= 0 {
+			v.ref(ptr+"/bufferView", img.BufferView, len(v.g.BufferViews))
+		} else if len(img.URI) == 0 {
+			v.report(SeverityError, ptr, "ONE_OF_MISMATCH", "exactly one of ('bufferView', 'uri') properties must be defined")
+		}
+	}
+}
+
+func (v *validator) validateSkins() {
+	for i, s := range v.g.Skins {
+		ptr := fmt.Sprintf("/skins/%v", i)
+		for j, n := range s.Joints {
+			v.ref(fmt.Sprintf("%v/joints/%v", ptr, j), int(n), len(v.g.Nodes))
+		}
+		if s.Skeleton >= 0 {
+			v.ref(ptr+"/skeleton", s.Skeleton, len(v.g.Nodes))
+		}
+		if s.InverseBindMatrices >= 0 && v.ref(ptr+"/inverseBindMatrices", s.InverseBindMatrices, len(v.g.Accessors)) {
+			acc := &v.g.Accessors[s.InverseBindMatrices]
+			if acc.Type != "MAT4" || acc.ComponentType != componentFloat {
+				v.report(SeverityError, ptr+"/inverseBindMatrices", "SKIN_IBM_INVALID_FORMAT",
+					"invalid IBM accessor format. must be MAT4 float")
+			}
+			if int(acc.Count) < len(s.Joints) {
+				v.report(SeverityError, ptr+"/inverseBindMatrices", "INVALID_IBM_ACCESSOR_COUNT",
+					"accessor of count %v expected. found %v", len(s.Joints), acc.Count)
+			}
+		}
+	}
+}
+
+func (v *validator) validateAnimations() {
+	for i, a := range v.g.Animations {
+		ptr := fmt.Sprintf("/animations/%v", i)
+
+		for j, s := range a.Samplers {
+			sptr := fmt.Sprintf("%v/samplers/%v", ptr, j)
+			switch s.Interpolation {
+			case "LINEAR", "STEP", "CUBICSPLINE":
+			default:
+				v.report(SeverityError, sptr+"/interpolation", "VALUE_NOT_IN_LIST", "invalid interpolation %v", s.Interpolation)
+			}
+			v.ref(sptr+"/output", int(s.Output), len(v.g.Accessors))
+			if !v.ref(sptr+"/input", int(s.Input), len(v.g.Accessors)) {
+				continue
+			}
+			acc := &v.g.Accessors[s.Input]
+			if acc.Type != "SCALAR" || acc.ComponentType != componentFloat {
+				v.report(SeverityError, sptr+"/input", "ANIMATION_SAMPLER_INPUT_ACCESSOR_INVALID_FORMAT",
+					"invalid input accessor format. must be SCALAR float")
+				continue
+			}
+			if v.hasErrorAt(fmt.Sprintf("/accessors/%v", s.Input)) {
+				continue
+			}
+			if in, e := ReadFloats(v.f, s.Input); e == nil {
+				for k := 1; k < len(in); k++ {
+					if in[k] <= in[k-1] {
+						v.report(SeverityError, fmt.Sprintf("/accessors/%v", s.Input), "ANIMATION_SAMPLER_INPUT_ACCESSOR_NON_INCREASING",
+							"animation input accessor element at index %v must be greater than previous", k)
+						break
+					}
+				}
+			}
+		}
+
+		targets := make(map[string]int)
+		for j, c := range a.Channels {
+			cptr := fmt.Sprintf("%v/channels/%v", ptr, j)
+			v.ref(cptr+"/sampler", int(c.Sampler), len(a.Samplers))
+			v.ref(cptr+"/target/node", int(c.Target.Node), len(v.g.Nodes))
+			switch c.Target.Path {
+			case "translation", "rotation", "scale", "weights":
+			default:
+				v.report(SeverityError, cptr+"/target/path", "VALUE_NOT_IN_LIST", "invalid path %v", c.Target.Path)
+			}
+
+			key := fmt.Sprintf("%v/%v", c.Target.Node, c.Target.Path)
+			if k, ok := targets[key]; ok {
+				v.report(SeverityError, cptr, "ANIMATION_DUPLICATE_TARGETS", "animation channel has the same target as channel %v", k)
+			}
+			targets[key] = j
+		}
+	}
+}
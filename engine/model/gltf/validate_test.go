@@ -0,0 +1,97 @@
+package gltf
+
+import (
+	"encoding/json"
+	"testing"
+)
+
+func TestValidate_assets(t *testing.T) {
+	for _, file := range []string{"BoomBox.gltf", "cube.gltf", "quad.glb"} {
+		f, e := TryLoad("../../../assets/models/" + file)
+		if e != nil {
+			t.Fatal(e)
+		}
+		for _, i := range Validate(f) {
+			if i.Severity == SeverityError {
+				t.Errorf("%v: unexpected issue %v", file, i)
+			}
+		}
+	}
+}
+
+func TestValidate(t *testing.T) {
+	data := floats(0, 0, 0, 1, 0, 0, 0, 1, 0)
+	data = append(data, 0, 1, 5, 0)
+	buffers := `"asset":{"version":"2.0"},"buffers":[{"uri":"data:;base64,` + encode(data) + `","byteLength":40}]`
+	views := `"bufferViews":[{"buffer":0,"byteLength":36},{"buffer":0,"byteOffset":36,"byteLength":4}]`
+	accessors := `"accessors":[{"bufferView":0,"componentType":5126,"count":3,"type":"VEC3"},` +
+		`{"bufferView":1,"componentType":5121,"count":3,"type":"SCALAR"}]`
+
+	tests := []struct {
+		name    string
+		doc     string
+		pointer string
+		code    string
+	}{
+		{"valid",
+			`{"scene":0,"scenes":[{"nodes":[0]}],"nodes":[{}],` + buffers + "}",
+			"", ""},
+		{"missing version",
+			`{"asset":{}}`,
+			"/asset/version", "UNDEFINED_PROPERTY"},
+		{"missing scene",
+			`{"scenes":[{"nodes":[]}],` + buffers + "}",
+			"/scene", "UNDEFINED_PROPERTY"},
+		{"scene out of range",
+			`{"scene":1,"scenes":[{"nodes":[]}],` + buffers + "}",
+			"/scene", "UNRESOLVED_REFERENCE"},
+		{"bufferView overruns buffer",
+			`{"bufferViews":[{"buffer":0,"byteOffset":8,"byteLength":36}],` + buffers + "}",
+			"/bufferViews/0", "BUFFER_VIEW_TOO_LONG"},
+		{"accessor overruns bufferView",
+			`{"accessors":[{"bufferView":0,"componentType":5126,"count":4,"type":"VEC3"}],` + views + "," + buffers + "}",
+			"/accessors/0", "ACCESSOR_TOO_LONG"},
+		{"index out of range",
+			`{"meshes":[{"primitives":[{"attributes":{"POSITION":0},"indices":1}]}],` + accessors + "," + views + "," + buffers + "}",
+			"/accessors/1", "ACCESSOR_INDEX_OOB"},
+		{"node loop",
+			`{"nodes":[{"children":[1]},{"children":[0]}],` + buffers + "}",
+			"/nodes/0", "NODE_LOOP"},
+		{"non-root scene node",
+			`{"scene":0,"scenes":[{"nodes":[1]}],"nodes":[{"children":[1]},{}],` + buffers + "}",
+			"/scenes/0/nodes/0", "SCENE_NON_ROOT_NODE"},
+		{"bad mesh",
+			`{"nodes":[{"mesh":2}],` + buffers + "}",
+			"/nodes/0/mesh", "UNRESOLVED_REFERENCE"},
+		{"attribute without set index",
+			`{"meshes":[{"primitives":[{"attributes":{"POSITION":0,"TEXCOORD":0}}]}],` + accessors + "," + views + "," + buffers + "}",
+			"/meshes/0/primitives/0/attributes/TEXCOORD", "MESH_PRIMITIVE_INVALID_ATTRIBUTE"},
+		{"texture info without index",
+			`{"materials":[{"normalTexture":{"scale":1}}],` + buffers + "}",
+			"/materials/0/normalTexture/index", "UNDEFINED_PROPERTY"},
+	}
+
+	for _, test := range tests {
+		f := &File{}
+		if e := json.Unmarshal([]byte(test.doc), &f.GlTF); e != nil {
+			t.Fatalf("%v: %v", test.name, e)
+		}
+		issues := Validate(f)
+
+		if len(test.code) == 0 {
+			if len(issues) != 0 {
+				t.Errorf("%v: unexpected issues %v", test.name, issues)
+			}
+			continue
+		}
+		found := false
+		for _, i := range issues {
+			if i.Pointer == test.pointer && i.Code == test.code {
+				found = true
+			}
+		}
+		if !found {
+			t.Errorf("%v: expected %v at %v. got %v", test.name, test.code, test.pointer, issues)
+		}
+	}
+}
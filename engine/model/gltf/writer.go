@@ -0,0 +1,177 @@
+package gltf
+
+import (
+	"bytes"
+	"encoding/binary"
+	"encoding/json"
+	"errors"
+	"io/ioutil"
+	"path/filepath"
+	"reflect"
+	"strings"
+)
+
+// Save writes the file as either .gltf or .glb, depending on the extension.
+// If the file has a binary chunk, it is stored as buffer 0. For .gltf files
+// the chunk is written to a .bin file next to the file.
+func (f *File) Save(file string) error {
+	g := f.GlTF
+	var bin []byte
+	if len(f.Chunks) > 0 {
+		bin = f.Chunks[0]
+		if len(g.Buffers) == 0 {
+			return errors.New("binary chunk has no buffer")
+		}
+	}
+
+	switch filepath.Ext(file) {
+	case ".gltf":
+		if bin != nil {
+			name := strings.TrimSuffix(filepath.Base(file), ".gltf") + ".bin"
+			if e := ioutil.WriteFile(filepath.Join(filepath.Dir(file), name), bin, 0644); e != nil {
+				return e
+			}
+			g.Buffers = append([]Buffer(nil), g.Buffers...)
+			g.Buffers[0].URI = name
+		}
+		data, e := marshal(&g)
+		if e != nil {
+			return e
+		}
+		return ioutil.WriteFile(file, data, 0644)
+
+	case ".glb":
+		data, e := marshal(&g)
+		if e != nil {
+			return e
+		}
+		return ioutil.WriteFile(file, encodeGlb(data, bin), 0644)
+
+	default:
+		return errors.New("invalid file extension")
+	}
+}
+
+// encodeGlb encodes JSON and binary data as a .glb file.
+func encodeGlb(js, bin []byte) []byte {
+	var out bytes.Buffer
+	write := func(v uint32) {
+		binary.Write(&out, binary.LittleEndian, v)
+	}
+	// Chunks must be 4 byte aligned
+	js = pad(js, ' ')
+	bin = pad(bin, 0)
+
+	length := 12 + 8 + len(js)
+	if len(bin) > 0 {
+		length += 8 + len(bin)
+	}
+
+	write(magicValue)
+	write(versionValue)
+	write(uint32(length))
+
+	write(uint32(len(js)))
+	write(typeJSON)
+	out.Write(js)
+
+	if len(bin) > 0 {
+		write(uint32(len(bin)))
+		write(typeBIN)
+		out.Write(bin)
+	}
+	return out.Bytes()
+}
+
+// pad pads data to a multiple of 4 bytes.
+func pad(data []byte, b byte) []byte {
+	for len(data)%4 != 0 {
+		data = append(data, b)
+	}
+	return data
+}
+
+// marshal encodes v as JSON.
+// Unlike encoding/json, negative integers are omitted, as they represent
+// undefined indices. Non-negative integers are always kept, as 0 is a
+// valid index. Empty objects marked as omitempty are omitted, as are objects
+// whose "index" is undefined (such as unused texture infos).
+func marshal(v interface{}) ([]byte, error) {
+	val, _ := encodeValue(reflect.ValueOf(v))
+	return json.Marshal(val)
+}
+
+// encodeValue converts v into values which encode as glTF JSON.
+// Also returns whether the value is empty.
+func encodeValue(v reflect.Value) (interface{}, bool) {
+	switch v.Kind() {
+	case reflect.Ptr:
+		if v.IsNil() {
+			return nil, true
+		}
+		return encodeValue(v.Elem())
+
+	case reflect.Struct:
+		if undefinedIndex(v) {
+			return nil, true
+		}
+		m := make(map[string]interface{})
+		t := v.Type()
+		for i := 0; i < t.NumField(); i++ {
+			tag := strings.Split(t.Field(i).Tag.Get("json"), ",")
+			if len(tag[0]) == 0 || tag[0] == "-" {
+				continue
+			}
+			f := v.Field(i)
+			switch f.Kind() {
+			case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64:
+				if f.Int() < 0 {
+					continue
+				}
+			}
+			val, empty := encodeValue(f)
+			if empty && len(tag) > 1 && tag[1] == "omitempty" {
+				continue
+			}
+			m[tag[0]] = val
+		}
+		return m, len(m) == 0 || v.IsZero()
+
+	case reflect.Slice:
+		out := make([]interface{}, v.Len())
+		for i := range out {
+			out[i], _ = encodeValue(v.Index(i))
+		}
+		return out, len(out) == 0
+
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64:
+		return v.Interface(), false
+
+	case reflect.Map:
+		out := make(map[string]interface{}, v.Len())
+		iter := v.MapRange()
+		for iter.Next() {
+			out[iter.Key().String()], _ = encodeValue(iter.Value())
+		}
+		return out, len(out) == 0
+
+	default:
+		return v.Interface(), v.IsZero()
+	}
+}
+
+// undefinedIndex returns whether v is a struct with an undefined "index".
+func undefinedIndex(v reflect.Value) bool {
+	t := v.Type()
+	for i := 0; i < t.NumField(); i++ {
+		if strings.Split(t.Field(i).Tag.Get("json"), ",")[0] != "index" {
+			continue
+		}
+		f := v.Field(i)
+		switch f.Kind() {
+		case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64:
+			return f.Int() < 0
+		}
+	}
+	return false
+}
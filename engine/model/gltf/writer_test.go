@@ -0,0 +1,103 @@
+package gltf
+
+import (
+	"encoding/json"
+	"path/filepath"
+	"reflect"
+	"strings"
+	"testing"
+)
+
+func TestFile_Save(t *testing.T) {
+	dir := t.TempDir()
+	src := &File{
+		GlTF: GlTF{
+			Asset:       Asset{Version: "2.0"},
+			Scene:       0,
+			Scenes:      []Scene{{Nodes: []uint{0}}},
+			Nodes:       []Node{{Name: "a", Camera: -1, Mesh: -1, Skin: 0, Rotation: []float32{0, 0, 0, 1}}},
+			Skins:       []Skin{{Joints: []uint{0}, InverseBindMatrices: -1, Skeleton: -1}},
+			Buffers:     []Buffer{{ByteLength: 12}},
+			BufferViews: []BufferView{{ByteLength: 12}},
+			Accessors:   []Accessor{{BufferView: 0, ComponentType: componentFloat, Count: 3, Type: "SCALAR"}},
+			Materials: []Material{{
+				AlphaMode: "OPAQUE",
+				PbrMetallicRoughness: MaterialPbrMetallicRoughness{
+					BaseColorTexture:         TextureInfo{Index: -1, TexCoord: -1},
+					MetallicRoughnessTexture: TextureInfo{Index: -1, TexCoord: -1},
+				},
+				NormalTexture:    MaterialNormalTextureInfo{Scale: 1, Index: -1, TexCoord: -1},
+				OcclusionTexture: MaterialOcclusionTextureInfo{Strength: 1, Index: -1, TexCoord: -1},
+				EmissiveTexture:  TextureInfo{Index: -1, TexCoord: -1},
+			}},
+		},
+		Chunks: [][]byte{floats(1, 2, 3)},
+	}
+
+	for _, file := range []string{"a.gltf", "a.glb"} {
+		path := filepath.Join(dir, file)
+		if e := src.Save(path); e != nil {
+			t.Fatalf("%v: %v", file, e)
+		}
+		f, e := TryLoad(path)
+		if e != nil {
+			t.Fatalf("%v: %v", file, e)
+		}
+
+		if issues := Validate(f); HasErrors(issues) {
+			t.Errorf("%v: invalid file %v", file, issues)
+		}
+		n := f.GlTF.Nodes[0]
+		if n.Name != "a" || n.Camera != -1 || n.Mesh != -1 || n.Skin != 0 {
+			t.Errorf("%v: wrong node. got %+v", file, n)
+		}
+		m := f.GlTF.Materials[0]
+		if m.NormalTexture.Index != -1 || m.OcclusionTexture.Index != -1 || m.EmissiveTexture.Index != -1 {
+			t.Errorf("%v: undefined textures were written. got %+v", file, m)
+		}
+		v, e := ReadFloats(f, 0)
+		if e != nil {
+			t.Fatalf("%v: %v", file, e)
+		}
+		if !reflect.DeepEqual(v, []float32{1, 2, 3}) {
+			t.Errorf("%v: wrong data. got %v, expected %v", file, v, []float32{1, 2, 3})
+		}
+	}
+}
+
+func Test_marshal_textureInfo(t *testing.T) {
+	m := Material{
+		PbrMetallicRoughness: MaterialPbrMetallicRoughness{
+			BaseColorTexture: TextureInfo{Index: -1},
+		},
+		NormalTexture:    MaterialNormalTextureInfo{Scale: 1, Index: -1},
+		OcclusionTexture: MaterialOcclusionTextureInfo{Strength: 1, Index: 0},
+		EmissiveTexture:  TextureInfo{Index: -1},
+	}
+	b, e := marshal(m)
+	if e != nil {
+		t.Fatal(e)
+	}
+	var out map[string]json.RawMessage
+	if e := json.Unmarshal(b, &out); e != nil {
+		t.Fatal(e)
+	}
+	for _, key := range []string{"normalTexture", "emissiveTexture"} {
+		if _, ok := out[key]; ok {
+			t.Errorf("texture info without index was written: %s", b)
+		}
+	}
+	if strings.Contains(string(out["pbrMetallicRoughness"]), "Texture") {
+		t.Errorf("texture info without index was written: %s", b)
+	}
+	if string(out["occlusionTexture"]) != `{"index":0,"strength":1,"texCoord":0}` {
+		t.Errorf("wrong occlusion texture. got %s", out["occlusionTexture"])
+	}
+}
+
+func TestFile_Save_badExtension(t *testing.T) {
+	f := &File{}
+	if e := f.Save(filepath.Join(t.TempDir(), "a.obj")); e == nil {
+		t.Error("expected error")
+	}
+}
@@ -1,143 +1,446 @@
 package model
 
 import (
+	"errors"
 	"fmt"
 	"os"
 
 	mgl "github.com/go-gl/mathgl/mgl32"
 
+	"github.com/patrick-jessen/goplay/engine/animation"
+	"github.com/patrick-jessen/goplay/engine/asset"
+	"github.com/patrick-jessen/goplay/engine/bounds"
+	"github.com/patrick-jessen/goplay/engine/log"
 	"github.com/patrick-jessen/goplay/engine/material"
 	"github.com/patrick-jessen/goplay/engine/model/geometry"
 	"github.com/patrick-jessen/goplay/engine/model/gltf"
+	"github.com/patrick-jessen/goplay/engine/queue"
 	"github.com/patrick-jessen/goplay/engine/scene"
-	"github.com/patrick-jessen/goplay/engine/shader"
 	"github.com/patrick-jessen/goplay/engine/texture"
 )
 
 const modelDir = "./assets/models/"
 
 var cache = make(map[string]Model)
-var nameIter = 0
+var validate = false
+
+// SetValidation sets whether models are validated when loaded.
+// Validation issues are logged, and models with errors fail to load.
+func SetValidation(enabled bool) {
+	validate = enabled
+}
 
 // Load returns a model by either loading it or reading from cache.
+// Panics if the model cannot be loaded.
 func Load(name string) Model {
+	m, e := TryLoad(name)
+	if e != nil {
+		panic(e)
+	}
+	return m
+}
+
+// TryLoad returns a model by either loading it or reading from cache.
+// Loading blocks the calling thread, see LoadAsync.
+// Errors are of type *asset.Error.
+func TryLoad(name string) (Model, error) {
 	// Read form cache
 	if val, ok := cache[name]; ok {
-		return val
+		return val, nil
 	}
 	// Load from disk
-	cache[name] = loadModel(name)
-	return cache[name]
+	m, e := loadModel(name)
+	if e != nil {
+		return Model{}, e
+	}
+	cache[name] = m
+	return m, nil
 }
 
 // loadModel loads a model from a file.
-func loadModel(name string) Model {
+func loadModel(name string) (Model, error) {
 	file := modelDir + name
 	if _, err := os.Stat(file + ".glb"); err == nil {
 		file += ".glb"
 	} else if _, err := os.Stat(file + ".gltf"); err == nil {
 		file += ".gltf"
 	} else {
-		panic("Model not found: " + name)
+		return Model{}, &asset.Error{File: file, Err: errors.New("model not found")}
 	}
 
-	return Model{file: gltf.Load(file)}
+	f, e := gltf.TryLoad(file)
+	if e != nil {
+		return Model{}, e
+	}
+
+	if validate {
+		var err error
+		for _, i := range gltf.Validate(f) {
+			if i.Severity == gltf.SeverityError && err == nil {
+				err = &asset.Error{File: file, Field: i.Pointer, Err: errors.New(i.Message)}
+			}
+			log.Warn("glTF validation issue", "file", file, "issue", i)
+		}
+		if err != nil {
+			return Model{}, err
+		}
+	}
+	return Model{file: f, shared: newShared()}, nil
 }
 
 type Model struct {
-	file *gltf.File
+	file   *gltf.File
+	shared *shared
 }
 
-func (m Model) Mount(sn *scene.Node) {
-	g := m.file.GlTF
-	scene := g.Scenes[g.Scene]
+// shared holds the resources which are shared by all mounts of a model.
+type shared struct {
+	geoms     map[[2]int]*geometry.Geometry // Keyed by mesh and primitive index.
+	refs      map[*geometry.Geometry]int    // Number of mesh renderers using each geometry.
+	materials map[int]material.Material     // Keyed by material index, or -1 for the default.
+}
+
+// newShared creates an empty set of shared resources.
+func newShared() *shared {
+	return &shared{
+		geoms:     make(map[[2]int]*geometry.Geometry),
+		refs:      make(map[*geometry.Geometry]int),
+		materials: make(map[int]material.Material),
+	}
+}
 
-	pos := sn.Position()
-	rot := sn.Rotation()
-	scal := sn.Scale()
+// sharesMaterial returns whether m is shared by the mounts.
+func (s *shared) sharesMaterial(m material.Material) bool {
+	for _, v := range s.materials {
+		if v == m {
+			return true
+		}
+	}
+	return false
+}
 
-	nameIter = 0
-	m.mountChild(sn, &gltf.Node{
-		Translation: []float32{pos.X(), pos.Y(), pos.Z()},
-		Rotation:    []float32{rot.W, rot.V.X(), rot.V.Y(), rot.V.Z()},
-		Scale:       []float32{scal.X(), scal.Y(), scal.Z()},
-		Children:    scene.Nodes,
-		Mesh:        -1,
-	})
+// release releases a reference to a geometry. The geometry is freed once
+// it is no longer used, and loaded again if the model is mounted again.
+func (s *shared) release(g *geometry.Geometry) {
+	s.refs[g]--
+	if s.refs[g] > 0 {
+		return
+	}
+	delete(s.refs, g)
+	for k, v := range s.geoms {
+		if v == g {
+			delete(s.geoms, k)
+		}
+	}
+	g.Free()
 }
 
-func (m Model) mountChild(sn *scene.Node, gn *gltf.Node) {
+// Instance is a model mounted onto a scene node.
+type Instance struct {
+	Root  *scene.Node
+	Nodes map[uint]*scene.Node // Maps glTF node indices to scene nodes.
+	Clips []*animation.Clip
+}
+
+// mounter holds the state of a model being mounted.
+type mounter struct {
+	Model
+	nodes   map[uint]*scene.Node  // Maps glTF node indices to scene nodes.
+	skinned map[*MeshRenderer]int // Maps skinned mesh renderers to glTF skin indices.
+}
+
+// Mount creates the node hierarchy of the model as children of sn.
+// If sn has an animation.ClipSetter component, such as an Animator, it is
+// given the clips of the model.
+// Geometries and materials are shared by all mounts of the model, such
+// that repeated mounts can be drawn as instances. Use
+// MeshRenderer.EditMaterial to modify the material of a single mount.
+func (m Model) Mount(sn *scene.Node) *Instance {
 	g := m.file.GlTF
+	mt := &mounter{
+		Model:   m,
+		nodes:   make(map[uint]*scene.Node),
+		skinned: make(map[*MeshRenderer]int),
+	}
+
+	// Without a default scene, the first scene is mounted
+	si := g.Scene
+	if si < 0 {
+		si = 0
+	}
+	if si < len(g.Scenes) {
+		mt.mountChildren(sn, g.Scenes[si].Nodes)
+	}
+	mt.resolveSkins()
+
+	inst := &Instance{Root: sn, Nodes: mt.nodes}
+	clips, e := animation.FromGlTF(m.file, mt.nodes)
+	if e != nil {
+		log.Error("could not load animations", "file", m.file.File, "error", e)
+	}
+	inst.Clips = clips
+
+	if a := scene.GetComponent[animation.ClipSetter](sn); a != nil {
+		a.SetClips(clips)
+	}
+	return inst
+}
 
-	sn.SetPosition(mgl.Vec3{gn.Translation[0], gn.Translation[1], gn.Translation[2]})
-	sn.SetRotation(mgl.Quat{W: gn.Rotation[0], V: mgl.Vec3{gn.Rotation[1], gn.Rotation[2], gn.Rotation[3]}})
-	sn.SetScale(mgl.Vec3{gn.Scale[0], gn.Scale[1], gn.Scale[2]})
+// mountChildren mounts the glTF nodes with the given indices as children of sn.
+func (mt *mounter) mountChildren(sn *scene.Node, children []uint) {
+	g := mt.file.GlTF
+	for _, nidx := range children {
+		gn := g.Nodes[nidx]
+		child := sn.MountChild(nodeName(sn, nidx, &gn))
+		mt.nodes[nidx] = child
+		mt.mountNode(child, &gn)
+	}
+}
+
+// nodeName returns a name for a glTF node, which is unique among the
+// children of parent. Unnamed nodes are named after their index, so
+// the names are stable across mounts.
+func nodeName(parent *scene.Node, idx uint, gn *gltf.Node) string {
+	nam := gn.Name
+	if len(nam) == 0 {
+		nam = fmt.Sprintf("node%v", idx)
+	}
+	if parent.Child(nam) != nil {
+		nam = fmt.Sprintf("%v_%v", nam, idx)
+	}
+	return nam
+}
+
+func (mt *mounter) mountNode(sn *scene.Node, gn *gltf.Node) {
+	g := mt.file.GlTF
+
+	// A node is transformed by either a matrix or TRS properties
+	mat := mgl.Ident4()
+	copy(mat[:], gn.Matrix)
+	if mat != mgl.Ident4() {
+		sn.SetMatrix(mat)
+	} else {
+		sn.SetPosition(mgl.Vec3{gn.Translation[0], gn.Translation[1], gn.Translation[2]})
+		// glTF stores quaternions as [x, y, z, w]
+		sn.SetRotation(mgl.Quat{W: gn.Rotation[3], V: mgl.Vec3{gn.Rotation[0], gn.Rotation[1], gn.Rotation[2]}})
+		sn.SetScale(mgl.Vec3{gn.Scale[0], gn.Scale[1], gn.Scale[2]})
+	}
 
 	if gn.Mesh >= 0 {
-		mr := &MeshRenderer{}
+		mr := &MeshRenderer{shared: mt.shared}
 		mesh := g.Meshes[gn.Mesh]
-		for _, p := range mesh.Primitives {
+		for pi := range mesh.Primitives {
+			p := &mesh.Primitives[pi]
+
 			// Set geometry
-			geom := gltf.GeometryFromPrimitive(m.file, &p)
+			geom, e := mt.geometry(gn.Mesh, pi)
+			if e != nil {
+				log.Error("could not load primitive", "file", mt.file.File, "mesh", gn.Mesh, "error", e)
+				continue
+			}
 			mr.geoms = append(mr.geoms, geom)
 
 			// Set material
 			if mr.Mat == nil {
-				if p.Material >= 0 {
-					gmat := g.Materials[p.Material]
-					mat := material.NewPBRMaterial()
-					if gmat.PbrMetallicRoughness.BaseColorTexture.Index >= 0 {
-						t := g.Textures[gmat.PbrMetallicRoughness.BaseColorTexture.Index]
-						tsrc := g.Images[t.Source]
-						mat.DiffuseTex = texture.Load(tsrc.URI)
-					}
-					if gmat.NormalTexture.Index >= 0 {
-						t := g.Textures[gmat.NormalTexture.Index]
-						tsrc := g.Images[t.Source]
-						mat.NormalTex = texture.Load(tsrc.URI)
-					}
-					mr.Mat = &mat
-				} else {
-					mr.Mat = material.NewDefaultMaterial()
-				}
+				mr.Mat = mt.material(p.Material)
 			}
 		}
+		if gn.Skin >= 0 {
+			mt.skinned[mr] = gn.Skin
+		}
+
+		// Default morph weights are given by the node or the mesh
+		if len(gn.Weights) > 0 {
+			mr.SetMorphWeights(gn.Weights)
+		} else {
+			mr.SetMorphWeights(mesh.Weights)
+		}
 
 		sn.AddComponent(mr)
 	}
 
-	for _, nidx := range gn.Children {
-		gn := g.Nodes[nidx]
+	mt.mountChildren(sn, gn.Children)
+}
 
-		nam := gn.Name
-		if len(nam) == 0 || sn.NewChild(gn.Name) != nil {
-			nam = fmt.Sprintf("%v", nameIter)
-			nameIter++
+// geometry returns the geometry of a primitive, which is loaded the first
+// time the primitive is mounted. Geometries decoded in advance are
+// uploaded. The caller holds a reference to it.
+func (mt *mounter) geometry(mesh, prim int) (*geometry.Geometry, error) {
+	key := [2]int{mesh, prim}
+	geom, ok := mt.shared.geoms[key]
+	if !ok {
+		var e error
+		geom, e = gltf.DecodePrimitive(mt.file, &mt.file.GlTF.Meshes[mesh].Primitives[prim])
+		if e != nil {
+			return nil, e
 		}
-		child := sn.NewChild(nam)
-		m.mountChild(child, &gn)
+		mt.shared.geoms[key] = geom
+	}
+	geom.Initialize()
+	mt.shared.refs[geom]++
+	return geom, nil
+}
+
+// material returns the material with the given index, which is created
+// the first time it is mounted. A negative index gives the default material.
+func (mt *mounter) material(idx int) material.Material {
+	if idx < 0 {
+		idx = -1
+	}
+	if mat, ok := mt.shared.materials[idx]; ok {
+		return mat
+	}
+	var mat material.Material
+	if idx >= 0 {
+		mat = mt.newMaterial(idx)
+	} else {
+		mat = material.NewDefaultMaterial()
+	}
+	mt.shared.materials[idx] = mat
+	return mat
+}
+
+// newMaterial creates the material with the given index.
+func (mt *mounter) newMaterial(idx int) material.Material {
+	gmat := &mt.file.GlTF.Materials[idx]
+	pbr := &gmat.PbrMetallicRoughness
+	mat := material.NewPBRMaterial()
+
+	copy(mat.BaseColorFactor[:], pbr.BaseColorFactor)
+	mat.BaseColorTex = mt.texture(pbr.BaseColorTexture.Index)
+	mat.MetallicFactor = pbr.MetallicFactor
+	mat.RoughnessFactor = pbr.RoughnessFactor
+	mat.MetallicRoughnessTex = mt.texture(pbr.MetallicRoughnessTexture.Index)
+	mat.NormalScale = gmat.NormalTexture.Scale
+	mat.NormalTex = mt.texture(gmat.NormalTexture.Index)
+	mat.OcclusionStrength = gmat.OcclusionTexture.Strength
+	mat.OcclusionTex = mt.texture(gmat.OcclusionTexture.Index)
+	copy(mat.EmissiveFactor[:], gmat.EmissiveFactor)
+	mat.EmissiveTex = mt.texture(gmat.EmissiveTexture.Index)
+	mat.AlphaMode = material.ParseAlphaMode(gmat.AlphaMode)
+	mat.AlphaCutoff = gmat.AlphaCutoff
+	mat.DoubleSided = gmat.DoubleSided
+	return &mat
+}
+
+// texture loads the texture with the given index.
+// Returns nil if the texture is undefined or has no image file.
+func (mt *mounter) texture(idx int) *texture.Texture {
+	g := &mt.file.GlTF
+	if idx < 0 || idx >= len(g.Textures) {
+		return nil
+	}
+	src := g.Textures[idx].Source
+	if src < 0 || src >= len(g.Images) || len(g.Images[src].URI) == 0 {
+		return nil
+	}
+	return texture.Load(g.Images[src].URI)
+}
+
+// resolveSkins attaches skins to skinned mesh renderers.
+// Must be called after all nodes are mounted, since joints may be
+// located anywhere in the node hierarchy.
+func (mt *mounter) resolveSkins() {
+	g := mt.file.GlTF
+	for mr, si := range mt.skinned {
+		if si >= len(g.Skins) {
+			log.Error("skin does not exist", "file", mt.file.File, "skin", si)
+			continue
+		}
+		sk, e := newSkin(mt.file, &g.Skins[si], mt.nodes)
+		if e != nil {
+			log.Error("could not load skin", "file", mt.file.File, "skin", si, "error", e)
+			continue
+		}
+		mr.skin = sk
 	}
 }
 
 type MeshRenderer struct {
-	node  *scene.Node
-	geoms []*geometry.Geometry
-	Mat   material.Material
+	node    *scene.Node
+	shared  *shared // Owner of the geometries, or nil if they are not shared.
+	geoms   []*geometry.Geometry
+	skin    *skin
+	weights []float32 // Weights of the morph targets.
+	Mat     material.Material
+}
+
+// EditMaterial returns the material of the mesh renderer for modification.
+// A material shared with other mounts of the model is first replaced by
+// a copy, such that modifications only affect this mesh renderer.
+func (mr *MeshRenderer) EditMaterial() material.Material {
+	if mr.shared == nil || !mr.shared.sharesMaterial(mr.Mat) {
+		return mr.Mat
+	}
+	if pm, ok := mr.Mat.(*material.PBRMaterial); ok {
+		c := *pm
+		mr.Mat = &c
+	}
+	return mr.Mat
+}
+
+// MorphWeights returns the weights of the morph targets.
+func (mr *MeshRenderer) MorphWeights() []float32 {
+	return mr.weights
+}
+
+// SetMorphWeights sets the weights of the morph targets.
+func (mr *MeshRenderer) SetMorphWeights(w []float32) {
+	if len(mr.weights) != len(w) {
+		mr.weights = make([]float32, len(w))
+	}
+	copy(mr.weights, w)
+}
+
+// Bounds returns the bounds of the geometries in world space.
+// Skinned meshes are deformed by their joints, so their bounds are
+// infinite.
+func (mr *MeshRenderer) Bounds() bounds.AABB {
+	if mr.skin != nil {
+		return bounds.Infinite()
+	}
+	world := mr.node.WorldTransform()
+	b := bounds.Empty()
+	for _, g := range mr.geoms {
+		b = b.Union(g.Bounds.Transform(world))
+	}
+	return b
 }
 
 func (mr *MeshRenderer) Initialize(n *scene.Node) {
 	mr.node = n
 }
-func (mr *MeshRenderer) Update() {
+func (mr *MeshRenderer) Update(float32) {
+}
+
+// OnDestroy releases the geometries of the mesh renderer. Geometries
+// shared with other mounts of the model are freed once unused.
+func (mr *MeshRenderer) OnDestroy() {
+	if mr.shared != nil {
+		for _, g := range mr.geoms {
+			mr.shared.release(g)
+		}
+	}
+	mr.geoms = nil
 }
 func (mr *MeshRenderer) Render() {
+	if !material.InPass(mr.Mat) {
+		return
+	}
 
 	world := mr.node.WorldTransform()
-	shader.SetModelMatrix(world)
-
-	mr.Mat.Apply()
+	var joints []mgl.Mat4
+	if mr.skin != nil {
+		joints = mr.skin.jointMatrices(world)
+	}
 
 	for _, g := range mr.geoms {
-		g.Draw()
+		queue.Submit(queue.Draw{
+			Geometry: g,
+			Material: mr.Mat,
+			World:    world,
+			Joints:   joints,
+			Weights:  mr.weights,
+		})
 	}
 }
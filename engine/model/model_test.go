@@ -0,0 +1,27 @@
+package model
+
+import (
+	"testing"
+
+	"github.com/patrick-jessen/goplay/engine/material"
+)
+
+func TestMeshRenderer_EditMaterial(t *testing.T) {
+	s := newShared()
+	mat := material.PBRMaterial{MetallicFactor: 1}
+	s.materials[0] = &mat
+	a := &MeshRenderer{shared: s, Mat: &mat}
+	b := &MeshRenderer{shared: s, Mat: &mat}
+
+	edited := a.EditMaterial().(*material.PBRMaterial)
+	edited.MetallicFactor = 0.5
+	if mat.MetallicFactor == 0.5 || b.Mat != material.Material(&mat) {
+		t.Error("shared material was modified")
+	}
+	if a.Mat != material.Material(edited) {
+		t.Error("mesh renderer does not use the edited material")
+	}
+	if a.EditMaterial() != material.Material(edited) {
+		t.Error("material was copied again")
+	}
+}
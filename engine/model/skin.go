@@ -0,0 +1,59 @@
+package model
+
+import (
+	"errors"
+	"fmt"
+
+	mgl "github.com/go-gl/mathgl/mgl32"
+
+	"github.com/patrick-jessen/goplay/engine/model/gltf"
+	"github.com/patrick-jessen/goplay/engine/scene"
+)
+
+// skin deforms a mesh using a hierarchy of joints.
+type skin struct {
+	joints      []*scene.Node
+	inverseBind []mgl.Mat4
+	mats        []mgl.Mat4 // Joint matrices of the last frame.
+}
+
+// newSkin creates a skin from a glTF skin.
+// nodes maps glTF node indices to the mounted scene nodes.
+func newSkin(f *gltf.File, s *gltf.Skin, nodes map[uint]*scene.Node) (*skin, error) {
+	sk := &skin{
+		joints:      make([]*scene.Node, len(s.Joints)),
+		inverseBind: make([]mgl.Mat4, len(s.Joints)),
+		mats:        make([]mgl.Mat4, len(s.Joints)),
+	}
+
+	for i, j := range s.Joints {
+		n, ok := nodes[j]
+		if !ok {
+			return nil, fmt.Errorf("joint node %v is not mounted", j)
+		}
+		sk.joints[i] = n
+		sk.inverseBind[i] = mgl.Ident4()
+	}
+
+	if s.InverseBindMatrices >= 0 {
+		mats, e := gltf.ReadMat4s(f, uint(s.InverseBindMatrices))
+		if e != nil {
+			return nil, e
+		}
+		if len(mats) < len(s.Joints) {
+			return nil, errors.New("too few inverse bind matrices")
+		}
+		copy(sk.inverseBind, mats)
+	}
+	return sk, nil
+}
+
+// jointMatrices calculates the joint matrices relative to the
+// world transform of the skinned mesh.
+func (s *skin) jointMatrices(world mgl.Mat4) []mgl.Mat4 {
+	inv := world.Inv()
+	for i, j := range s.joints {
+		s.mats[i] = inv.Mul4(j.WorldTransform()).Mul4(s.inverseBind[i])
+	}
+	return s.mats
+}
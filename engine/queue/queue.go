@@ -0,0 +1,214 @@
+// Package queue collects draw calls such that they can be sorted before
+// they are issued.
+//
+// Opaque draws are sorted by shader, material and geometry to minimize
+// state changes, and consecutive draws of the same geometry and material
+// are issued as a single instanced draw. Transparent draws are issued
+// afterwards from back to front, such that they blend correctly.
+package queue
+
+import (
+	"reflect"
+	"sort"
+	"unsafe"
+
+	mgl "github.com/go-gl/mathgl/mgl32"
+
+	"github.com/patrick-jessen/goplay/engine/material"
+	"github.com/patrick-jessen/goplay/engine/model/geometry"
+	"github.com/patrick-jessen/goplay/engine/shader"
+)
+
+// Draw is a draw call of a geometry.
+type Draw struct {
+	Geometry *geometry.Geometry
+	Material material.Material
+	World    mgl.Mat4   // Model to world transformation.
+	Joints   []mgl.Mat4 // Joint matrices of a skinned geometry, or nil.
+	Weights  []float32  // Morph target weights.
+
+	depth float32 // Squared distance from the eye.
+}
+
+// queue holds the draw calls being collected.
+type queue struct {
+	eye         mgl.Vec3
+	opaque      []Draw
+	transparent []Draw
+	instances   []mgl.Mat4 // Model matrices of the instanced draw being issued.
+}
+
+var active *queue
+var q queue
+
+// Begin starts collecting draw calls. Transparent draws are ordered by
+// their distance from eye.
+func Begin(eye mgl.Vec3) {
+	q.eye = eye
+	q.opaque = q.opaque[:0]
+	q.transparent = q.transparent[:0]
+	active = &q
+}
+
+// End sorts and issues the draw calls collected since Begin.
+func End() {
+	active = nil
+	sortDraws(q.opaque, q.transparent)
+
+	// Consecutive draws of the same material need not apply it again
+	var prev uintptr
+	apply := func(m material.Material) bool {
+		id := materialID(m)
+		changed := id == 0 || id != prev
+		prev = id
+		return changed
+	}
+	for draws := q.opaque; len(draws) > 0; {
+		n := batchSize(draws)
+		if n > 1 {
+			q.issueInstanced(draws[:n], apply(draws[0].Material))
+		} else {
+			draws[0].issue(apply(draws[0].Material))
+		}
+		draws = draws[n:]
+	}
+	for i := range q.transparent {
+		q.transparent[i].issue(apply(q.transparent[i].Material))
+	}
+	shader.SetInstanceMatrices(nil)
+
+	// Do not keep the scene alive through the queue
+	for i := range q.opaque {
+		q.opaque[i] = Draw{}
+	}
+	for i := range q.transparent {
+		q.transparent[i] = Draw{}
+	}
+}
+
+// Submit adds a draw call to the queue. Outside of Begin and End, the
+// draw is issued immediately.
+func Submit(d Draw) {
+	if active == nil {
+		d.issue(true)
+		return
+	}
+
+	// Order by the center of the geometry, or its origin if unbounded
+	center := d.World.Col(3).Vec3()
+	if b := d.Geometry.Bounds; !b.IsEmpty() && !b.IsInfinite() {
+		center = mgl.TransformCoordinate(b.Center(), d.World)
+	}
+	diff := center.Sub(active.eye)
+	d.depth = diff.Dot(diff)
+
+	if material.Blended(d.Material) {
+		active.transparent = append(active.transparent, d)
+	} else {
+		active.opaque = append(active.opaque, d)
+	}
+}
+
+// sortDraws sorts opaque draws by state and then front to back, and
+// transparent draws back to front.
+func sortDraws(opaque, transparent []Draw) {
+	sort.SliceStable(opaque, func(i, j int) bool {
+		a, b := &opaque[i], &opaque[j]
+		if sa, sb := shaderID(a.Material), shaderID(b.Material); sa != sb {
+			return sa < sb
+		}
+		if ma, mb := materialID(a.Material), materialID(b.Material); ma != mb {
+			return ma < mb
+		}
+		if a.Geometry != b.Geometry {
+			return uintptr(unsafe.Pointer(a.Geometry)) < uintptr(unsafe.Pointer(b.Geometry))
+		}
+		return a.depth < b.depth
+	})
+	sort.SliceStable(transparent, func(i, j int) bool {
+		return transparent[i].depth > transparent[j].depth
+	})
+}
+
+// batchSize returns the number of leading draws which can be issued as
+// a single instanced draw. Only PBR materials are rendered by shaders
+// supporting instancing. Skinned draws are never instanced, and morphed
+// draws only if their weights are equal.
+func batchSize(draws []Draw) int {
+	first := &draws[0]
+	if _, ok := first.Material.(*material.PBRMaterial); !ok || len(first.Joints) > 0 {
+		return 1
+	}
+	n := 1
+	for n < len(draws) && n < shader.MaxInstances {
+		d := &draws[n]
+		if d.Geometry != first.Geometry || materialID(d.Material) != materialID(first.Material) ||
+			len(d.Joints) > 0 || !equalWeights(d.Weights, first.Weights) {
+			break
+		}
+		n++
+	}
+	return n
+}
+
+// equalWeights returns whether two sets of morph target weights are equal.
+func equalWeights(a, b []float32) bool {
+	if len(a) != len(b) {
+		return false
+	}
+	for i := range a {
+		if a[i] != b[i] {
+			return false
+		}
+	}
+	return true
+}
+
+// shaderID returns the shader program of a material.
+// Returns 0 if it is unknown.
+func shaderID(m material.Material) uint32 {
+	if p, ok := m.(*material.PBRMaterial); ok {
+		return p.Shader.ID()
+	}
+	return 0
+}
+
+// materialID returns a number identifying a material.
+// Returns 0 if the material is not a pointer.
+func materialID(m material.Material) uintptr {
+	v := reflect.ValueOf(m)
+	if v.Kind() == reflect.Ptr {
+		return v.Pointer()
+	}
+	return 0
+}
+
+// issue issues the draw call.
+// If apply is false, the material is assumed to be applied already.
+func (d *Draw) issue(apply bool) {
+	shader.SetInstanceMatrices(nil)
+	shader.SetModelMatrix(d.World)
+	shader.SetJointMatrices(d.Joints)
+	shader.SetMorphWeights(d.Weights)
+	if apply {
+		d.Material.Apply()
+	}
+	d.Geometry.Draw()
+}
+
+// issueInstanced issues draws of the same geometry and material as a
+// single instanced draw.
+// If apply is false, the material is assumed to be applied already.
+func (q *queue) issueInstanced(draws []Draw, apply bool) {
+	q.instances = q.instances[:0]
+	for i := range draws {
+		q.instances = append(q.instances, draws[i].World)
+	}
+	shader.SetInstanceMatrices(q.instances)
+	shader.SetJointMatrices(nil)
+	shader.SetMorphWeights(draws[0].Weights)
+	if apply {
+		draws[0].Material.Apply()
+	}
+	draws[0].Geometry.DrawInstanced(int32(len(draws)))
+}
@@ -0,0 +1,86 @@
+package queue
+
+import (
+	"reflect"
+	"testing"
+
+	mgl "github.com/go-gl/mathgl/mgl32"
+
+	"github.com/patrick-jessen/goplay/engine/material"
+	"github.com/patrick-jessen/goplay/engine/model/geometry"
+	"github.com/patrick-jessen/goplay/engine/shader"
+)
+
+func TestSubmit(t *testing.T) {
+	opaqueA := &material.PBRMaterial{}
+	opaqueB := &material.PBRMaterial{}
+	blended := &material.PBRMaterial{AlphaMode: material.AlphaBlend}
+	geom := &geometry.Geometry{}
+
+	at := func(z float32) mgl.Mat4 { return mgl.Translate3D(0, 0, z) }
+	Begin(mgl.Vec3{})
+	defer func() { active = nil }()
+	Submit(Draw{Geometry: geom, Material: blended, World: at(-1)})
+	Submit(Draw{Geometry: geom, Material: opaqueB, World: at(-2)})
+	Submit(Draw{Geometry: geom, Material: opaqueA, World: at(-3)})
+	Submit(Draw{Geometry: geom, Material: blended, World: at(-5)})
+	Submit(Draw{Geometry: geom, Material: opaqueB, World: at(-1)})
+
+	if len(q.opaque) != 3 || len(q.transparent) != 2 {
+		t.Fatalf("wrong number of draws. got %v opaque and %v transparent", len(q.opaque), len(q.transparent))
+	}
+	sortDraws(q.opaque, q.transparent)
+
+	// Draws of the same material are adjacent, front to back
+	if q.opaque[1].Material != q.opaque[0].Material && q.opaque[1].Material != q.opaque[2].Material {
+		t.Error("draws of the same material are not adjacent")
+	}
+	for i := 0; i < 2; i++ {
+		a, b := q.opaque[i], q.opaque[i+1]
+		if a.Material == b.Material && a.depth > b.depth {
+			t.Errorf("draws %v and %v are not front to back", i, i+1)
+		}
+	}
+
+	// Transparent draws are back to front
+	if q.transparent[0].World != at(-5) || q.transparent[1].World != at(-1) {
+		t.Errorf("transparent draws are not back to front. got %v", q.transparent)
+	}
+}
+
+func TestBatchSize(t *testing.T) {
+	matA := &material.PBRMaterial{}
+	matB := &material.PBRMaterial{}
+	geomA := &geometry.Geometry{}
+	geomB := &geometry.Geometry{}
+
+	draws := []Draw{
+		{Geometry: geomA, Material: matA},
+		{Geometry: geomA, Material: matA},
+		{Geometry: geomA, Material: matA, Weights: []float32{0.5}},
+		{Geometry: geomA, Material: matA, Weights: []float32{0.5}},
+		{Geometry: geomA, Material: matA, Joints: []mgl.Mat4{mgl.Ident4()}},
+		{Geometry: geomA, Material: matA, Joints: []mgl.Mat4{mgl.Ident4()}},
+		{Geometry: geomA, Material: matB},
+		{Geometry: geomB, Material: matB},
+	}
+	var sizes []int
+	for rest := draws; len(rest) > 0; {
+		n := batchSize(rest)
+		sizes = append(sizes, n)
+		rest = rest[n:]
+	}
+
+	expected := []int{2, 2, 1, 1, 1, 1}
+	if !reflect.DeepEqual(sizes, expected) {
+		t.Errorf("wrong batches. got %v, expected %v", sizes, expected)
+	}
+
+	many := make([]Draw, shader.MaxInstances+1)
+	for i := range many {
+		many[i] = Draw{Geometry: geomA, Material: matA}
+	}
+	if n := batchSize(many); n != shader.MaxInstances {
+		t.Errorf("batch exceeds the maximum number of instances. got %v", n)
+	}
+}
@@ -0,0 +1,280 @@
+package renderer
+
+import (
+	"math"
+	"unsafe"
+
+	"github.com/go-gl/gl/v3.2-core/gl"
+	mgl "github.com/go-gl/mathgl/mgl32"
+
+	"github.com/patrick-jessen/goplay/engine/framebuffer"
+	"github.com/patrick-jessen/goplay/engine/material"
+	"github.com/patrick-jessen/goplay/engine/scene"
+	"github.com/patrick-jessen/goplay/engine/shader"
+	"github.com/patrick-jessen/goplay/engine/window"
+)
+
+// tileSize is the size in pixels of the screen tiles used for light culling.
+const tileSize = 32
+
+// Texture units of the light culling data, following the shadow maps.
+const (
+	lightDataUnit = shader.PointShadowUnit + shader.MaxPointShadows
+	tileDataUnit  = lightDataUnit + 1
+)
+
+// deferredRenderer renders opaque materials into a G-buffer, which is
+// then lit in screen space. Each screen tile is only lit by the lights
+// overlapping it. Blended materials are rendered afterwards using
+// forward shading. Multisampling is not supported.
+type deferredRenderer struct {
+	gBuffer       *framebuffer.FrameBuffer // Base color, normal and emissive.
+	lightBuffer   *framebuffer.FrameBuffer // Shaded image.
+	shadows       *shadowMaps
+	post          *postChain
+	width, height int
+
+	gBufferShader shader.Shader
+	lightShader   shader.Shader
+	lightScene    scene.Scene // Full screen quad lighting the G-buffer.
+	postScene     scene.Scene
+
+	lightData  texBuffer
+	tileData   texBuffer
+	uInvVP     int32
+	uNumTilesX int32
+}
+
+func (d *deferredRenderer) initialize() {
+	d.width, d.height = window.Settings.Size()
+	d.gBuffer = framebuffer.New(d.width, d.height, 3, 0)
+	d.lightBuffer = framebuffer.New(d.width, d.height, 1, 0)
+	d.shadows = newShadowMaps(Settings.curSR)
+	d.post = newPostChain(d.width, d.height)
+
+	d.gBufferShader = shader.Load("gbuffer")
+	d.lightShader = shader.Load("deferred")
+	gl.Uniform1i(d.lightShader.GetUniform("lightData"), lightDataUnit)
+	gl.Uniform1i(d.lightShader.GetUniform("tileData"), tileDataUnit)
+	gl.Uniform1i(d.lightShader.GetUniform("tileSize"), tileSize)
+	d.uInvVP = d.lightShader.GetUniform("invViewProj")
+	d.uNumTilesX = d.lightShader.GetUniform("numTilesX")
+	d.lightScene = newQuadScene(&quadMat{Shader: d.lightShader})
+
+	d.postScene = scene.New()
+	if Settings.curAA == FXAA {
+		d.postScene = newFXAAScene(d.width, d.height)
+	}
+
+	d.lightData = newTexBuffer(gl.RGBA32F)
+	d.tileData = newTexBuffer(gl.R32I)
+}
+
+func (d *deferredRenderer) deinitialize() {
+	d.gBuffer.Free()
+	d.lightBuffer.Free()
+	d.shadows.free()
+	d.post.free()
+	d.lightData.free()
+	d.tileData.free()
+}
+
+func (d *deferredRenderer) render(sc *scene.Scene, lights []shader.Light) {
+
+	// Shadow map pass
+	d.shadows.render(sc, lights)
+
+	// Only the transparent pass is limited in the number of lights
+	if len(lights) > shader.MaxLights {
+		shader.SetLights(lights[:shader.MaxLights])
+	} else {
+		shader.SetLights(lights)
+	}
+
+	// Geometry pass
+	d.gBuffer.Bind()
+	gl.Viewport(0, 0, int32(d.width), int32(d.height))
+	gl.Clear(gl.COLOR_BUFFER_BIT | gl.DEPTH_BUFFER_BIT)
+	material.SetPass(material.PassOpaque)
+	shader.SetOverride(&d.gBufferShader)
+	stats = renderVisible(sc)
+	shader.SetOverride(nil)
+	gl.Disable(gl.BLEND)
+	gl.Enable(gl.CULL_FACE)
+
+	// Lighting pass. The depth is kept for the transparent pass. Without a
+	// camera, the geometry is drawn as by the forward renderer and lit as
+	// if in clip space.
+	d.gBuffer.Blit(d.lightBuffer, d.width, d.height, true)
+	d.lightBuffer.Bind()
+	gl.Clear(gl.COLOR_BUFFER_BIT)
+	vp := mgl.Ident4()
+	if cam := sc.Camera(); cam != nil {
+		vp = cam.ViewProjectionMatrix()
+	}
+	inv := vp.Inv()
+	d.lightShader.Use()
+	gl.UniformMatrix4fv(d.uInvVP, 1, false, &inv[0])
+	d.uploadLights(lights, vp)
+
+	for i := 0; i < 3; i++ {
+		d.gBuffer.BindColorTexture(i, i)
+	}
+	d.gBuffer.BindDepthTexture(3)
+	d.shadows.bind()
+	d.lightData.bind(lightDataUnit)
+	d.tileData.bind(tileDataUnit)
+
+	gl.Disable(gl.DEPTH_TEST)
+	d.lightScene.Render()
+	gl.Enable(gl.DEPTH_TEST)
+
+	// Transparent pass
+	material.SetPass(material.PassTransparent)
+	renderVisible(sc)
+	material.SetPass(material.PassAll)
+	gl.Disable(gl.BLEND)
+	gl.Enable(gl.CULL_FACE)
+	gl.ActiveTexture(gl.TEXTURE0)
+
+	// Postprocessing pass
+	present(d.post.apply(sc, d.lightBuffer), &d.postScene, d.width, d.height)
+}
+
+// uploadLights uploads the lights and assigns them to screen tiles.
+// Assumes the lighting shader is in use.
+func (d *deferredRenderer) uploadLights(lights []shader.Light, viewProj mgl.Mat4) {
+	data := shader.PackLights(lights)
+	d.lightData.upload(gl.Ptr(data), len(data)*4)
+
+	tiles := lightTiles(lights, viewProj, d.width, d.height)
+	d.tileData.upload(gl.Ptr(tiles), len(tiles)*4)
+
+	numX, _ := numTiles(d.width, d.height)
+	gl.Uniform1i(d.uNumTilesX, int32(numX))
+}
+
+// numTiles returns the number of tiles covering the screen.
+func numTiles(width, height int) (int, int) {
+	return (width + tileSize - 1) / tileSize, (height + tileSize - 1) / tileSize
+}
+
+// lightTiles assigns lights to the screen tiles they may affect.
+// Returns [offset, count] for each tile, row by row, followed by the
+// indices of the lights. Offsets are relative to the start of the data.
+func lightTiles(lights []shader.Light, viewProj mgl.Mat4, width, height int) []int32 {
+	numX, numY := numTiles(width, height)
+	lists := make([][]int32, numX*numY)
+
+	for i := range lights {
+		x0, y0, x1, y1 := 0, 0, numX-1, numY-1
+		if b, ok := lightBounds(&lights[i], viewProj); ok {
+			if b[2] < -1 || b[0] > 1 || b[3] < -1 || b[1] > 1 {
+				continue // Off screen
+			}
+			toTile := func(ndc float32, size, num int) int {
+				t := int((ndc*0.5 + 0.5) * float32(size) / tileSize)
+				if t < 0 {
+					return 0
+				}
+				if t >= num {
+					return num - 1
+				}
+				return t
+			}
+			x0, x1 = toTile(b[0], width, numX), toTile(b[2], width, numX)
+			y0, y1 = toTile(b[1], height, numY), toTile(b[3], height, numY)
+		}
+		for y := y0; y <= y1; y++ {
+			for x := x0; x <= x1; x++ {
+				t := y*numX + x
+				lists[t] = append(lists[t], int32(i))
+			}
+		}
+	}
+
+	data := make([]int32, 2*len(lists))
+	for t, list := range lists {
+		data[2*t] = int32(len(data))
+		data[2*t+1] = int32(len(list))
+		data = append(data, list...)
+	}
+	return data
+}
+
+// lightBounds returns the screen bounds [minX, minY, maxX, maxY] of a
+// light in normalized device coordinates. The bounds are empty if the
+// light is behind the camera. Returns false if the light may affect the
+// whole screen.
+func lightBounds(l *shader.Light, viewProj mgl.Mat4) ([4]float32, bool) {
+	b := [4]float32{math.MaxFloat32, math.MaxFloat32, -math.MaxFloat32, -math.MaxFloat32}
+	if l.Type == shader.LightDirectional || l.Range <= 0 {
+		return b, false
+	}
+
+	// Project the corners of the light's bounding box
+	var behind int
+	for i := 0; i < 8; i++ {
+		offset := mgl.Vec3{-l.Range, -l.Range, -l.Range}
+		for axis := 0; axis < 3; axis++ {
+			if i&(1<<uint(axis)) != 0 {
+				offset[axis] = l.Range
+			}
+		}
+		c := viewProj.Mul4x1(l.Position.Add(offset).Vec4(1))
+		if c.W() <= 0 {
+			behind++
+			continue
+		}
+		x, y := c.X()/c.W(), c.Y()/c.W()
+		b[0] = float32(math.Min(float64(b[0]), float64(x)))
+		b[1] = float32(math.Min(float64(b[1]), float64(y)))
+		b[2] = float32(math.Max(float64(b[2]), float64(x)))
+		b[3] = float32(math.Max(float64(b[3]), float64(y)))
+	}
+	// A box crossing the camera plane cannot be bounded on screen. A
+	// box entirely behind the camera keeps empty bounds.
+	if behind > 0 && behind < 8 {
+		return b, false
+	}
+	return b, true
+}
+
+// texBuffer is a buffer which is sampled as a texture.
+type texBuffer struct {
+	buffer  uint32
+	texture uint32
+}
+
+// newTexBuffer creates a buffer texture of the given format.
+func newTexBuffer(format uint32) texBuffer {
+	var t texBuffer
+	gl.GenBuffers(1, &t.buffer)
+	gl.GenTextures(1, &t.texture)
+	gl.BindBuffer(gl.TEXTURE_BUFFER, t.buffer)
+	gl.BufferData(gl.TEXTURE_BUFFER, 16, nil, gl.DYNAMIC_DRAW)
+	gl.BindTexture(gl.TEXTURE_BUFFER, t.texture)
+	gl.TexBuffer(gl.TEXTURE_BUFFER, format, t.buffer)
+	gl.BindTexture(gl.TEXTURE_BUFFER, 0)
+	return t
+}
+
+// upload replaces the content of the buffer.
+func (t *texBuffer) upload(data unsafe.Pointer, size int) {
+	if size == 0 {
+		return
+	}
+	gl.BindBuffer(gl.TEXTURE_BUFFER, t.buffer)
+	gl.BufferData(gl.TEXTURE_BUFFER, size, data, gl.DYNAMIC_DRAW)
+}
+
+// bind binds the texture to the given texture unit.
+func (t *texBuffer) bind(unit int) {
+	gl.ActiveTexture(gl.TEXTURE0 + uint32(unit))
+	gl.BindTexture(gl.TEXTURE_BUFFER, t.texture)
+}
+
+func (t *texBuffer) free() {
+	gl.DeleteTextures(1, &t.texture)
+	gl.DeleteBuffers(1, &t.buffer)
+}
@@ -0,0 +1,44 @@
+package renderer
+
+import (
+	"testing"
+
+	mgl "github.com/go-gl/mathgl/mgl32"
+
+	"github.com/patrick-jessen/goplay/engine/shader"
+)
+
+func TestLightTiles(t *testing.T) {
+	// 4x2 tiles looking down -Z
+	width, height := 4*tileSize, 2*tileSize
+	proj := mgl.Perspective(mgl.DegToRad(90), 2, 0.1, 100)
+	view := mgl.LookAtV(mgl.Vec3{}, mgl.Vec3{0, 0, -1}, mgl.Vec3{0, 1, 0})
+	vp := proj.Mul4(view)
+
+	lights := []shader.Light{
+		{Type: shader.LightDirectional},
+		{Type: shader.LightPoint, Position: mgl.Vec3{-15, 5, -10}, Range: 1}, // Top left tile
+		{Type: shader.LightPoint, Position: mgl.Vec3{0, 0, 10}, Range: 1},    // Behind the camera
+		{Type: shader.LightPoint, Position: mgl.Vec3{100, 0, -10}, Range: 1}, // Off screen
+	}
+	data := lightTiles(lights, vp, width, height)
+
+	expected := [][]int32{
+		{0}, {0}, {0}, {0},
+		{0, 1}, {0}, {0}, {0},
+	}
+	for i, e := range expected {
+		offset, count := data[2*i], data[2*i+1]
+		got := data[offset : offset+count]
+		if len(got) != len(e) {
+			t.Errorf("tile %v: wrong lights. got %v, expected %v", i, got, e)
+			continue
+		}
+		for j := range e {
+			if got[j] != e[j] {
+				t.Errorf("tile %v: wrong lights. got %v, expected %v", i, got, e)
+				break
+			}
+		}
+	}
+}
@@ -19,6 +19,8 @@ func (m quadMat) Apply() {
 
 type forwardRenderer struct {
 	shaderFrameBuffer *framebuffer.FrameBuffer
+	shadows           *shadowMaps
+	post              *postChain
 	width, height     int
 	postScene         scene.Scene
 }
@@ -32,14 +34,7 @@ func (f *forwardRenderer) initialize() {
 	switch Settings.curAA {
 	case NoAA:
 	case FXAA:
-		model.Load("quad").Mount(f.postScene.Root)
-		s := shader.Load("fxaa")
-		uRes := s.GetUniform("resolution")
-		gl.Uniform2f(uRes, float32(f.width), float32(f.height))
-
-		f.postScene.Root.Child("0").Component("MeshRenderer").(*model.MeshRenderer).Mat = &quadMat{
-			Shader: s,
-		}
+		f.postScene = newFXAAScene(f.width, f.height)
 	case MSAAx2:
 		msLevel = 2
 	case MSAAx4:
@@ -51,38 +46,72 @@ func (f *forwardRenderer) initialize() {
 	}
 
 	f.shaderFrameBuffer = framebuffer.New(f.width, f.height, 1, msLevel)
+	f.shadows = newShadowMaps(Settings.curSR)
+	f.post = newPostChain(f.width, f.height)
 }
 
 func (f *forwardRenderer) deinitialize() {
 	f.shaderFrameBuffer.Free()
+	f.shadows.free()
+	f.post.free()
 }
 
-func (f *forwardRenderer) render(scene *scene.Scene) {
+func (f *forwardRenderer) render(scene *scene.Scene, lights []shader.Light) {
 
 	// Shadow map pass
-	f.renderShadows()
+	f.shadows.render(scene, lights)
+	shader.SetLights(lights)
 
 	// Shading pass
 	f.shaderFrameBuffer.Bind()
+	gl.Viewport(0, 0, int32(f.width), int32(f.height))
 	gl.Clear(gl.COLOR_BUFFER_BIT | gl.DEPTH_BUFFER_BIT)
-	scene.Render()
+	f.shadows.bind()
+	stats = renderVisible(scene)
+
+	// Materials may change blending and culling
+	gl.Disable(gl.BLEND)
+	gl.Enable(gl.CULL_FACE)
 
 	// Postprocessing pass
+	present(f.post.apply(scene, f.shaderFrameBuffer), &f.postScene, f.width, f.height)
+}
+
+// newQuadScene creates a scene holding a full screen quad, which is
+// rendered using the given material.
+func newQuadScene(mat *quadMat) scene.Scene {
+	sc := scene.New()
+	quad := model.Load("quad").Mount(sc.Root)
+	quad.Nodes[0].Component("MeshRenderer").(*model.MeshRenderer).Mat = mat
+	return sc
+}
+
+// newFXAAScene creates a scene which applies FXAA to texture unit 0.
+func newFXAAScene(width, height int) scene.Scene {
+	s := shader.Load("fxaa")
+	uRes := s.GetUniform("resolution")
+	gl.Uniform2f(uRes, float32(width), float32(height))
+	return newQuadScene(&quadMat{Shader: s})
+}
+
+// present draws the first color attachment of fb onto the target.
+// If FXAA is enabled, it is applied by rendering postScene.
+func present(fb *framebuffer.FrameBuffer, postScene *scene.Scene, width, height int) {
 	switch Settings.curAA {
 	case FXAA:
-		framebuffer.Unbind()
+		if target != nil {
+			target.Bind()
+		} else {
+			framebuffer.Unbind()
+		}
 		gl.Clear(gl.COLOR_BUFFER_BIT | gl.DEPTH_BUFFER_BIT)
 
-		f.shaderFrameBuffer.BindColorTexture(0, 0)
-		f.postScene.Render()
+		fb.BindColorTexture(0, 0)
+		postScene.Render()
 	default:
 		// 1. NoAA blits onto default frame buffer 1:1.
 		// 2. MSAAx_ blits onto default frame buffer and
 		// performs linear interpolation on samples.
-		f.shaderFrameBuffer.Blit(nil, f.width, f.height, false)
+		fb.Blit(target, width, height, false)
 	}
 }
-
-func (f *forwardRenderer) renderShadows() {
-	// TODO
-}
@@ -0,0 +1,417 @@
+package renderer
+
+import (
+	"errors"
+	"fmt"
+
+	"github.com/go-gl/gl/v3.2-core/gl"
+
+	"github.com/patrick-jessen/goplay/engine/framebuffer"
+	"github.com/patrick-jessen/goplay/engine/log"
+	"github.com/patrick-jessen/goplay/engine/scene"
+	"github.com/patrick-jessen/goplay/engine/shader"
+	"github.com/patrick-jessen/goplay/engine/texture"
+)
+
+func init() {
+	scene.RegisterComponent(&PostProcess{})
+}
+
+// EffectType is the type of a post-processing effect.
+type EffectType int
+
+const (
+	Exposure EffectType = iota
+	TonemapACES
+	TonemapReinhard
+	Bloom
+	SSAO
+	ColorGrading
+	Vignette
+	Gamma
+)
+
+// effectInfo describes a type of effect.
+type effectInfo struct {
+	name   string
+	shader string
+	params map[string]float32 // Default parameters.
+}
+
+var effectInfos = [...]effectInfo{
+	Exposure:        {"Exposure", "exposure", map[string]float32{"exposure": 0}},
+	TonemapACES:     {"TonemapACES", "tonemap", map[string]float32{}},
+	TonemapReinhard: {"TonemapReinhard", "tonemap", map[string]float32{"whitePoint": 4}},
+	Bloom:           {"Bloom", "bloom", map[string]float32{"threshold": 1, "intensity": 0.1, "radius": 1}},
+	SSAO:            {"SSAO", "ssao", map[string]float32{"radius": 0.5, "intensity": 1, "bias": 0.025}},
+	ColorGrading:    {"ColorGrading", "grading", map[string]float32{"intensity": 1}},
+	Vignette:        {"Vignette", "vignette", map[string]float32{"intensity": 0.4, "radius": 0.6, "smoothness": 0.5}},
+	Gamma:           {"Gamma", "gamma", map[string]float32{"gamma": 1}},
+}
+
+func (t EffectType) String() string {
+	if t < 0 || int(t) >= len(effectInfos) {
+		return fmt.Sprintf("EffectType(%v)", int(t))
+	}
+	return effectInfos[t].name
+}
+
+// MarshalText encodes the effect type by name.
+func (t EffectType) MarshalText() ([]byte, error) {
+	if t < 0 || int(t) >= len(effectInfos) {
+		return nil, fmt.Errorf("invalid effect type %v", int(t))
+	}
+	return []byte(effectInfos[t].name), nil
+}
+
+// UnmarshalText decodes the effect type from its name.
+func (t *EffectType) UnmarshalText(b []byte) error {
+	for i, info := range effectInfos {
+		if info.name == string(b) {
+			*t = EffectType(i)
+			return nil
+		}
+	}
+	return fmt.Errorf("unknown effect type %q", b)
+}
+
+// Effect is a step of the post-processing chain.
+type Effect struct {
+	Type EffectType
+	// Params overrides the default parameters of the effect type.
+	Params map[string]float32 `json:",omitempty"`
+	// Texture is the lookup table used by ColorGrading, relative to
+	// assets/textures/. It holds N slices of NxN texels side by side.
+	Texture string `json:",omitempty"`
+}
+
+// Parameters returns the parameters of the effect, including defaults.
+func (e *Effect) Parameters() map[string]float32 {
+	out := make(map[string]float32)
+	if e.Type < 0 || int(e.Type) >= len(effectInfos) {
+		return out
+	}
+	for k, v := range effectInfos[e.Type].params {
+		out[k] = v
+	}
+	for k, v := range e.Params {
+		out[k] = v
+	}
+	return out
+}
+
+// validate returns an error if the effect cannot be applied.
+func (e *Effect) validate() error {
+	if e.Type < 0 || int(e.Type) >= len(effectInfos) {
+		return fmt.Errorf("invalid effect type %v", int(e.Type))
+	}
+	for k := range e.Params {
+		if _, ok := effectInfos[e.Type].params[k]; !ok {
+			return fmt.Errorf("unknown parameter %q", k)
+		}
+	}
+	if e.Type == ColorGrading && e.Texture == "" {
+		return errors.New("missing lookup table")
+	}
+	return nil
+}
+
+// cloneEffects returns a deep copy of effects.
+func cloneEffects(effects []Effect) []Effect {
+	if len(effects) == 0 {
+		return nil
+	}
+	out := make([]Effect, len(effects))
+	for i, e := range effects {
+		out[i] = e
+		if e.Params != nil {
+			out[i].Params = make(map[string]float32, len(e.Params))
+			for k, v := range e.Params {
+				out[i].Params[k] = v
+			}
+		}
+	}
+	return out
+}
+
+// PostProcess is a component which replaces the post-processing chain
+// of the renderer settings while its node holds the scene camera.
+// Changed must be called after modifying Effects.
+type PostProcess struct {
+	Effects []Effect
+
+	version int // Incremented when Effects are modified.
+}
+
+// Changed marks the effects as modified, such that the chain is rebuilt
+// in the next frame.
+func (p *PostProcess) Changed() {
+	p.version++
+}
+
+func (p *PostProcess) Initialize(*scene.Node) {}
+func (p *PostProcess) Update(float32)         {}
+func (p *PostProcess) Render()                {}
+
+// effectsOf returns the post-processing chain used for a scene, and the
+// component holding it. The component is nil if the chain of the renderer
+// settings is used.
+func effectsOf(sc *scene.Scene) ([]Effect, *PostProcess) {
+	if cam := sc.Camera(); cam != nil {
+		if pp := scene.GetComponent[*PostProcess](cam.Node()); pp != nil {
+			return pp.Effects, pp
+		}
+	}
+	return Settings.curPost, nil
+}
+
+// Texture units used by effects.
+const (
+	effectColorUnit = 0 // Output of the previous effect.
+	effectDepthUnit = 1 // Depth of the scene.
+	effectAuxUnit   = 2 // Texture specific to the effect.
+)
+
+// pass is an effect ready for rendering.
+type pass struct {
+	effect   Effect
+	shader   shader.Shader
+	values   map[string]float32
+	uniforms map[int32]float32 // Parameters by uniform location.
+	lut      *texture.Texture
+
+	uProj, uInvProj    int32
+	uStage, uDirection int32
+
+	// Tonemapping effects share a shader, so the operator is set on each
+	// render.
+	uOperator, operator int32
+}
+
+// postChain applies the post-processing effects to the rendered image.
+// Effects ping-pong between two frame buffers.
+type postChain struct {
+	width, height int
+	effects       []Effect // The effects the passes were built from.
+	passes        []*pass
+
+	// The component and its version the passes were built from. The
+	// settings do not change during the lifetime of a chain.
+	built   bool
+	source  *PostProcess
+	version int
+
+	resolved *framebuffer.FrameBuffer    // Resolved color and depth of the scene.
+	ping     [2]*framebuffer.FrameBuffer // Outputs of the effects.
+	bloom    [2]*framebuffer.FrameBuffer // Half resolution, used by Bloom.
+
+	quad scene.Scene
+	mat  *quadMat
+}
+
+// newPostChain creates a post-processing chain for images of the given
+// size. Buffers are allocated once the chain has effects.
+func newPostChain(width, height int) *postChain {
+	c := &postChain{
+		width:  width,
+		height: height,
+		mat:    &quadMat{},
+	}
+	c.quad = newQuadScene(c.mat)
+	return c
+}
+
+// free frees the buffers of the chain.
+func (c *postChain) free() {
+	for _, fb := range []*framebuffer.FrameBuffer{c.resolved, c.ping[0], c.ping[1], c.bloom[0], c.bloom[1]} {
+		if fb != nil {
+			fb.Free()
+		}
+	}
+	c.resolved = nil
+	c.ping = [2]*framebuffer.FrameBuffer{}
+	c.bloom = [2]*framebuffer.FrameBuffer{}
+}
+
+// build prepares the passes of the given effects.
+// Effects which cannot be applied are skipped.
+func (c *postChain) build(effects []Effect) {
+	c.free()
+	c.effects = cloneEffects(effects)
+	c.passes = nil
+
+	var bloom bool
+	for _, e := range c.effects {
+		p, err := newPass(e)
+		if err != nil {
+			log.Warn("skipping post-processing effect", "effect", e.Type, "error", err)
+			continue
+		}
+		c.passes = append(c.passes, p)
+		bloom = bloom || e.Type == Bloom
+	}
+	if len(c.passes) == 0 {
+		return
+	}
+
+	c.resolved = framebuffer.New(c.width, c.height, 1, 0)
+	c.ping[0] = framebuffer.New(c.width, c.height, 1, 0)
+	c.ping[1] = framebuffer.New(c.width, c.height, 1, 0)
+	if bloom {
+		w, h := c.bloomSize()
+		c.bloom[0] = framebuffer.New(w, h, 1, 0)
+		c.bloom[1] = framebuffer.New(w, h, 1, 0)
+	}
+}
+
+// newPass loads the shader of an effect and sets its parameters.
+func newPass(e Effect) (*pass, error) {
+	if err := e.validate(); err != nil {
+		return nil, err
+	}
+	s, err := shader.TryLoad(effectInfos[e.Type].shader)
+	if err != nil {
+		return nil, err
+	}
+
+	p := &pass{
+		effect:   e,
+		shader:   s,
+		values:   e.Parameters(),
+		uniforms: make(map[int32]float32),
+	}
+	for k, v := range p.values {
+		p.uniforms[s.GetUniform(k)] = v
+	}
+
+	switch e.Type {
+	case TonemapACES, TonemapReinhard:
+		p.uOperator = s.GetUniform("operator")
+		p.operator = int32(e.Type - TonemapACES)
+	case SSAO:
+		p.uProj = s.GetUniform("proj")
+		p.uInvProj = s.GetUniform("invProj")
+	case Bloom:
+		p.uStage = s.GetUniform("stage")
+		p.uDirection = s.GetUniform("direction")
+	case ColorGrading:
+		p.lut = texture.LoadData(e.Texture)
+	}
+	return p, nil
+}
+
+// stale returns whether the passes must be rebuilt for the effects of pp,
+// or of the settings if pp is nil.
+func (c *postChain) stale(pp *PostProcess) bool {
+	return !c.built || pp != c.source || (pp != nil && pp.version != c.version)
+}
+
+// apply applies the post-processing chain of sc to the first color
+// attachment of fb. Returns the frame buffer holding the result.
+func (c *postChain) apply(sc *scene.Scene, fb *framebuffer.FrameBuffer) *framebuffer.FrameBuffer {
+	if effects, pp := effectsOf(sc); c.stale(pp) {
+		c.build(effects)
+		c.built, c.source = true, pp
+		if pp != nil {
+			c.version = pp.version
+		}
+	}
+	if len(c.passes) == 0 {
+		return fb
+	}
+
+	// Resolve multisampling. The depth is kept for SSAO.
+	fb.Blit(c.resolved, c.width, c.height, true)
+	gl.Disable(gl.DEPTH_TEST)
+
+	src := c.resolved
+	for i, p := range c.passes {
+		dst := c.ping[i%2]
+		c.resolved.BindDepthTexture(effectDepthUnit)
+		c.render(sc, p, src, dst)
+		src = dst
+	}
+
+	gl.Enable(gl.DEPTH_TEST)
+	gl.ActiveTexture(gl.TEXTURE0)
+	return src
+}
+
+// render applies a single effect to src and writes the result to dst.
+func (c *postChain) render(sc *scene.Scene, p *pass, src, dst *framebuffer.FrameBuffer) {
+	p.shader.Use()
+	for loc, v := range p.uniforms {
+		gl.Uniform1f(loc, v)
+	}
+
+	switch p.effect.Type {
+	case TonemapACES, TonemapReinhard:
+		gl.Uniform1i(p.uOperator, p.operator)
+	case SSAO:
+		cam := sc.Camera()
+		if cam == nil {
+			src.Blit(dst, c.width, c.height, false)
+			return
+		}
+		proj := cam.ProjectionMatrix
+		inv := proj.Inv()
+		gl.UniformMatrix4fv(p.uProj, 1, false, &proj[0])
+		gl.UniformMatrix4fv(p.uInvProj, 1, false, &inv[0])
+	case ColorGrading:
+		if !p.lut.Loaded() {
+			src.Blit(dst, c.width, c.height, false)
+			return
+		}
+		p.lut.Bind(effectAuxUnit)
+	case Bloom:
+		c.renderBloom(p, src, dst)
+		return
+	}
+
+	src.BindColorTexture(0, effectColorUnit)
+	c.draw(p.shader, dst, c.width, c.height)
+}
+
+// renderBloom extracts the highlights of src at half resolution, blurs
+// them and adds them to src.
+func (c *postChain) renderBloom(p *pass, src, dst *framebuffer.FrameBuffer) {
+	w, h := c.bloomSize()
+	radius := p.values["radius"]
+
+	gl.Uniform1i(p.uStage, 0)
+	src.BindColorTexture(0, effectColorUnit)
+	c.draw(p.shader, c.bloom[0], w, h)
+
+	gl.Uniform1i(p.uStage, 1)
+	gl.Uniform2f(p.uDirection, radius, 0)
+	c.bloom[0].BindColorTexture(0, effectColorUnit)
+	c.draw(p.shader, c.bloom[1], w, h)
+	gl.Uniform2f(p.uDirection, 0, radius)
+	c.bloom[1].BindColorTexture(0, effectColorUnit)
+	c.draw(p.shader, c.bloom[0], w, h)
+
+	gl.Uniform1i(p.uStage, 2)
+	src.BindColorTexture(0, effectColorUnit)
+	c.bloom[0].BindColorTexture(0, effectAuxUnit)
+	c.draw(p.shader, dst, c.width, c.height)
+}
+
+// bloomSize returns the size of the bloom buffers.
+func (c *postChain) bloomSize() (int, int) {
+	w, h := c.width/2, c.height/2
+	if w < 1 {
+		w = 1
+	}
+	if h < 1 {
+		h = 1
+	}
+	return w, h
+}
+
+// draw renders a full screen quad into dst using shader s.
+func (c *postChain) draw(s shader.Shader, dst *framebuffer.FrameBuffer, width, height int) {
+	dst.Bind()
+	gl.Viewport(0, 0, int32(width), int32(height))
+	c.mat.Shader = s
+	c.quad.Render()
+}
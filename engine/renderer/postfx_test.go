@@ -0,0 +1,85 @@
+package renderer
+
+import (
+	"encoding/json"
+	"reflect"
+	"testing"
+)
+
+func TestEffect_UnmarshalJSON(t *testing.T) {
+	var effects []Effect
+	e := json.Unmarshal([]byte(`[
+		{"Type": "TonemapReinhard"},
+		{"Type": "Bloom", "Params": {"intensity": 0.5}}
+	]`), &effects)
+	if e != nil {
+		t.Fatal(e)
+	}
+	if len(effects) != 2 || effects[0].Type != TonemapReinhard || effects[1].Type != Bloom {
+		t.Fatalf("wrong effects. got %+v", effects)
+	}
+
+	expected := map[string]float32{"threshold": 1, "intensity": 0.5, "radius": 1}
+	if p := effects[1].Parameters(); !reflect.DeepEqual(p, expected) {
+		t.Errorf("wrong parameters. got %v, expected %v", p, expected)
+	}
+
+	if e := json.Unmarshal([]byte(`[{"Type": "Sepia"}]`), &effects); e == nil {
+		t.Error("expected error for unknown effect type")
+	}
+}
+
+func TestEffect_MarshalJSON(t *testing.T) {
+	b, e := json.Marshal(Effect{Type: Vignette})
+	if e != nil {
+		t.Fatal(e)
+	}
+	if string(b) != `{"Type":"Vignette"}` {
+		t.Errorf("wrong JSON. got %s", b)
+	}
+}
+
+func TestEffect_validate(t *testing.T) {
+	tests := []struct {
+		effect Effect
+		valid  bool
+	}{
+		{Effect{Type: Gamma, Params: map[string]float32{"gamma": 2.2}}, true},
+		{Effect{Type: Gamma, Params: map[string]float32{"exposure": 1}}, false},
+		{Effect{Type: ColorGrading}, false},
+		{Effect{Type: ColorGrading, Texture: "lut.png"}, true},
+		{Effect{Type: EffectType(-1)}, false},
+	}
+	for i, test := range tests {
+		if e := test.effect.validate(); (e == nil) != test.valid {
+			t.Errorf("test %v: got error %v, expected valid %v", i, e, test.valid)
+		}
+	}
+}
+
+func TestPostChain_stale(t *testing.T) {
+	c := &postChain{}
+	if !c.stale(nil) {
+		t.Error("new chain is not stale")
+	}
+	c.built = true
+	if c.stale(nil) {
+		t.Error("chain of the settings is stale")
+	}
+
+	pp := &PostProcess{}
+	if !c.stale(pp) {
+		t.Error("chain is not stale when the component appears")
+	}
+	c.source = pp
+	if c.stale(pp) {
+		t.Error("chain is stale without changes")
+	}
+	pp.Changed()
+	if !c.stale(pp) {
+		t.Error("chain is not stale after changes")
+	}
+	if !c.stale(nil) {
+		t.Error("chain is not stale when the component disappears")
+	}
+}
@@ -2,19 +2,32 @@ package renderer
 
 import (
 	"github.com/go-gl/gl/v3.2-core/gl"
+	mgl "github.com/go-gl/mathgl/mgl32"
+	"github.com/patrick-jessen/goplay/engine/bounds"
+	"github.com/patrick-jessen/goplay/engine/framebuffer"
+	"github.com/patrick-jessen/goplay/engine/light"
 	"github.com/patrick-jessen/goplay/engine/log"
+	"github.com/patrick-jessen/goplay/engine/queue"
 	"github.com/patrick-jessen/goplay/engine/scene"
+	"github.com/patrick-jessen/goplay/engine/shader"
 	"github.com/patrick-jessen/goplay/engine/window"
 )
 
 type renderer interface {
 	initialize()
 	deinitialize()
-	render(*scene.Scene)
+	render(*scene.Scene, []shader.Light)
 }
 
 var rendererInst renderer
 
+// stats holds the culling statistics of the last frame.
+var stats scene.CullStats
+
+// target is the frame buffer which receives the final image.
+// If nil, the window's default frame buffer is used.
+var target *framebuffer.FrameBuffer
+
 var Settings = settings{
 	curType: Forward,
 	newType: Forward,
@@ -22,12 +35,23 @@ var Settings = settings{
 	newAA:   MSAAx4,
 	curSR:   1024,
 	newSR:   1024,
+	curPCF:  1,
+	newPCF:  1,
+	curBias: shadowBias{0.0005, 0.002},
+	newBias: shadowBias{0.0005, 0.002},
 }
 
 type settings struct {
 	curType, newType Type
 	curAA, newAA     Antialiasing
 	curSR, newSR     int
+	curPCF, newPCF   int
+	curBias, newBias shadowBias
+	curPost, newPost []Effect
+}
+
+type shadowBias struct {
+	constant, slope float32
 }
 
 type Type int
@@ -35,6 +59,7 @@ type Antialiasing int
 
 const (
 	Forward Type = iota
+	Deferred
 )
 const (
 	NoAA Antialiasing = iota
@@ -45,10 +70,24 @@ const (
 	MSAAx16
 )
 
+// Valid returns whether t is a known renderer type.
+func (t Type) Valid() bool {
+	return t == Forward || t == Deferred
+}
+
+// MaxShadowResolution is the largest width and height of shadow maps.
+const MaxShadowResolution = 8192
+
 func (s *settings) Type() Type {
 	return s.curType
 }
+
+// SetType sets the type of renderer. Unknown types are ignored.
 func (s *settings) SetType(t Type) {
+	if !t.Valid() {
+		log.Warn("unknown renderer type", "type", int(t))
+		return
+	}
 	s.newType = t
 }
 func (s *settings) Antialiasing() Antialiasing {
@@ -79,9 +118,55 @@ func (s *settings) SetAntialising(a Antialiasing) {
 func (s *settings) ShadowResolution() int {
 	return s.curSR
 }
+
+// SetShadowResolution sets the width and height of shadow maps.
+// The resolution is clamped to [1, MaxShadowResolution].
 func (s *settings) SetShadowResolution(r int) {
+	if r < 1 {
+		r = 1
+	}
+	if r > MaxShadowResolution {
+		r = MaxShadowResolution
+	}
 	s.newSR = r
 }
+
+// ShadowFilter returns the radius of the PCF kernel in texels.
+func (s *settings) ShadowFilter() int {
+	return s.curPCF
+}
+
+// SetShadowFilter sets the radius of the PCF kernel in texels.
+// A radius of 0 disables filtering.
+func (s *settings) SetShadowFilter(radius int) {
+	if radius < 0 {
+		radius = 0
+	}
+	s.newPCF = radius
+}
+
+// ShadowBias returns the constant and slope scaled depth bias.
+func (s *settings) ShadowBias() (constant, slope float32) {
+	return s.curBias.constant, s.curBias.slope
+}
+
+// SetShadowBias sets the constant and slope scaled depth bias, which
+// prevent surfaces from shadowing themselves.
+func (s *settings) SetShadowBias(constant, slope float32) {
+	s.newBias = shadowBias{constant, slope}
+}
+
+// PostProcessing returns the post-processing chain.
+func (s *settings) PostProcessing() []Effect {
+	return cloneEffects(s.curPost)
+}
+
+// SetPostProcessing sets the post-processing effects, which are applied
+// in order. A PostProcess component on the camera node of a scene
+// replaces the chain for that scene.
+func (s *settings) SetPostProcessing(effects []Effect) {
+	s.newPost = cloneEffects(effects)
+}
 func (s *settings) Apply() {
 	rendererInst.deinitialize()
 
@@ -89,14 +174,17 @@ func (s *settings) Apply() {
 		switch s.newType {
 		case Forward:
 			rendererInst = &forwardRenderer{}
-		default:
-			log.Panic("not implemented")
+		case Deferred:
+			rendererInst = &deferredRenderer{}
 		}
 	}
 
 	s.curType = s.newType
 	s.curAA = s.newAA
 	s.curSR = s.newSR
+	s.curPCF = s.newPCF
+	s.curBias = s.newBias
+	s.curPost = s.newPost
 
 	rendererInst.initialize()
 }
@@ -114,6 +202,44 @@ func Initialize() {
 func Deinitialize() {
 	rendererInst.deinitialize()
 }
+
+// SetTarget sets the frame buffer which receives the final image.
+// Passing nil renders to the window.
+func SetTarget(fb *framebuffer.FrameBuffer) {
+	target = fb
+}
+
+// Stats returns the number of bounded components drawn and culled from
+// the camera's view in the last frame. Shadow maps are not counted.
+func Stats() scene.CullStats {
+	return stats
+}
+
+// renderVisible renders the parts of sc which may be visible from its
+// camera. Without a camera, everything is rendered.
+func renderVisible(sc *scene.Scene) scene.CullStats {
+	cam := sc.Camera()
+	if cam == nil {
+		queue.Begin(mgl.Vec3{})
+		sc.Render()
+		queue.End()
+		return scene.CullStats{}
+	}
+	eye := cam.ViewMatrix().Inv().Col(3).Vec3()
+	return renderFrustum(sc, cam.ViewProjectionMatrix(), eye)
+}
+
+// renderFrustum renders the parts of sc inside the frustum of viewProj
+// through the render queue. Transparent draws are ordered by their
+// distance from eye.
+func renderFrustum(sc *scene.Scene, viewProj mgl.Mat4, eye mgl.Vec3) scene.CullStats {
+	f := bounds.FrustumFromMatrix(viewProj)
+	queue.Begin(eye)
+	stats := sc.RenderFrustum(&f)
+	queue.End()
+	return stats
+}
 func Render() {
-	rendererInst.render(scene.Current())
+	s := scene.Current()
+	rendererInst.render(s, light.Gather(s.Root))
 }
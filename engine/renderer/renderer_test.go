@@ -0,0 +1,15 @@
+package renderer
+
+import "testing"
+
+func TestSettings_SetType(t *testing.T) {
+	s := settings{newType: Forward}
+	s.SetType(Deferred)
+	if s.newType != Deferred {
+		t.Errorf("wrong type. got %v, expected %v", s.newType, Deferred)
+	}
+	s.SetType(Type(5))
+	if s.newType != Deferred {
+		t.Errorf("unknown type was not ignored. got %v", s.newType)
+	}
+}
@@ -0,0 +1,274 @@
+package renderer
+
+import (
+	"math"
+
+	"github.com/go-gl/gl/v3.2-core/gl"
+	mgl "github.com/go-gl/mathgl/mgl32"
+
+	"github.com/patrick-jessen/goplay/engine/framebuffer"
+	"github.com/patrick-jessen/goplay/engine/material"
+	"github.com/patrick-jessen/goplay/engine/scene"
+	"github.com/patrick-jessen/goplay/engine/shader"
+)
+
+const (
+	// shadowDistance is the distance from the camera covered by cascades.
+	shadowDistance = 100
+	// cascadeLambda blends between logarithmic (1) and uniform (0) splits.
+	cascadeLambda = 0.75
+	// shadowRange is the far plane of lights without a range.
+	shadowRange = 100
+)
+
+// shadowMaps holds the shadow maps of a renderer.
+type shadowMaps struct {
+	size     int
+	shader   shader.Shader
+	cascades [shader.NumCascades]*framebuffer.FrameBuffer
+	spots    [shader.MaxSpotShadows]*framebuffer.FrameBuffer
+	points   [shader.MaxPointShadows]*framebuffer.FrameBuffer
+}
+
+// newShadowMaps creates shadow maps of the given resolution. Each map is
+// allocated when a light first needs it.
+func newShadowMaps(size int) *shadowMaps {
+	return &shadowMaps{
+		size:   size,
+		shader: shader.Load("shadow"),
+	}
+}
+
+// depthMap returns the shadow map at index i of maps, allocating it with
+// alloc if needed.
+func (s *shadowMaps) depthMap(maps []*framebuffer.FrameBuffer, i int, alloc func(int) *framebuffer.FrameBuffer) *framebuffer.FrameBuffer {
+	if maps[i] == nil {
+		maps[i] = alloc(s.size)
+	}
+	return maps[i]
+}
+
+// free frees the shadow maps which have been allocated.
+func (s *shadowMaps) free() {
+	for _, maps := range [][]*framebuffer.FrameBuffer{s.cascades[:], s.spots[:], s.points[:]} {
+		for _, fb := range maps {
+			if fb != nil {
+				fb.Free()
+			}
+		}
+	}
+}
+
+// render assigns shadow maps to the lights casting shadows and renders
+// the scene into them. Lights which do not get a shadow map have their
+// Shadow set to -1. Leaves the viewport at the size of the shadow maps.
+func (s *shadowMaps) render(sc *scene.Scene, lights []shader.Light) {
+	data := shader.Shadows{
+		PCF:       int32(Settings.ShadowFilter()),
+		TexelSize: 1 / float32(s.size),
+	}
+	data.Bias, data.SlopeBias = Settings.ShadowBias()
+
+	shader.SetOverride(&s.shader)
+	material.SetPass(material.PassOpaque)
+	gl.Viewport(0, 0, int32(s.size), int32(s.size))
+	gl.Disable(gl.CULL_FACE)
+
+	cam := sc.Camera()
+	var numCascaded, numSpots, numPoints int
+	for i := range lights {
+		l := &lights[i]
+		if l.Shadow < 0 {
+			continue
+		}
+		l.Shadow = -1
+
+		switch l.Type {
+		case shader.LightDirectional:
+			// Only one directional light can have cascades
+			if numCascaded > 0 || cam == nil {
+				continue
+			}
+			for c, m := range cascadeMatrices(cam, l.Direction, s.size) {
+				data.CascadeMats[c] = m
+				s.draw(sc, s.depthMap(s.cascades[:], c, framebuffer.NewDepth), m, l.Position)
+			}
+			l.Shadow = 0
+			numCascaded++
+
+		case shader.LightSpot:
+			if numSpots == len(s.spots) {
+				continue
+			}
+			m := spotMatrix(l)
+			data.SpotMats[numSpots] = m
+			s.draw(sc, s.depthMap(s.spots[:], numSpots, framebuffer.NewDepth), m, l.Position)
+			l.Shadow = int32(numSpots)
+			numSpots++
+
+		case shader.LightPoint:
+			if numPoints == len(s.points) {
+				continue
+			}
+			far := lightRange(l)
+			data.PointFar[numPoints] = far
+			fb := s.depthMap(s.points[:], numPoints, framebuffer.NewDepthCube)
+			for f, m := range cubeMatrices(l.Position, far) {
+				fb.BindFace(f)
+				gl.Clear(gl.DEPTH_BUFFER_BIT)
+				shader.SetViewProjectionMatrix(m)
+				renderFrustum(sc, m, l.Position)
+			}
+			l.Shadow = int32(numPoints)
+			numPoints++
+		}
+	}
+
+	shader.SetOverride(nil)
+	material.SetPass(material.PassAll)
+	gl.Enable(gl.CULL_FACE)
+	if cam != nil {
+		shader.SetViewProjectionMatrix(cam.ViewProjectionMatrix())
+	}
+	shader.SetShadows(&data)
+}
+
+// draw renders the depth of the scene into fb as seen from eye.
+// Casters outside the light's view are culled.
+func (s *shadowMaps) draw(sc *scene.Scene, fb *framebuffer.FrameBuffer, viewProj mgl.Mat4, eye mgl.Vec3) {
+	fb.Bind()
+	gl.Clear(gl.DEPTH_BUFFER_BIT)
+	shader.SetViewProjectionMatrix(viewProj)
+	renderFrustum(sc, viewProj, eye)
+}
+
+// bind binds the shadow maps to their texture units. Units of maps which
+// have not been allocated are left without a texture.
+func (s *shadowMaps) bind() {
+	bind := func(maps []*framebuffer.FrameBuffer, unit int, target uint32) {
+		for i, fb := range maps {
+			if fb != nil {
+				fb.BindDepthTexture(unit + i)
+				continue
+			}
+			gl.ActiveTexture(gl.TEXTURE0 + uint32(unit+i))
+			gl.BindTexture(target, 0)
+		}
+	}
+	bind(s.cascades[:], shader.CascadeUnit, gl.TEXTURE_2D)
+	bind(s.spots[:], shader.SpotShadowUnit, gl.TEXTURE_2D)
+	bind(s.points[:], shader.PointShadowUnit, gl.TEXTURE_CUBE_MAP)
+
+	// Prevent textures uploaded later from replacing a shadow map
+	gl.ActiveTexture(gl.TEXTURE0)
+}
+
+// cascadeSplits returns the view distances at which each cascade ends.
+func cascadeSplits(near, far float32) (out [shader.NumCascades]float32) {
+	for i := range out {
+		f := float64(i+1) / float64(len(out))
+		logSplit := float64(near) * math.Pow(float64(far/near), f)
+		uniformSplit := float64(near) + float64(far-near)*f
+		out[i] = float32(cascadeLambda*logSplit + (1-cascadeLambda)*uniformSplit)
+	}
+	return out
+}
+
+// cascadeMatrices returns the shadow matrices of a directional light.
+// Each cascade covers a slice of the camera frustum.
+func cascadeMatrices(cam *scene.Camera, dir mgl.Vec3, size int) (out [shader.NumCascades]mgl.Mat4) {
+	near, far := cam.ClipPlanes()
+	if far > shadowDistance {
+		far = shadowDistance
+	}
+	// The projection matrix holds the inverse tangents of the half FOVs
+	tanX := 1 / cam.ProjectionMatrix[0]
+	tanY := 1 / cam.ProjectionMatrix[5]
+	invView := cam.ViewMatrix().Inv()
+
+	start := near
+	for i, end := range cascadeSplits(near, far) {
+		// Bounding sphere of the frustum slice
+		var corners []mgl.Vec3
+		for _, d := range []float32{start, end} {
+			for _, c := range [][2]float32{{-1, -1}, {1, -1}, {-1, 1}, {1, 1}} {
+				p := mgl.Vec3{c[0] * tanX * d, c[1] * tanY * d, -d}
+				corners = append(corners, mgl.TransformCoordinate(p, invView))
+			}
+		}
+		var center mgl.Vec3
+		for _, c := range corners {
+			center = center.Add(c.Mul(1 / float32(len(corners))))
+		}
+		var radius float32
+		for _, c := range corners {
+			if d := c.Sub(center).Len(); d > radius {
+				radius = d
+			}
+		}
+		// Keep the size constant as the camera rotates to reduce shimmering
+		radius = float32(math.Ceil(float64(radius)*16) / 16)
+
+		// Include casters up to shadowDistance in front of the slice
+		eye := center.Sub(dir.Mul(radius + shadowDistance))
+		view := mgl.LookAtV(eye, center, upVector(dir))
+		proj := mgl.Ortho(-radius, radius, -radius, radius, 0, 2*radius+shadowDistance)
+		out[i] = snapToTexel(proj.Mul4(view), size)
+		start = end
+	}
+	return out
+}
+
+// snapToTexel offsets a shadow matrix such that the world origin lies
+// on a texel. This prevents shadow edges from shimmering as the camera
+// moves.
+func snapToTexel(m mgl.Mat4, size int) mgl.Mat4 {
+	half := float32(size) / 2
+	origin := m.Mul4x1(mgl.Vec4{0, 0, 0, 1}).Vec2().Mul(half)
+	dx := (float32(math.Round(float64(origin[0]))) - origin[0]) / half
+	dy := (float32(math.Round(float64(origin[1]))) - origin[1]) / half
+	return mgl.Translate3D(dx, dy, 0).Mul4(m)
+}
+
+// spotMatrix returns the shadow matrix of a spot light.
+func spotMatrix(l *shader.Light) mgl.Mat4 {
+	angle := 2 * float32(math.Acos(float64(l.OuterCone)))
+	angle = mgl.Clamp(angle, mgl.DegToRad(1), mgl.DegToRad(170))
+	proj := mgl.Perspective(angle, 1, shader.PointShadowNear, lightRange(l))
+	view := mgl.LookAtV(l.Position, l.Position.Add(l.Direction), upVector(l.Direction))
+	return proj.Mul4(view)
+}
+
+// cubeMatrices returns the shadow matrices of each face of a point
+// light's cube map, in the order of the cube map faces.
+func cubeMatrices(pos mgl.Vec3, far float32) (out [6]mgl.Mat4) {
+	faces := [6][2]mgl.Vec3{
+		{{1, 0, 0}, {0, -1, 0}},
+		{{-1, 0, 0}, {0, -1, 0}},
+		{{0, 1, 0}, {0, 0, 1}},
+		{{0, -1, 0}, {0, 0, -1}},
+		{{0, 0, 1}, {0, -1, 0}},
+		{{0, 0, -1}, {0, -1, 0}},
+	}
+	proj := mgl.Perspective(mgl.DegToRad(90), 1, shader.PointShadowNear, far)
+	for i, f := range faces {
+		out[i] = proj.Mul4(mgl.LookAtV(pos, pos.Add(f[0]), f[1]))
+	}
+	return out
+}
+
+// lightRange returns the far plane of a light's shadow map.
+func lightRange(l *shader.Light) float32 {
+	if l.Range > 0 {
+		return l.Range
+	}
+	return shadowRange
+}
+
+// upVector returns an up vector which is not parallel to dir.
+func upVector(dir mgl.Vec3) mgl.Vec3 {
+	if math.Abs(float64(dir.Y())) > 0.99 {
+		return mgl.Vec3{0, 0, 1}
+	}
+	return mgl.Vec3{0, 1, 0}
+}
@@ -0,0 +1,66 @@
+package renderer
+
+import (
+	"testing"
+
+	mgl "github.com/go-gl/mathgl/mgl32"
+
+	"github.com/patrick-jessen/goplay/engine/shader"
+)
+
+func TestCascadeSplits(t *testing.T) {
+	splits := cascadeSplits(0.1, 100)
+	prev := float32(0.1)
+	for i, s := range splits {
+		if s <= prev {
+			t.Errorf("split %v: not increasing. got %v after %v", i, s, prev)
+		}
+		prev = s
+	}
+	if !mgl.FloatEqual(prev, 100) {
+		t.Errorf("last split should be at far plane. got %v", prev)
+	}
+}
+
+func TestSpotMatrix(t *testing.T) {
+	l := shader.Light{
+		Type:      shader.LightSpot,
+		Position:  mgl.Vec3{1, 2, 3},
+		Direction: mgl.Vec3{0, -1, 0},
+		OuterCone: 0.5,
+		Range:     10,
+	}
+	m := spotMatrix(&l)
+
+	// A point along the direction is projected onto the center of the map
+	p := mgl.TransformCoordinate(mgl.Vec3{1, -3, 3}, m)
+	if !mgl.FloatEqualThreshold(p.X(), 0, 1e-4) || !mgl.FloatEqualThreshold(p.Y(), 0, 1e-4) || p.Z() < -1 || p.Z() > 1 {
+		t.Errorf("wrong projection. got %v", p)
+	}
+}
+
+func TestSnapToTexel(t *testing.T) {
+	m := snapToTexel(mgl.Translate3D(0.123, -0.456, 0), 1024)
+	origin := m.Mul4x1(mgl.Vec4{0, 0, 0, 1}).Vec2().Mul(512)
+	for _, v := range origin {
+		if d := v - float32(int(v)); d > 1e-3 && d < 1-1e-3 {
+			t.Errorf("origin not on a texel. got %v", origin)
+		}
+	}
+}
+
+func TestSettings_SetShadowResolution(t *testing.T) {
+	tests := map[int]int{
+		-1:                      1,
+		0:                       1,
+		2048:                    2048,
+		MaxShadowResolution + 1: MaxShadowResolution,
+	}
+	for r, expected := range tests {
+		var s settings
+		s.SetShadowResolution(r)
+		if s.newSR != expected {
+			t.Errorf("wrong resolution for %v. got %v, expected %v", r, s.newSR, expected)
+		}
+	}
+}
@@ -5,10 +5,31 @@ import (
 	"github.com/patrick-jessen/goplay/engine/scene"
 )
 
+// LoadScene loads a scene and mounts its models asynchronously.
+// Panics if the scene cannot be loaded.
 func LoadScene(name string) *scene.Scene {
-	s := scene.Load(name)
-	for k, v := range scene.MountMap {
-		model.Load(v).Mount(k)
+	s, e := TryLoadScene(name)
+	if e != nil {
+		panic(e)
 	}
 	return s
 }
+
+// TryLoadScene loads a scene and mounts its models asynchronously, see
+// model.MountAsync. Models which fail to load are logged.
+// Errors are of type *asset.Error.
+func TryLoadScene(name string) (*scene.Scene, error) {
+	s, e := scene.TryLoad(name)
+
+	// Only mount nodes from this scene
+	mounts := scene.MountMap
+	scene.MountMap = make(map[*scene.Node]string)
+
+	if e != nil {
+		return nil, e
+	}
+	for k, v := range mounts {
+		model.MountAsync(k, v, nil)
+	}
+	return s, nil
+}
@@ -9,9 +9,15 @@ func init() {
 	RegisterComponent(&Camera{})
 }
 
+// Clip planes of the camera.
+const (
+	nearPlane = 0.01
+	farPlane  = 1000.0
+)
+
 type Camera struct {
 	FOV              float32
-	ProjectionMatrix mgl.Mat4
+	ProjectionMatrix mgl.Mat4 `json:"-"`
 	node             *Node
 }
 
@@ -32,19 +38,34 @@ func (c *Camera) Initialize(n *Node) {
 	c.ProjectionMatrix = mgl.Perspective(
 		mgl.DegToRad(c.FOV),
 		float32(w)/float32(h),
-		0.01, 1000.0)
+		nearPlane, farPlane)
 
 	window.AddResizeHandler(func(w, h int) {
 		c.ProjectionMatrix = mgl.Perspective(
 			mgl.DegToRad(c.FOV),
 			float32(w)/float32(h),
-			0.01, 1000.0)
+			nearPlane, farPlane)
 	})
 }
 
-func (c *Camera) Render() {}
-func (c *Camera) Update() {}
+func (c *Camera) Render()        {}
+func (c *Camera) Update(float32) {}
+
+// Node returns the node the camera is attached to.
+func (c *Camera) Node() *Node {
+	return c.node
+}
+
+// ClipPlanes returns the distances to the near and far clip planes.
+func (c *Camera) ClipPlanes() (near, far float32) {
+	return nearPlane, farPlane
+}
+
+// ViewMatrix returns the view matrix of the camera.
+func (c *Camera) ViewMatrix() mgl.Mat4 {
+	return c.node.WorldTransform()
+}
 
 func (c *Camera) ViewProjectionMatrix() mgl.Mat4 {
-	return c.ProjectionMatrix.Mul4(c.node.WorldTransform())
+	return c.ProjectionMatrix.Mul4(c.ViewMatrix())
 }
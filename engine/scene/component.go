@@ -1,6 +1,10 @@
 package scene
 
-import "reflect"
+import (
+	"reflect"
+
+	"github.com/patrick-jessen/goplay/engine/bounds"
+)
 
 var componentMap = make(map[string]reflect.Type)
 
@@ -13,6 +17,34 @@ func RegisterComponent(c Component) {
 // Component is a behavior which can be attached to nodes.
 type Component interface {
 	Initialize(*Node)
-	Update()
+	// Update is called once every frame with the game time in seconds
+	// since the last frame.
+	Update(dt float32)
 	Render()
 }
+
+// FixedUpdater is implemented by components which simulate state, such as
+// physics. FixedUpdate is called in steps of fixed game time, which may
+// happen zero or several times per frame, before Update.
+type FixedUpdater interface {
+	Component
+	FixedUpdate(dt float32)
+}
+
+// Destroyer is implemented by components which hold resources, such as
+// GPU buffers, which must be freed when the component is removed from
+// its node or the node is destroyed.
+type Destroyer interface {
+	Component
+	// OnDestroy is called once, after which the component is not used.
+	OnDestroy()
+}
+
+// Bounded is implemented by components which occupy space, such as
+// meshes. When rendering with a frustum, bounded components outside of
+// it are skipped.
+type Bounded interface {
+	Component
+	// Bounds returns the bounds of the component in world space.
+	Bounds() bounds.AABB
+}
@@ -1,10 +1,16 @@
 package scene
 
 import (
+	"bytes"
 	"encoding/json"
+	"errors"
+	"fmt"
 	"reflect"
+	"sort"
 
 	mgl "github.com/go-gl/mathgl/mgl32"
+	"github.com/patrick-jessen/goplay/engine/asset"
+	"github.com/patrick-jessen/goplay/engine/bounds"
 )
 
 var MountMap = make(map[*Node]string)
@@ -14,11 +20,22 @@ type Node struct {
 	Transform
 	children   map[string]*Node
 	components map[string]Component
+	childOrder []*Node        // Children in the order they are updated.
+	compOrder  []Component    // Components in the order they are updated.
+	compAdded  []Component    // Components in the order they were added.
+	priorities map[string]int // Non-zero execution priorities of components.
+	removals   int            // Number of components removed.
 
 	parent         *Node
 	scene          *Scene
 	name           string
 	worldTransform mgl.Mat4
+	worldBounds    bounds.AABB // Bounds of the node and its descendants.
+	tags           []string
+	layer          uint
+
+	mount   string // The name of the model mounted onto the node.
+	mounted bool   // Whether the node was created by mounting a model.
 }
 
 // newNode creates a new node.
@@ -27,7 +44,9 @@ func newNode() *Node {
 		Transform:      newTransform(),
 		children:       make(map[string]*Node),
 		components:     make(map[string]Component),
+		priorities:     make(map[string]int),
 		worldTransform: mgl.Ident4(),
+		worldBounds:    bounds.Infinite(), // Not culled until updated
 		name:           "root",
 	}
 }
@@ -38,10 +57,10 @@ func (n *Node) initialize(scene *Scene, parent *Node, name string) {
 	n.parent = parent
 	n.name = name
 
-	for k, v := range n.children {
-		v.initialize(scene, n, k)
+	for _, v := range n.childOrder {
+		v.initialize(scene, n, v.name)
 	}
-	for _, v := range n.components {
+	for _, v := range n.compOrder {
 		v.Initialize(n)
 	}
 }
@@ -55,20 +74,196 @@ func (n *Node) NewChild(name string) *Node {
 
 	child := newNode()
 	child.initialize(n.scene, n, name)
-	n.children[name] = child
+	n.addChild(name, child)
+	n.invalidateBounds()
+	return child
+}
+
+// MountChild creates a new child which is part of a mounted model.
+// Such children are not serialized, as they are recreated when the
+// model is mounted.
+func (n *Node) MountChild(name string) *Node {
+	child := n.NewChild(name)
+	child.mounted = true
 	return child
 }
 
 // AddComponent adds a component.
 // A node can only have one instance of the same component.
 func (n *Node) AddComponent(c Component) {
-	compName := reflect.TypeOf(c).Elem().Name()
+	compName := componentName(c)
 	if _, ok := n.components[compName]; ok {
 		panic("component already exists: " + compName)
 	}
 
 	c.Initialize(n)
-	n.components[compName] = c
+	n.addComponent(compName, c)
+	n.invalidateBounds()
+}
+
+// addChild appends a child, without initializing it.
+func (n *Node) addChild(name string, child *Node) {
+	child.name = name
+	n.children[name] = child
+	n.childOrder = append(n.childOrder, child)
+}
+
+// addComponent appends a component, without initializing it.
+func (n *Node) addComponent(name string, c Component) {
+	n.components[name] = c
+	n.compAdded = append(n.compAdded, c)
+	n.sortComponents()
+}
+
+// RemoveComponent removes the component with the given type.
+// The component is notified if it implements Destroyer.
+func (n *Node) RemoveComponent(name string) {
+	c, ok := n.components[name]
+	if !ok {
+		panic("component does not exist: " + name)
+	}
+
+	delete(n.components, name)
+	delete(n.priorities, name)
+	n.removals++
+	for i, v := range n.compAdded {
+		if v == c {
+			n.compAdded = append(n.compAdded[:i], n.compAdded[i+1:]...)
+			break
+		}
+	}
+	n.sortComponents()
+	n.invalidateBounds()
+
+	if d, ok := c.(Destroyer); ok {
+		d.OnDestroy()
+	}
+}
+
+// RemoveChild detaches the child with the given name, such that it is
+// no longer updated or rendered. The child is returned, and may be
+// attached elsewhere using SetParent.
+// Returns nil if child does not exist.
+func (n *Node) RemoveChild(name string) *Node {
+	child, ok := n.children[name]
+	if !ok {
+		return nil
+	}
+	n.detach(child)
+	child.setScene(nil)
+	return child
+}
+
+// detach removes a child from the node.
+// The order of children is replaced rather than modified, since it may
+// be traversed.
+func (n *Node) detach(child *Node) {
+	delete(n.children, child.name)
+	order := make([]*Node, 0, len(n.childOrder))
+	for _, c := range n.childOrder {
+		if c != child {
+			order = append(order, c)
+		}
+	}
+	n.childOrder = order
+	child.parent = nil
+	n.invalidateBounds()
+}
+
+// setScene sets the scene of the node and its descendants.
+func (n *Node) setScene(s *Scene) {
+	n.scene = s
+	for _, c := range n.childOrder {
+		c.setScene(s)
+	}
+}
+
+// SetParent moves the node to become a child of parent, keeping its name.
+// If keepWorld is true, the local transformation is changed such that the
+// world transformation is preserved. Otherwise the local transformation
+// is preserved. Panics if parent has a child of the same name, or if
+// parent is the node itself or one of its descendants.
+func (n *Node) SetParent(parent *Node, keepWorld bool) {
+	if parent == n.parent {
+		return
+	}
+	for p := parent; p != nil; p = p.parent {
+		if p == n {
+			panic("node cannot be moved below itself: " + n.name)
+		}
+	}
+	if _, ok := parent.children[n.name]; ok {
+		panic("child already exists: " + n.name)
+	}
+
+	if n.parent != nil {
+		n.parent.detach(n)
+	}
+	parent.addChild(n.name, n)
+	n.parent = parent
+	n.setScene(parent.scene)
+
+	if keepWorld {
+		n.SetMatrix(parent.worldTransform.Inv().Mul4(n.worldTransform))
+	} else {
+		n.worldTransform = parent.worldTransform.Mul4(n.Transform.mat)
+	}
+	parent.invalidateBounds()
+}
+
+// Rename changes the name of the node.
+// Panics if a sibling already has the name.
+func (n *Node) Rename(name string) {
+	if name == n.name {
+		return
+	}
+	if n.parent != nil {
+		if _, ok := n.parent.children[name]; ok {
+			panic("child already exists: " + name)
+		}
+		delete(n.parent.children, n.name)
+		n.parent.children[name] = n
+	}
+	n.name = name
+}
+
+// Destroy removes the node from its parent and destroys it along with
+// its descendants. Components implementing Destroyer are notified, such
+// that they can free their resources. The node must not be used after
+// it is destroyed.
+func (n *Node) Destroy() {
+	if n.parent != nil {
+		n.parent.detach(n)
+	}
+	n.destroy()
+}
+
+// destroy destroys the node and its descendants. Descendants are destroyed
+// first, and components in reverse order of execution.
+func (n *Node) destroy() {
+	for _, c := range n.childOrder {
+		c.destroy()
+		c.parent = nil
+	}
+	for i := len(n.compOrder) - 1; i >= 0; i-- {
+		if d, ok := n.compOrder[i].(Destroyer); ok {
+			d.OnDestroy()
+		}
+	}
+
+	n.children = make(map[string]*Node)
+	n.components = make(map[string]Component)
+	n.childOrder = nil
+	n.compOrder = nil
+	n.compAdded = nil
+	n.removals++
+	n.scene = nil
+	delete(MountMap, n)
+}
+
+// componentName returns the name of a component's type.
+func componentName(c Component) string {
+	return reflect.TypeOf(c).Elem().Name()
 }
 
 // Child returns the child with the given name.
@@ -77,43 +272,265 @@ func (n *Node) Child(name string) *Node {
 	return n.children[name]
 }
 
+// Children returns the children of the node in the order they are
+// updated. Children are in the order they were added, unless moved by
+// SetSiblingIndex.
+func (n *Node) Children() []*Node {
+	out := make([]*Node, len(n.childOrder))
+	copy(out, n.childOrder)
+	return out
+}
+
+// SiblingIndex returns the index of the node among its siblings.
+func (n *Node) SiblingIndex() int {
+	if n.parent == nil {
+		return 0
+	}
+	for i, c := range n.parent.childOrder {
+		if c == n {
+			return i
+		}
+	}
+	return -1
+}
+
+// SetSiblingIndex moves the node to the given index among its siblings.
+// The index is clamped to the number of siblings.
+func (n *Node) SetSiblingIndex(idx int) {
+	if n.parent == nil {
+		return
+	}
+	// The order is copied, since it may be traversed
+	siblings := append([]*Node(nil), n.parent.childOrder...)
+	cur := n.SiblingIndex()
+	if idx < 0 {
+		idx = 0
+	} else if idx >= len(siblings) {
+		idx = len(siblings) - 1
+	}
+
+	if idx < cur {
+		copy(siblings[idx+1:cur+1], siblings[idx:cur])
+	} else {
+		copy(siblings[cur:idx], siblings[cur+1:idx+1])
+	}
+	siblings[idx] = n
+	n.parent.childOrder = siblings
+}
+
+// Name returns the name of the node.
+func (n *Node) Name() string {
+	return n.name
+}
+
 // Component returns the component with the given type.
 // Returns nil if component does not exist.
 func (n *Node) Component(name string) Component {
 	return n.components[name]
 }
 
+// Components returns the components of the node in the order they are
+// updated. Components are ordered by priority, and then by the order in
+// which they were added.
+func (n *Node) Components() []Component {
+	out := make([]Component, len(n.compOrder))
+	copy(out, n.compOrder)
+	return out
+}
+
+// ComponentPriority returns the execution priority of the component
+// with the given type.
+func (n *Node) ComponentPriority(name string) int {
+	return n.priorities[name]
+}
+
+// SetComponentPriority sets the execution priority of the component with
+// the given type. Components of lower priority are updated and rendered
+// first. The default priority is 0.
+func (n *Node) SetComponentPriority(name string, priority int) {
+	if _, ok := n.components[name]; !ok {
+		panic("component does not exist: " + name)
+	}
+
+	if priority == 0 {
+		delete(n.priorities, name)
+	} else {
+		n.priorities[name] = priority
+	}
+	n.sortComponents()
+}
+
+// sortComponents orders the components by priority. Components of equal
+// priority are ordered by when they were added. The order is replaced
+// rather than modified, since it may be traversed.
+func (n *Node) sortComponents() {
+	n.compOrder = append([]Component(nil), n.compAdded...)
+	sort.SliceStable(n.compOrder, func(i, j int) bool {
+		return n.priorities[componentName(n.compOrder[i])] < n.priorities[componentName(n.compOrder[j])]
+	})
+}
+
+// Tags returns the tags of the node.
+func (n *Node) Tags() []string {
+	out := make([]string, len(n.tags))
+	copy(out, n.tags)
+	return out
+}
+
+// HasTag returns whether the node has the given tag.
+func (n *Node) HasTag(tag string) bool {
+	for _, t := range n.tags {
+		if t == tag {
+			return true
+		}
+	}
+	return false
+}
+
+// AddTag adds a tag to the node, unless it already has it.
+func (n *Node) AddTag(tag string) {
+	if !n.HasTag(tag) {
+		n.tags = append(n.tags, tag)
+	}
+}
+
+// RemoveTag removes a tag from the node.
+func (n *Node) RemoveTag(tag string) {
+	for i, t := range n.tags {
+		if t == tag {
+			n.tags = append(n.tags[:i], n.tags[i+1:]...)
+			return
+		}
+	}
+}
+
+// Layer returns the layer of the node. Nodes are in layer 0 by default.
+func (n *Node) Layer() uint {
+	return n.layer
+}
+
+// SetLayer sets the layer of the node.
+// Panics if the layer is not below NumLayers.
+func (n *Node) SetLayer(layer uint) {
+	if layer >= NumLayers {
+		panic(fmt.Sprintf("layer out of range: %v", layer))
+	}
+	n.layer = layer
+}
+
+// Mount returns the name of the model mounted onto the node.
+// Returns an empty string if no model is mounted.
+func (n *Node) Mount() string {
+	return n.mount
+}
+
 // WorldTransform return the node's global transformation.
 func (n *Node) WorldTransform() mgl.Mat4 {
 	return n.worldTransform
 }
 
+// Bounds returns the world space bounds of the node's components and
+// its descendants, as of the last update.
+func (n *Node) Bounds() bounds.AABB {
+	return n.worldBounds
+}
+
+// invalidateBounds prevents the node and its ancestors from being culled
+// until their bounds are updated.
+func (n *Node) invalidateBounds() {
+	for p := n; p != nil; p = p.parent {
+		p.worldBounds = bounds.Infinite()
+	}
+}
+
 // Parent returns the node's parent.
 func (n *Node) Parent() *Node {
 	return n.parent
 }
 
+// eachComponent calls f for each component in the order they are updated.
+// Components may be added and removed by f. Components added are called
+// in the next traversal, and components removed are skipped.
+func (n *Node) eachComponent(f func(Component)) {
+	order, removals := n.compOrder, n.removals
+	for _, c := range order {
+		if n.removals != removals && n.components[componentName(c)] != c {
+			continue
+		}
+		f(c)
+	}
+}
+
+// eachChild calls f for each child in the order they are updated.
+// Children may be added and removed by f. Children added are called in
+// the next traversal, and children removed are skipped.
+func (n *Node) eachChild(f func(*Node)) {
+	for _, c := range n.childOrder {
+		if c.parent == n {
+			f(c)
+		}
+	}
+}
+
+// fixedUpdate is called once every fixed step.
+func (n *Node) fixedUpdate(dt float32) {
+	n.eachComponent(func(c Component) {
+		if f, ok := c.(FixedUpdater); ok {
+			f.FixedUpdate(dt)
+		}
+	})
+	n.eachChild(func(c *Node) {
+		c.fixedUpdate(dt)
+	})
+}
+
 // update is called once every game loop.
-func (n *Node) update() {
+func (n *Node) update(dt float32) {
 	if n.parent != nil {
 		n.worldTransform = n.parent.worldTransform.Mul4(n.Transform.mat)
 	}
 
-	for _, c := range n.components {
-		c.Update()
-	}
-	for _, c := range n.children {
-		c.update()
-	}
+	n.worldBounds = bounds.Empty()
+	n.eachComponent(func(c Component) {
+		c.Update(dt)
+		if b, ok := c.(Bounded); ok {
+			n.worldBounds = n.worldBounds.Union(b.Bounds())
+		}
+	})
+	n.eachChild(func(c *Node) {
+		c.update(dt)
+		n.worldBounds = n.worldBounds.Union(c.worldBounds)
+	})
 }
 
 func (n *Node) render() {
-	for _, c := range n.components {
+	n.eachComponent(func(c Component) {
 		c.Render()
-	}
-	for _, c := range n.children {
+	})
+	n.eachChild(func(c *Node) {
 		c.render()
-	}
+	})
+}
+
+// renderFrustum renders the components which may be inside f.
+// If visible is false, the node is known to be outside f.
+func (n *Node) renderFrustum(f *bounds.Frustum, visible bool, stats *CullStats) {
+	// Descendants of nodes outside the frustum need no testing
+	visible = visible && f.Intersects(n.worldBounds)
+
+	n.eachComponent(func(c Component) {
+		if b, ok := c.(Bounded); ok {
+			if !visible || !f.Intersects(b.Bounds()) {
+				stats.Culled++
+				return
+			}
+			stats.Drawn++
+		}
+		c.Render()
+	})
+	n.eachChild(func(c *Node) {
+		c.renderFrustum(f, visible, stats)
+	})
 }
 
 // UnmarshalJSON decodes a node from JSON.
@@ -134,13 +551,38 @@ func (n *Node) UnmarshalJSON(data []byte) error {
 			return e
 		}
 
-		for k, v := range childMap {
+		for _, k := range objectKeys(*c) {
 			child := newNode()
-			e = json.Unmarshal(*v, child)
+			e = json.Unmarshal(*childMap[k], child)
 			if e != nil {
-				return e
+				return fieldError("children."+k, e)
 			}
-			n.children[k] = child
+			n.addChild(k, child)
+		}
+	}
+	if t, ok := objMap["tags"]; ok {
+		var tags []string
+		e = json.Unmarshal(*t, &tags)
+		if e != nil {
+			return fieldError("tags", e)
+		}
+		for _, tag := range tags {
+			n.AddTag(tag)
+		}
+	}
+	if l, ok := objMap["layer"]; ok {
+		e = json.Unmarshal(*l, &n.layer)
+		if e != nil {
+			return fieldError("layer", e)
+		}
+		if n.layer >= NumLayers {
+			return &asset.Error{Field: "layer", Err: fmt.Errorf("must be below %v", NumLayers)}
+		}
+	}
+	if p, ok := objMap["priorities"]; ok {
+		e = json.Unmarshal(*p, &n.priorities)
+		if e != nil {
+			return fieldError("priorities", e)
 		}
 	}
 	if c, ok := objMap["components"]; ok {
@@ -150,43 +592,130 @@ func (n *Node) UnmarshalJSON(data []byte) error {
 			return e
 		}
 
-		for k, v := range compMap {
+		for _, k := range objectKeys(*c) {
 			typ, ok := componentMap[k]
 			if !ok {
-				panic("invalid component type: " + k)
+				return &asset.Error{Field: "components." + k, Err: errors.New("unknown component type")}
 			}
 			comp := reflect.New(typ)
-			e = json.Unmarshal(*v, comp.Interface())
+			e = json.Unmarshal(*compMap[k], comp.Interface())
 			if e != nil {
-				return e
+				return fieldError("components."+k, e)
 			}
-			n.components[k] = comp.Interface().(Component)
+			n.addComponent(k, comp.Interface().(Component))
 		}
 	}
 	if m, ok := objMap["mount"]; ok {
 		var str string
 		json.Unmarshal(*m, &str)
+		n.mount = str
 		MountMap[n] = str
 	}
 
 	return nil
 }
 
+// objectKeys returns the keys of a JSON object in the order they appear,
+// without duplicates. Returns nil if data is not an object.
+func objectKeys(data []byte) []string {
+	dec := json.NewDecoder(bytes.NewReader(data))
+	if t, e := dec.Token(); e != nil || t != json.Delim('{') {
+		return nil
+	}
+
+	var keys []string
+	seen := make(map[string]bool)
+	for dec.More() {
+		t, e := dec.Token()
+		if e != nil {
+			return keys
+		}
+		k := t.(string)
+		if !seen[k] {
+			keys = append(keys, k)
+			seen[k] = true
+		}
+		var v json.RawMessage
+		if e = dec.Decode(&v); e != nil {
+			return keys
+		}
+	}
+	return keys
+}
+
+// fieldError prefixes the field of an error with the given field.
+func fieldError(field string, e error) error {
+	if ae, ok := e.(*asset.Error); ok {
+		return &asset.Error{File: ae.File, Field: field + "." + ae.Field, Err: ae.Err}
+	}
+	return &asset.Error{Field: field, Err: e}
+}
+
 // MarshalJSON encodes a node as JSON.
+// Mounted models are encoded as references rather than their content.
+// Children and components are encoded in order, such that the output is
+// stable.
 func (n *Node) MarshalJSON() ([]byte, error) {
+	var children, components orderedObject
+	for _, v := range n.childOrder {
+		if !v.mounted {
+			children.keys = append(children.keys, v.name)
+			children.values = append(children.values, v)
+		}
+	}
+	for _, v := range n.compOrder {
+		components.keys = append(components.keys, componentName(v))
+		components.values = append(components.values, v)
+	}
+
 	tmp := struct {
-		Transform  Transform            `json:"transform"`
-		Children   map[string]*Node     `json:"children"`
-		Components map[string]Component `json:"components"`
+		Transform  Transform      `json:"transform"`
+		Children   orderedObject  `json:"children"`
+		Components orderedObject  `json:"components"`
+		Priorities map[string]int `json:"priorities,omitempty"`
+		Tags       []string       `json:"tags,omitempty"`
+		Layer      uint           `json:"layer,omitempty"`
+		Mount      string         `json:"mount,omitempty"`
 	}{
 		Transform:  n.Transform,
-		Children:   n.children,
-		Components: n.components,
+		Children:   children,
+		Components: components,
+		Priorities: n.priorities,
+		Tags:       n.tags,
+		Layer:      n.layer,
+		Mount:      n.mount,
 	}
 
 	return json.Marshal(&tmp)
 }
 
+// orderedObject is encoded as a JSON object with keys in the given order.
+type orderedObject struct {
+	keys   []string
+	values []interface{}
+}
+
+// MarshalJSON encodes the object as JSON.
+func (o orderedObject) MarshalJSON() ([]byte, error) {
+	var buf bytes.Buffer
+	buf.WriteByte('{')
+	for i, k := range o.keys {
+		if i > 0 {
+			buf.WriteByte(',')
+		}
+		key, _ := json.Marshal(k)
+		buf.Write(key)
+		buf.WriteByte(':')
+		val, e := json.Marshal(o.values[i])
+		if e != nil {
+			return nil, e
+		}
+		buf.Write(val)
+	}
+	buf.WriteByte('}')
+	return buf.Bytes(), nil
+}
+
 // String returns a JSON encoded string.
 func (n Node) String() string {
 	b, _ := json.Marshal(&n)
@@ -2,19 +2,26 @@ package scene
 
 import (
 	"encoding/json"
+	"reflect"
 	"testing"
 
 	mgl "github.com/go-gl/mathgl/mgl32"
+	"github.com/patrick-jessen/goplay/engine/asset"
+	"github.com/patrick-jessen/goplay/engine/bounds"
 )
 
 func init() {
 	RegisterComponent(&testComponent{})
+	RegisterComponent(&otherComponent{})
+	RegisterComponent(&destroyingComponent{})
+	RegisterComponent(&removingComponent{})
 }
 
 type testComponent struct {
 	initializeCalled int
 	renderCalled     int
 	updateCalled     int
+	destroyCalled    int
 	node             *Node
 	Value            int `json:"value"`
 }
@@ -23,12 +30,20 @@ func (t *testComponent) Initialize(n *Node) {
 	t.initializeCalled++
 	t.node = n
 }
-func (t *testComponent) Update() {
+func (t *testComponent) Update(float32) {
 	t.updateCalled++
 }
 func (t *testComponent) Render() {
 	t.renderCalled++
 }
+func (t *testComponent) OnDestroy() {
+	t.destroyCalled++
+}
+
+// otherComponent is a second component type, used for testing order.
+type otherComponent struct {
+	testComponent
+}
 
 func Test_newNode(t *testing.T) {
 	n := newNode()
@@ -53,7 +68,7 @@ func TestNode_initialize(t *testing.T) {
 	parent := newNode()
 	child := newNode()
 
-	child.initialize(parent, "child")
+	child.initialize(nil, parent, "child")
 
 	if child.Parent() != parent {
 		t.Errorf("incorrect parent. got %v, expected %v", child.parent, parent)
@@ -136,13 +151,200 @@ func TestNode_Component(t *testing.T) {
 	}
 }
 
+func TestNode_Components(t *testing.T) {
+	n := newNode()
+	c := &testComponent{}
+	n.AddComponent(c)
+
+	comps := n.Components()
+	if len(comps) != 1 || comps[0] != c {
+		t.Errorf("wrong components. got %v", comps)
+	}
+}
+
+// names returns the names of nodes.
+func names(nodes []*Node) []string {
+	out := make([]string, len(nodes))
+	for i, n := range nodes {
+		out[i] = n.Name()
+	}
+	return out
+}
+
+func TestNode_Children(t *testing.T) {
+	n := newNode()
+	for _, name := range []string{"b", "a", "c"} {
+		n.NewChild(name)
+	}
+
+	if got := names(n.Children()); !reflect.DeepEqual(got, []string{"b", "a", "c"}) {
+		t.Errorf("children are not in insertion order. got %v", got)
+	}
+}
+
+func TestNode_SetSiblingIndex(t *testing.T) {
+	n := newNode()
+	for _, name := range []string{"a", "b", "c"} {
+		n.NewChild(name)
+	}
+
+	tests := []struct {
+		child    string
+		idx      int
+		expected []string
+	}{
+		{"c", 0, []string{"c", "a", "b"}},
+		{"c", 1, []string{"a", "c", "b"}},
+		{"a", 5, []string{"c", "b", "a"}},
+		{"b", -1, []string{"b", "c", "a"}},
+	}
+	for i, test := range tests {
+		n.Child(test.child).SetSiblingIndex(test.idx)
+		if got := names(n.Children()); !reflect.DeepEqual(got, test.expected) {
+			t.Errorf("test %v: wrong order. got %v, expected %v", i, got, test.expected)
+		}
+	}
+	if idx := n.Child("c").SiblingIndex(); idx != 1 {
+		t.Errorf("wrong sibling index. got %v, expected %v", idx, 1)
+	}
+}
+
+func TestNode_SetComponentPriority(t *testing.T) {
+	n := newNode()
+	first, second := &testComponent{}, &otherComponent{}
+	n.AddComponent(first)
+	n.AddComponent(second)
+
+	n.SetComponentPriority("otherComponent", -1)
+	if comps := n.Components(); comps[0] != second || comps[1] != first {
+		t.Errorf("component of lower priority is not first. got %v", comps)
+	}
+	if p := n.ComponentPriority("otherComponent"); p != -1 {
+		t.Errorf("wrong priority. got %v, expected %v", p, -1)
+	}
+
+	n.SetComponentPriority("otherComponent", 0)
+	if comps := n.Components(); comps[0] != first || comps[1] != second {
+		t.Errorf("components of equal priority are not in insertion order. got %v", comps)
+	}
+}
+
+func TestNode_RemoveComponent(t *testing.T) {
+	n := newNode()
+	c := &testComponent{}
+	n.AddComponent(c)
+	n.SetComponentPriority("testComponent", 1)
+
+	n.RemoveComponent("testComponent")
+	if n.Component("testComponent") != nil || len(n.Components()) != 0 {
+		t.Error("component was not removed")
+	}
+	if n.ComponentPriority("testComponent") != 0 {
+		t.Error("priority of removed component was kept")
+	}
+	if c.destroyCalled != 1 {
+		t.Errorf("OnDestroy() was not called the right number of times. got %v expected %v", c.destroyCalled, 1)
+	}
+}
+
+func TestNode_RemoveChild(t *testing.T) {
+	n := newNode()
+	child := n.NewChild("child")
+	c := &testComponent{}
+	child.AddComponent(c)
+
+	if removed := n.RemoveChild("child"); removed != child {
+		t.Errorf("wrong child returned. got %v, expected %v", removed, child)
+	}
+	if n.Child("child") != nil || len(n.Children()) != 0 || child.Parent() != nil {
+		t.Error("child was not removed")
+	}
+	if c.destroyCalled != 0 {
+		t.Error("components of removed child should not be destroyed")
+	}
+	if n.RemoveChild("child") != nil {
+		t.Error("non-existing child was removed")
+	}
+}
+
+func TestNode_SetParent(t *testing.T) {
+	root := newNode()
+	a := root.NewChild("a")
+	a.SetPosition(mgl.Vec3{1, 0, 0})
+	b := root.NewChild("b")
+	b.SetPosition(mgl.Vec3{0, 2, 0})
+	child := a.NewChild("child")
+	child.SetPosition(mgl.Vec3{0, 0, 3})
+	root.update(0)
+
+	child.SetParent(b, true)
+	if child.Parent() != b || b.Child("child") != child || a.Child("child") != nil {
+		t.Fatal("child was not moved")
+	}
+	if !child.Position().ApproxEqual(mgl.Vec3{1, -2, 3}) {
+		t.Errorf("world transform was not kept. got position %v", child.Position())
+	}
+
+	child.SetParent(a, false)
+	if child.Position() != (mgl.Vec3{1, -2, 3}) {
+		t.Errorf("local transform was not kept. got position %v", child.Position())
+	}
+	if child.WorldTransform() != mgl.Translate3D(2, -2, 3) {
+		t.Errorf("wrong world transform. got %v", child.WorldTransform())
+	}
+
+	defer func() {
+		recover()
+	}()
+	a.SetParent(child, false)
+	t.Error("node can be moved below itself")
+}
+
+func TestNode_Rename(t *testing.T) {
+	n := newNode()
+	child := n.NewChild("a")
+	n.NewChild("b")
+
+	child.Rename("c")
+	if child.Name() != "c" || n.Child("c") != child || n.Child("a") != nil {
+		t.Error("child was not renamed")
+	}
+
+	defer func() {
+		recover()
+	}()
+	child.Rename("b")
+	t.Error("child can be renamed to the name of a sibling")
+}
+
+func TestNode_Destroy(t *testing.T) {
+	root := newNode()
+	n := root.NewChild("node")
+	c, childComp := &testComponent{}, &testComponent{}
+	n.AddComponent(c)
+	n.NewChild("child").AddComponent(childComp)
+
+	n.Destroy()
+	if root.Child("node") != nil {
+		t.Error("node was not removed from its parent")
+	}
+	if c.destroyCalled != 1 || childComp.destroyCalled != 1 {
+		t.Error("components were not destroyed")
+	}
+
+	n.Destroy()
+	if c.destroyCalled != 1 {
+		t.Error("components were destroyed twice")
+	}
+}
+
 func TestNode_update(t *testing.T) {
 	parent := newNode()
 	child := parent.NewChild("child")
 	child.SetPosition(mgl.Vec3{1, 2, 3})
 	child.AddComponent(&testComponent{})
 
-	parent.update()
+	parent.update(0)
 
 	if parent.WorldTransform() != mgl.Ident4() {
 		t.Errorf("root has wrong world transform.\ngot %v\nexpected %v",
@@ -159,6 +361,73 @@ func TestNode_update(t *testing.T) {
 	}
 }
 
+// destroyingComponent destroys its node when updated.
+type destroyingComponent struct {
+	testComponent
+}
+
+func (d *destroyingComponent) Update(float32) {
+	d.updateCalled++
+	d.node.Destroy()
+}
+
+// removingComponent removes the component with the given name when updated.
+type removingComponent struct {
+	testComponent
+	remove string
+}
+
+func (r *removingComponent) Update(float32) {
+	r.updateCalled++
+	r.node.RemoveComponent(r.remove)
+}
+
+func TestNode_update_destroy(t *testing.T) {
+	parent := newNode()
+	var comps []*testComponent
+	for _, name := range []string{"a", "b", "c"} {
+		c := &testComponent{}
+		parent.NewChild(name).AddComponent(c)
+		comps = append(comps, c)
+	}
+	d := &destroyingComponent{}
+	parent.Child("a").AddComponent(d)
+
+	parent.update(0)
+	if d.updateCalled != 1 {
+		t.Errorf("destroying component was updated %v times", d.updateCalled)
+	}
+	for i, c := range comps {
+		if c.updateCalled != 1 {
+			t.Errorf("component %v was updated %v times, expected 1", i, c.updateCalled)
+		}
+	}
+	if len(parent.Children()) != 2 {
+		t.Errorf("wrong number of children. got %v, expected 2", len(parent.Children()))
+	}
+
+	parent.update(0)
+	if comps[1].updateCalled != 2 || comps[2].updateCalled != 2 {
+		t.Error("remaining children were not updated")
+	}
+}
+
+func TestNode_update_removeComponent(t *testing.T) {
+	n := newNode()
+	r := &removingComponent{remove: "otherComponent"}
+	o := &otherComponent{}
+	n.AddComponent(r)
+	n.AddComponent(o)
+
+	n.update(0)
+	if r.updateCalled != 1 || o.updateCalled != 0 {
+		t.Errorf("wrong updates. got %v and %v, expected 1 and 0", r.updateCalled, o.updateCalled)
+	}
+	if o.destroyCalled != 1 {
+		t.Error("removed component was not destroyed")
+	}
+}
+
 func TestNode_render(t *testing.T) {
 	n := newNode()
 	c := testComponent{}
@@ -170,6 +439,57 @@ func TestNode_render(t *testing.T) {
 	}
 }
 
+// boundedComponent is a unit box at the origin of its node.
+type boundedComponent struct {
+	testComponent
+}
+
+func (b *boundedComponent) Bounds() bounds.AABB {
+	unit := bounds.AABB{Min: mgl.Vec3{-1, -1, -1}, Max: mgl.Vec3{1, 1, 1}}
+	return unit.Transform(b.node.WorldTransform())
+}
+
+func TestNode_renderFrustum(t *testing.T) {
+	root := newNode()
+	near := root.NewChild("near")
+	near.SetPosition(mgl.Vec3{0, 0, -10})
+	far := root.NewChild("far")
+	far.SetPosition(mgl.Vec3{0, 0, 10})
+	farChild := far.NewChild("child")
+	farChild.SetPosition(mgl.Vec3{0, 0, -20}) // In front of the camera
+
+	nearComp, farComp, childComp := &boundedComponent{}, &boundedComponent{}, &boundedComponent{}
+	unbounded := &testComponent{}
+	near.AddComponent(nearComp)
+	far.AddComponent(farComp)
+	far.AddComponent(unbounded)
+	farChild.AddComponent(childComp)
+	root.update(0)
+
+	expected := bounds.AABB{Min: mgl.Vec3{-1, -1, -11}, Max: mgl.Vec3{1, 1, 11}}
+	if b := root.Bounds(); b != expected {
+		t.Errorf("wrong root bounds. got %v, expected %v", b, expected)
+	}
+
+	proj := mgl.Perspective(mgl.DegToRad(90), 1, 0.1, 100)
+	f := bounds.FrustumFromMatrix(proj)
+	var stats CullStats
+	root.renderFrustum(&f, true, &stats)
+
+	if nearComp.renderCalled != 1 || childComp.renderCalled != 1 {
+		t.Error("visible components were not rendered")
+	}
+	if farComp.renderCalled != 0 {
+		t.Error("component behind the camera was rendered")
+	}
+	if unbounded.renderCalled != 1 {
+		t.Error("components without bounds should always be rendered")
+	}
+	if stats != (CullStats{Drawn: 2, Culled: 1}) {
+		t.Errorf("wrong stats. got %+v", stats)
+	}
+}
+
 func TestNode_UnmarshalJSON(t *testing.T) {
 	jsonSrc := `{
 		"transform": {
@@ -223,22 +543,25 @@ func TestNode_UnmarshalJSON(t *testing.T) {
 
 	// Test for non-existing component
 	jsonSrc = `{
-		"components": {
-			"doesNotExist": {
-				"value": 42
+		"children": {
+			"test": {
+				"components": {
+					"doesNotExist": {
+						"value": 42
+					}
+				}
 			}
 		}
 	}`
 	n = newNode()
-	defer func() {
-		recover()
-	}()
-
 	e = json.Unmarshal([]byte(jsonSrc), n)
-	if e != nil {
-		t.Fatal(e)
+	ae, ok := e.(*asset.Error)
+	if !ok {
+		t.Fatalf("non-existing component types allowed. got error %v", e)
+	}
+	if ae.Field != "children.test.components.doesNotExist" {
+		t.Errorf("wrong field. got %v, expected %v", ae.Field, "children.test.components.doesNotExist")
 	}
-	t.Error("non-existing component types allowed")
 }
 
 func TestNode_MarshalJSON(t *testing.T) {
@@ -256,6 +579,60 @@ func TestNode_MarshalJSON(t *testing.T) {
 	}
 }
 
+func TestNode_MarshalJSON_order(t *testing.T) {
+	jsonSrc := `{
+		"children": {"b": {}, "a": {}},
+		"components": {"otherComponent": {}, "testComponent": {}},
+		"priorities": {"testComponent": -1}
+	}`
+	expected := `{"transform":{"position":[0,0,0],"rotation":[1,0,0,0],"scale":[1,1,1]},"children":{` +
+		`"b":{"transform":{"position":[0,0,0],"rotation":[1,0,0,0],"scale":[1,1,1]},"children":{},"components":{}},` +
+		`"a":{"transform":{"position":[0,0,0],"rotation":[1,0,0,0],"scale":[1,1,1]},"children":{},"components":{}}},` +
+		`"components":{"testComponent":{"value":0},"otherComponent":{"value":0}},"priorities":{"testComponent":-1}}`
+
+	n := newNode()
+	if e := json.Unmarshal([]byte(jsonSrc), n); e != nil {
+		t.Fatal(e)
+	}
+	if got := names(n.Children()); !reflect.DeepEqual(got, []string{"b", "a"}) {
+		t.Errorf("children are not in file order. got %v", got)
+	}
+
+	b, e := json.Marshal(n)
+	if e != nil {
+		t.Fatal("failed to marshal node")
+	}
+	if string(b) != expected {
+		t.Errorf("did not marshal correctly.\n got %v\n expected %v", string(b), expected)
+	}
+}
+
+func TestNode_MarshalJSON_mount(t *testing.T) {
+	expected := `{"transform":{"position":[0,0,0],"rotation":[1,0,0,0],"scale":[1,1,1]},"children":{"user":{"transform":{"position":[0,0,0],"rotation":[1,0,0,0],"scale":[1,1,1]},"children":{},"components":{}}},"components":{},"mount":"cube"}`
+
+	n := newNode()
+	e := json.Unmarshal([]byte(`{"mount":"cube"}`), n)
+	if e != nil {
+		t.Fatal(e)
+	}
+	defer delete(MountMap, n)
+
+	if n.Mount() != "cube" {
+		t.Errorf("mount not set. got %v, expected %v", n.Mount(), "cube")
+	}
+
+	n.MountChild("0").MountChild("1").AddComponent(&testComponent{})
+	n.NewChild("user")
+
+	b, e := json.Marshal(n)
+	if e != nil {
+		t.Fatal("failed to marshal node")
+	}
+	if string(b) != expected {
+		t.Errorf("did not marshal correctly.\n got %v\n expected %v", string(b), expected)
+	}
+}
+
 func TestNode_String(t *testing.T) {
 	expected := `{"transform":{"position":[0,0,0],"rotation":[1,0,0,0],"scale":[1,1,1]},"children":{"child":{"transform":{"position":[0,0,0],"rotation":[1,0,0,0],"scale":[1,1,1]},"children":{},"components":{"testComponent":{"value":1234}}}},"components":{}}`
 
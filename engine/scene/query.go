@@ -0,0 +1,102 @@
+package scene
+
+import "strings"
+
+// NumLayers is the number of layers a node can be in.
+const NumLayers = 32
+
+// LayerMask is a set of layers, where bit i is set if layer i is included.
+type LayerMask uint32
+
+// AllLayers is the mask including every layer.
+const AllLayers LayerMask = 1<<NumLayers - 1
+
+// Contains returns whether the mask includes the given layer.
+func (m LayerMask) Contains(layer uint) bool {
+	return layer < NumLayers && m&(1<<layer) != 0
+}
+
+// Find returns the descendant at the given path, which consists of child
+// names separated by slashes, e.g. "camera/rig/lens". An empty path gives
+// the node itself. Returns nil if the descendant does not exist.
+func (n *Node) Find(path string) *Node {
+	cur := n
+	for _, name := range strings.Split(path, "/") {
+		if len(name) == 0 {
+			continue
+		}
+		if cur = cur.children[name]; cur == nil {
+			return nil
+		}
+	}
+	return cur
+}
+
+// Path returns the path of the node from the root of its tree, such that
+// root.Find(n.Path()) == n.
+func (n *Node) Path() string {
+	if n.parent == nil {
+		return ""
+	}
+	if p := n.parent.Path(); len(p) > 0 {
+		return p + "/" + n.name
+	}
+	return n.name
+}
+
+// GetComponent returns the first component of n which is of type T.
+// T may be an interface, such as Bounded.
+// Returns the zero value of T if there is no such component.
+func GetComponent[T any](n *Node) T {
+	for _, c := range n.compOrder {
+		if t, ok := c.(T); ok {
+			return t
+		}
+	}
+	var zero T
+	return zero
+}
+
+// FindAll returns the components of type T of n and its descendants, in
+// the order they are updated. T may be an interface, such as Bounded.
+func FindAll[T any](n *Node) []T {
+	var out []T
+	n.walk(func(d *Node) {
+		for _, c := range d.compOrder {
+			if t, ok := c.(T); ok {
+				out = append(out, t)
+			}
+		}
+	})
+	return out
+}
+
+// FindWithTag returns n and its descendants which have the given tag.
+func (n *Node) FindWithTag(tag string) []*Node {
+	var out []*Node
+	n.walk(func(d *Node) {
+		if d.HasTag(tag) {
+			out = append(out, d)
+		}
+	})
+	return out
+}
+
+// FindInLayers returns n and its descendants whose layer is in mask.
+func (n *Node) FindInLayers(mask LayerMask) []*Node {
+	var out []*Node
+	n.walk(func(d *Node) {
+		if mask.Contains(d.layer) {
+			out = append(out, d)
+		}
+	})
+	return out
+}
+
+// walk calls fn for n and its descendants, in the order they are updated.
+func (n *Node) walk(fn func(*Node)) {
+	fn(n)
+	for _, c := range n.childOrder {
+		c.walk(fn)
+	}
+}
@@ -0,0 +1,122 @@
+package scene
+
+import (
+	"encoding/json"
+	"testing"
+
+	"github.com/patrick-jessen/goplay/engine/asset"
+)
+
+func TestNode_Find(t *testing.T) {
+	root := newNode()
+	lens := root.NewChild("camera").NewChild("rig").NewChild("lens")
+
+	tests := []struct {
+		path     string
+		expected *Node
+	}{
+		{"camera/rig/lens", lens},
+		{"/camera/rig/lens/", lens},
+		{"", root},
+		{"camera/lens", nil},
+	}
+	for _, test := range tests {
+		if got := root.Find(test.path); got != test.expected {
+			t.Errorf("%q: got %v, expected %v", test.path, got, test.expected)
+		}
+	}
+	if p := lens.Path(); p != "camera/rig/lens" {
+		t.Errorf("wrong path. got %v, expected %v", p, "camera/rig/lens")
+	}
+}
+
+func TestGetComponent(t *testing.T) {
+	n := newNode()
+	c := &boundedComponent{}
+	n.AddComponent(c)
+
+	if got := GetComponent[*boundedComponent](n); got != c {
+		t.Errorf("wrong component. got %v, expected %v", got, c)
+	}
+	if got := GetComponent[Bounded](n); got != c {
+		t.Errorf("wrong component by interface. got %v, expected %v", got, c)
+	}
+	if got := GetComponent[*testComponent](n); got != nil {
+		t.Errorf("got component of wrong type: %v", got)
+	}
+}
+
+func TestFindAll(t *testing.T) {
+	root := newNode()
+	a, b := &testComponent{}, &testComponent{}
+	root.NewChild("a").AddComponent(a)
+	root.NewChild("b").NewChild("c").AddComponent(b)
+	root.AddComponent(&boundedComponent{})
+
+	got := FindAll[*testComponent](root)
+	if len(got) != 2 || got[0] != a || got[1] != b {
+		t.Errorf("wrong components. got %v", got)
+	}
+}
+
+func TestNode_FindWithTag(t *testing.T) {
+	root := newNode()
+	a := root.NewChild("a")
+	a.AddTag("enemy")
+	a.AddTag("enemy")
+	b := root.NewChild("b")
+	b.AddTag("enemy")
+	b.AddTag("boss")
+
+	if got := root.FindWithTag("enemy"); len(got) != 2 || got[0] != a || got[1] != b {
+		t.Errorf("wrong nodes. got %v", got)
+	}
+	if len(a.Tags()) != 1 {
+		t.Errorf("duplicate tag added. got %v", a.Tags())
+	}
+
+	b.RemoveTag("enemy")
+	if got := root.FindWithTag("enemy"); len(got) != 1 || got[0] != a {
+		t.Errorf("wrong nodes after removing tag. got %v", got)
+	}
+}
+
+func TestNode_FindInLayers(t *testing.T) {
+	root := newNode()
+	a := root.NewChild("a")
+	a.SetLayer(3)
+	b := root.NewChild("b")
+	b.SetLayer(5)
+
+	if got := root.FindInLayers(1<<3 | 1<<5); len(got) != 2 || got[0] != a || got[1] != b {
+		t.Errorf("wrong nodes. got %v", got)
+	}
+	if got := root.FindInLayers(1 << 0); len(got) != 1 || got[0] != root {
+		t.Errorf("wrong nodes in default layer. got %v", got)
+	}
+}
+
+func TestNode_UnmarshalJSON_tags(t *testing.T) {
+	n := newNode()
+	e := json.Unmarshal([]byte(`{"tags": ["player", "hero"], "layer": 2}`), n)
+	if e != nil {
+		t.Fatal(e)
+	}
+	if !n.HasTag("player") || !n.HasTag("hero") || n.Layer() != 2 {
+		t.Errorf("tags or layer not set. got %v and %v", n.Tags(), n.Layer())
+	}
+
+	b, _ := json.Marshal(n)
+	m := newNode()
+	if e = json.Unmarshal(b, m); e != nil {
+		t.Fatal(e)
+	}
+	if m.String() != n.String() {
+		t.Errorf("tags did not round-trip.\n got %v\n expected %v", m.String(), n.String())
+	}
+
+	e = json.Unmarshal([]byte(`{"layer": 32}`), newNode())
+	if ae, ok := e.(*asset.Error); !ok || ae.Field != "layer" {
+		t.Errorf("layer out of range allowed. got error %v", e)
+	}
+}
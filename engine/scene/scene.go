@@ -4,6 +4,8 @@ import (
 	"encoding/json"
 	"io/ioutil"
 
+	"github.com/patrick-jessen/goplay/engine/asset"
+	"github.com/patrick-jessen/goplay/engine/bounds"
 	"github.com/patrick-jessen/goplay/engine/shader"
 )
 
@@ -26,35 +28,87 @@ func New() Scene {
 	}
 }
 
+// Load loads the scene assets/scenes/{name}.json.
+// Panics if the scene cannot be loaded.
 func Load(name string) *Scene {
-	b, e := ioutil.ReadFile(sceneDir + name + ".json")
+	s, e := TryLoad(name)
 	if e != nil {
-		panic("scene not found: " + e.Error())
+		panic("could not load scene: " + e.Error())
+	}
+	return s
+}
+
+// TryLoad loads the scene assets/scenes/{name}.json.
+// Errors are of type *asset.Error.
+func TryLoad(name string) (*Scene, error) {
+	file := sceneDir + name + ".json"
+	b, e := ioutil.ReadFile(file)
+	if e != nil {
+		return nil, &asset.Error{File: file, Err: e}
 	}
 
 	node := newNode()
 	e = json.Unmarshal(b, node)
-	if e != nil {
-		panic("could not unmarshal scene: " + e.Error())
+	if ae, ok := e.(*asset.Error); ok {
+		ae.File = file
+		return nil, ae
+	} else if e != nil {
+		return nil, &asset.Error{File: file, Err: e}
 	}
 
 	var scene Scene
 	node.initialize(&scene, nil, "root")
 	scene.Root = node
-	return &scene
+	return &scene, nil
 }
 
-func (s *Scene) Update() {
+// Save writes the scene to assets/scenes/{name}.json.
+func (s *Scene) Save(name string) error {
+	b, e := json.MarshalIndent(s.Root, "", "    ")
+	if e != nil {
+		return e
+	}
+	return ioutil.WriteFile(sceneDir+name+".json", b, 0644)
+}
 
-	shader.SetViewProjectionMatrix(s.camera.ViewProjectionMatrix())
+// Camera returns the camera used for rendering the scene.
+// Returns nil if the scene has no camera.
+func (s *Scene) Camera() *Camera {
+	return s.camera
+}
 
-	s.Root.update()
+// FixedUpdate steps the simulation of the scene by dt seconds.
+func (s *Scene) FixedUpdate(dt float32) {
+	s.Root.fixedUpdate(dt)
+}
+
+// Update updates the scene, dt seconds after the last update.
+func (s *Scene) Update(dt float32) {
+	if s.camera != nil {
+		shader.SetViewProjectionMatrix(s.camera.ViewProjectionMatrix())
+	}
+
+	s.Root.update(dt)
 }
 
 func (s *Scene) Render() {
 	s.Root.render()
 }
 
+// CullStats counts the bounded components considered when rendering.
+type CullStats struct {
+	Drawn  int // Components which were rendered.
+	Culled int // Components which were outside the frustum.
+}
+
+// RenderFrustum renders the components which may be inside f.
+// Components which are not Bounded are always rendered.
+func (s *Scene) RenderFrustum(f *bounds.Frustum) CullStats {
+	var stats CullStats
+	s.Root.renderFrustum(f, true, &stats)
+	return stats
+}
+
 func (s *Scene) MakeCurrent() {
 	currentScene = s
 }
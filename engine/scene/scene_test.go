@@ -0,0 +1,38 @@
+package scene
+
+import (
+	"encoding/json"
+	"io/ioutil"
+	"os"
+	"testing"
+
+	mgl "github.com/go-gl/mathgl/mgl32"
+)
+
+func TestScene_Save(t *testing.T) {
+	wd, _ := os.Getwd()
+	defer os.Chdir(wd)
+	os.Chdir(t.TempDir())
+	os.MkdirAll(sceneDir, 0755)
+
+	s := New()
+	child := s.Root.NewChild("child")
+	child.SetPosition(mgl.Vec3{1, 2, 3})
+	child.AddComponent(&testComponent{Value: 42})
+
+	if e := s.Save("test"); e != nil {
+		t.Fatal(e)
+	}
+
+	b, e := ioutil.ReadFile(sceneDir + "test.json")
+	if e != nil {
+		t.Fatal(e)
+	}
+	n := newNode()
+	if e = json.Unmarshal(b, n); e != nil {
+		t.Fatal(e)
+	}
+	if n.String() != s.Root.String() {
+		t.Errorf("scene did not round-trip.\n got %v\n expected %v", n.String(), s.Root.String())
+	}
+}
@@ -0,0 +1,55 @@
+package shader
+
+import (
+	"github.com/go-gl/gl/v3.2-core/gl"
+	mgl "github.com/go-gl/mathgl/mgl32"
+
+	"github.com/patrick-jessen/goplay/engine/log"
+)
+
+// MaxInstances is the maximum number of instances of an instanced draw.
+const MaxInstances = 128
+
+var instanceUBO uint32
+
+// numInstances is the number of instance matrices in the uniform block.
+var numInstances int32
+
+// SetInstanceMatrices sets the model matrices of an instanced draw.
+// Passing nil disables instancing, such that the model matrix is used.
+func SetInstanceMatrices(mats []mgl.Mat4) {
+	if len(mats) > MaxInstances {
+		log.Warn("too many instances", "instances", len(mats), "max", MaxInstances)
+		mats = mats[:MaxInstances]
+	}
+
+	num := int32(len(mats))
+	if num == 0 && numInstances == 0 {
+		return
+	}
+	gl.BindBuffer(gl.UNIFORM_BUFFER, instanceUBO)
+	gl.BufferSubData(gl.UNIFORM_BUFFER, 0, 4, gl.Ptr(&num))
+	if num > 0 {
+		gl.BufferSubData(gl.UNIFORM_BUFFER, 16, len(mats)*64, gl.Ptr(&mats[0][0]))
+	}
+	numInstances = num
+}
+
+// initializeInstanceBuffer creates the instance uniform block.
+func initializeInstanceBuffer() {
+	// [numInstances:int, pad:12, instanceMats:mat4[MaxInstances]]
+	gl.GenBuffers(1, &instanceUBO)
+	gl.BindBuffer(gl.UNIFORM_BUFFER, instanceUBO)
+	gl.BufferData(gl.UNIFORM_BUFFER, 16+MaxInstances*64, nil, gl.DYNAMIC_DRAW)
+	gl.BindBufferBase(gl.UNIFORM_BUFFER, 4, instanceUBO)
+
+	var zero int32
+	gl.BufferSubData(gl.UNIFORM_BUFFER, 0, 4, gl.Ptr(&zero))
+}
+
+// bindInstanceUniforms binds the instance uniform block of a program.
+func bindInstanceUniforms(handle uint32) {
+	if ubi := gl.GetUniformBlockIndex(handle, gl.Str("instance_data\x00")); ubi != gl.INVALID_INDEX {
+		gl.UniformBlockBinding(handle, ubi, 4)
+	}
+}
@@ -6,27 +6,86 @@ package shader
 import (
 	"bytes"
 	"encoding/binary"
+	"errors"
+	"fmt"
 	"io/ioutil"
 	"strings"
 
 	"github.com/go-gl/gl/v3.2-core/gl"
 	mgl "github.com/go-gl/mathgl/mgl32"
+	"github.com/patrick-jessen/goplay/engine/asset"
+	"github.com/patrick-jessen/goplay/engine/log"
 )
 
 const shaderDir = "./assets/shaders/"
 
+const (
+	// MaxJoints is the maximum number of joints in a skin.
+	MaxJoints = 64
+	// MaxMorphTargets is the maximum number of active morph targets.
+	MaxMorphTargets = 4
+	// MaxLights is the maximum number of lights affecting a frame.
+	MaxLights = 64
+)
+
+// LightType is the type of a light.
+type LightType int32
+
+const (
+	LightDirectional LightType = iota
+	LightPoint
+	LightSpot
+)
+
+// Light holds the shader data of a light in world space.
+type Light struct {
+	Type      LightType
+	Position  mgl.Vec3
+	Direction mgl.Vec3 // The direction the light is pointing.
+	Color     mgl.Vec3 // Color multiplied by intensity.
+	Range     float32  // Distance at which the light reaches zero. 0 means infinite.
+	InnerCone float32  // Cosine of the angle at which a spot light starts to fall off.
+	OuterCone float32  // Cosine of the angle at which a spot light reaches zero.
+	Shadow    int32    // Index of the light's shadow map, or -1 if it has none.
+}
+
+// numTextureUnits is the number of sampler uniforms (tex0, tex1, ...)
+// which are bound to the texture unit of the same index.
+const numTextureUnits = 5
+
 var cache = make(map[string]Shader)
 var ubo uint32
+var skinUBO uint32
+var lightUBO uint32
+var override *Shader
+
+// current is the program in use, which is not rebound by Use.
+var current uint32
 
 // Load returns a shader by either loading it or reading from cache.
+// Panics if the shader cannot be loaded.
 func Load(name string) Shader {
+	s, e := TryLoad(name)
+	if e != nil {
+		panic(e)
+	}
+	return s
+}
+
+// TryLoad returns a shader by either loading it or reading from cache.
+// Errors are of type *asset.Error.
+func TryLoad(name string) (Shader, error) {
 	// Read form cache
 	if val, ok := cache[name]; ok {
-		return val
+		return val, nil
 	}
 	// Load from disk
-	cache[name] = Shader{handle: loadProgram(name)}
-	return cache[name]
+	handle, e := loadProgram(name)
+	if e != nil {
+		return Shader{}, e
+	}
+	cache[name] = Shader{handle: handle}
+	return cache[name], nil
 }
 
 // Shader represents an OpenGL shader program.
@@ -35,12 +94,38 @@ type Shader struct {
 }
 
 // Use sets a shader program for use.
+// If an override is set, the override is used instead.
 func (s Shader) Use() {
-	gl.UseProgram(s.handle)
+	h := s.handle
+	if override != nil {
+		h = override.handle
+	}
+	if h != current {
+		gl.UseProgram(h)
+		current = h
+	}
+}
+
+// ID returns a number identifying the shader program.
+func (s Shader) ID() uint32 {
+	return s.handle
+}
+
+// SetOverride makes every shader use s instead, such as when rendering
+// shadow maps or G-buffers. Passing nil removes the override.
+func SetOverride(s *Shader) {
+	override = s
+}
+
+// Override returns the shader overriding every shader.
+// Returns nil if there is no override.
+func Override() *Shader {
+	return override
 }
 
 func (s Shader) GetUniform(name string) int32 {
-	s.Use()
+	gl.UseProgram(s.handle)
+	current = s.handle
 	return gl.GetUniformLocation(s.handle, gl.Str(name+"\x00"))
 }
 
@@ -68,46 +153,161 @@ func SetViewPosition(pos mgl.Vec3) {
 	gl.BufferSubData(gl.UNIFORM_BUFFER, 128, 12, gl.Ptr(&pos[0]))
 }
 
+// SetJointMatrices sets the joint matrices used for skinning.
+// Passing nil disables skinning.
+func SetJointMatrices(mats []mgl.Mat4) {
+	if len(mats) > MaxJoints {
+		log.Warn("too many joints in skin", "joints", len(mats), "max", MaxJoints)
+		mats = mats[:MaxJoints]
+	}
+
+	num := int32(len(mats))
+	gl.BindBuffer(gl.UNIFORM_BUFFER, skinUBO)
+	gl.BufferSubData(gl.UNIFORM_BUFFER, 0, 4, gl.Ptr(&num))
+	if num > 0 {
+		gl.BufferSubData(gl.UNIFORM_BUFFER, 32, len(mats)*64, gl.Ptr(&mats[0][0]))
+	}
+}
+
+// SetMorphWeights sets the weights of the morph targets.
+// Passing nil disables morphing.
+func SetMorphWeights(weights []float32) {
+	var w [MaxMorphTargets]float32
+	copy(w[:], weights)
+
+	gl.BindBuffer(gl.UNIFORM_BUFFER, skinUBO)
+	gl.BufferSubData(gl.UNIFORM_BUFFER, 16, MaxMorphTargets*4, gl.Ptr(&w[0]))
+}
+
+// SetLights sets the lights for all shaders.
+func SetLights(lights []Light) {
+	if len(lights) > MaxLights {
+		log.Warn("too many lights", "lights", len(lights), "max", MaxLights)
+		lights = lights[:MaxLights]
+	}
+	data := PackLights(lights)
+
+	num := int32(len(lights))
+	gl.BindBuffer(gl.UNIFORM_BUFFER, lightUBO)
+	gl.BufferSubData(gl.UNIFORM_BUFFER, 0, 4, gl.Ptr(&num))
+	if num > 0 {
+		gl.BufferSubData(gl.UNIFORM_BUFFER, 16, len(data)*4, gl.Ptr(&data[0]))
+	}
+}
+
+// PackLights packs lights into the layout used by shaders.
+// Each light is packed into 4 vec4s:
+// [position, type], [direction, range], [color, shadow], [innerCone, outerCone, 0, 0]
+func PackLights(lights []Light) []float32 {
+	data := make([]float32, 16*len(lights))
+	for i, l := range lights {
+		d := data[i*16:]
+		copy(d[0:3], l.Position[:])
+		d[3] = float32(l.Type)
+		copy(d[4:7], l.Direction[:])
+		d[7] = l.Range
+		copy(d[8:11], l.Color[:])
+		d[11] = float32(l.Shadow)
+		d[12] = l.InnerCone
+		d[13] = l.OuterCone
+	}
+	return data
+}
+
 // loadProgram loads shaders from files and creates a shader program.
-func loadProgram(name string) uint32 {
+func loadProgram(name string) (uint32, error) {
 	file := shaderDir + name + "/" + name
 
-	vertSrc, e := ioutil.ReadFile(file + ".vert")
+	vertSrc, e := readSource(file+".vert", 0)
 	if e != nil {
-		panic("failed to load vertex shader:\n" + e.Error())
+		return 0, e
 	}
-	fragSrc, e := ioutil.ReadFile(file + ".frag")
+	fragSrc, e := readSource(file+".frag", 0)
 	if e != nil {
-		panic("failed to load fragment shader:\n" + e.Error())
+		return 0, e
 	}
 
-	vert := compileShader(gl.VERTEX_SHADER, string(vertSrc))
-	frag := compileShader(gl.FRAGMENT_SHADER, string(fragSrc))
+	vert, e := compileShader(gl.VERTEX_SHADER, vertSrc)
+	if e != nil {
+		return 0, &asset.Error{File: file + ".vert", Err: e}
+	}
+	frag, e := compileShader(gl.FRAGMENT_SHADER, fragSrc)
+	if e != nil {
+		gl.DeleteShader(vert)
+		return 0, &asset.Error{File: file + ".frag", Err: e}
+	}
 
 	handle := gl.CreateProgram()
 
 	gl.AttachShader(handle, vert)
 	gl.AttachShader(handle, frag)
 
-	linkProgram(handle)
+	e = linkProgram(handle)
 
 	gl.DeleteShader(vert)
 	gl.DeleteShader(frag)
 
-	// TEMP
-	initializeUniformBuffer()
+	if e != nil {
+		gl.DeleteProgram(handle)
+		return 0, &asset.Error{File: shaderDir + name, Err: e}
+	}
+
+	// Uniform blocks are bound to the shared buffers
+	initializeUniformBuffers()
 	gl.UseProgram(handle)
+	current = handle
 	ubi := gl.GetUniformBlockIndex(handle, gl.Str("shader_data\x00"))
 	gl.UniformBlockBinding(handle, ubi, 0)
+	if ubi = gl.GetUniformBlockIndex(handle, gl.Str("skin_data\x00")); ubi != gl.INVALID_INDEX {
+		gl.UniformBlockBinding(handle, ubi, 1)
+	}
+	if ubi = gl.GetUniformBlockIndex(handle, gl.Str("light_data\x00")); ubi != gl.INVALID_INDEX {
+		gl.UniformBlockBinding(handle, ubi, 2)
+	}
+	bindShadowUniforms(handle)
+	bindInstanceUniforms(handle)
 
 	// Other uniforms
-	gl.Uniform1i(gl.GetUniformLocation(handle, gl.Str("tex0\x00")), 0)
-	gl.Uniform1i(gl.GetUniformLocation(handle, gl.Str("tex1\x00")), 1)
+	for i := int32(0); i < numTextureUnits; i++ {
+		gl.Uniform1i(gl.GetUniformLocation(handle, gl.Str(fmt.Sprintf("tex%v\x00", i))), i)
+	}
 
-	return handle
+	return handle, nil
 }
 
-func linkProgram(handle uint32) {
+// maxIncludeDepth limits nested includes, which also catches cycles.
+const maxIncludeDepth = 8
+
+// readSource reads a shader source file and resolves its includes.
+// A line of the form #include "name" is replaced by the content of
+// the file assets/shaders/name.
+// Errors are of type *asset.Error.
+func readSource(file string, depth int) (string, error) {
+	src, e := ioutil.ReadFile(file)
+	if e != nil {
+		return "", &asset.Error{File: file, Err: e}
+	}
+	if depth > maxIncludeDepth {
+		return "", &asset.Error{File: file, Err: errors.New("includes nested too deeply")}
+	}
+
+	lines := strings.Split(string(src), "\n")
+	for i, l := range lines {
+		l = strings.TrimSpace(l)
+		if !strings.HasPrefix(l, "#include") {
+			continue
+		}
+		name := strings.Trim(strings.TrimSpace(strings.TrimPrefix(l, "#include")), `"`)
+		inc, e := readSource(shaderDir+name, depth+1)
+		if e != nil {
+			return "", e
+		}
+		lines[i] = inc
+	}
+	return strings.Join(lines, "\n"), nil
+}
+
+func linkProgram(handle uint32) error {
 	gl.LinkProgram(handle)
 
 	var status int32
@@ -119,11 +319,12 @@ func linkProgram(handle uint32) {
 		log := strings.Repeat("\x00", int(logLength+1))
 		gl.GetProgramInfoLog(handle, logLength, nil, gl.Str(log))
 
-		panic("failed to link program:\n" + log)
+		return errors.New("failed to link program:\n" + log)
 	}
+	return nil
 }
 
-func compileShader(t uint32, src string) uint32 {
+func compileShader(t uint32, src string) (uint32, error) {
 	// Make sure string is null-terminated
 	src += "\x00"
 
@@ -143,12 +344,15 @@ func compileShader(t uint32, src string) uint32 {
 		log := strings.Repeat("\x00", int(logLength+1))
 		gl.GetShaderInfoLog(handle, logLength, nil, gl.Str(log))
 
-		panic("failed to compile shader:\n" + log)
+		gl.DeleteShader(handle)
+		return 0, errors.New("failed to compile shader:\n" + log)
 	}
-	return handle
+	return handle, nil
 }
 
-func initializeUniformBuffer() {
+// initializeUniformBuffers creates the uniform buffers shared by all
+// programs. Only the first call has an effect.
+func initializeUniformBuffers() {
 	if ubo != 0 {
 		return
 	}
@@ -175,4 +379,24 @@ func initializeUniformBuffer() {
 	gl.BindBufferBase(gl.UNIFORM_BUFFER, 0, handle)
 
 	ubo = handle
+
+	// Deformation data:
+	// [numJoints:int, pad:12, morphWeights:vec4, joints:mat4[MaxJoints]]
+	gl.GenBuffers(1, &skinUBO)
+	gl.BindBuffer(gl.UNIFORM_BUFFER, skinUBO)
+	gl.BufferData(gl.UNIFORM_BUFFER, 32+MaxJoints*64, nil, gl.DYNAMIC_DRAW)
+	gl.BindBufferBase(gl.UNIFORM_BUFFER, 1, skinUBO)
+	SetJointMatrices(nil)
+	SetMorphWeights(nil)
+
+	// Light data:
+	// [numLights:int, pad:12, lights:vec4[4][MaxLights]]
+	gl.GenBuffers(1, &lightUBO)
+	gl.BindBuffer(gl.UNIFORM_BUFFER, lightUBO)
+	gl.BufferData(gl.UNIFORM_BUFFER, 16+MaxLights*64, nil, gl.DYNAMIC_DRAW)
+	gl.BindBufferBase(gl.UNIFORM_BUFFER, 2, lightUBO)
+	SetLights(nil)
+
+	initializeShadowBuffer()
+	initializeInstanceBuffer()
 }
@@ -0,0 +1,94 @@
+package shader
+
+import (
+	"fmt"
+
+	"github.com/go-gl/gl/v3.2-core/gl"
+	mgl "github.com/go-gl/mathgl/mgl32"
+)
+
+const (
+	// NumCascades is the number of shadow cascades of a directional light.
+	NumCascades = 3
+	// MaxSpotShadows is the maximum number of spot lights casting shadows.
+	MaxSpotShadows = 4
+	// MaxPointShadows is the maximum number of point lights casting shadows.
+	MaxPointShadows = 2
+)
+
+// Texture units of the shadow maps. Lower units are used by materials.
+const (
+	CascadeUnit     = numTextureUnits
+	SpotShadowUnit  = CascadeUnit + NumCascades
+	PointShadowUnit = SpotShadowUnit + MaxSpotShadows
+)
+
+// PointShadowNear is the near plane of point light shadow maps.
+const PointShadowNear = 0.05
+
+// Shadows holds the shader data of the shadow maps.
+type Shadows struct {
+	CascadeMats [NumCascades]mgl.Mat4    // World to shadow map transformations.
+	SpotMats    [MaxSpotShadows]mgl.Mat4 // World to shadow map transformations.
+	PointFar    [MaxPointShadows]float32 // Far plane of each point light shadow map.
+	Bias        float32                  // Constant depth bias.
+	SlopeBias   float32                  // Depth bias scaled by the slope of the surface.
+	PCF         int32                    // Radius of the PCF kernel in texels.
+	TexelSize   float32                  // Size of a texel in shadow map coordinates.
+}
+
+var shadowUBO uint32
+
+// SetShadows sets the shadow data for all shaders.
+func SetShadows(s *Shadows) {
+	// [cascadeMats:mat4[NumCascades], spotMats:mat4[MaxSpotShadows],
+	//  pointFar:vec4, bias:float, slopeBias:float, pcf:float, texelSize:float]
+	data := make([]float32, 0, shadowDataSize/4)
+	for _, m := range s.CascadeMats {
+		data = append(data, m[:]...)
+	}
+	for _, m := range s.SpotMats {
+		data = append(data, m[:]...)
+	}
+	var far [4]float32
+	copy(far[:], s.PointFar[:])
+	data = append(data, far[:]...)
+	data = append(data, s.Bias, s.SlopeBias, float32(s.PCF), s.TexelSize)
+
+	gl.BindBuffer(gl.UNIFORM_BUFFER, shadowUBO)
+	gl.BufferSubData(gl.UNIFORM_BUFFER, 0, len(data)*4, gl.Ptr(&data[0]))
+}
+
+// shadowDataSize is the size of the shadow uniform block.
+const shadowDataSize = (NumCascades+MaxSpotShadows)*64 + 32
+
+// initializeShadowBuffer creates the shadow uniform block.
+func initializeShadowBuffer() {
+	gl.GenBuffers(1, &shadowUBO)
+	gl.BindBuffer(gl.UNIFORM_BUFFER, shadowUBO)
+	gl.BufferData(gl.UNIFORM_BUFFER, shadowDataSize, nil, gl.DYNAMIC_DRAW)
+	gl.BindBufferBase(gl.UNIFORM_BUFFER, 3, shadowUBO)
+}
+
+// bindShadowUniforms binds the shadow uniforms of a program.
+// Assumes the program is in use.
+func bindShadowUniforms(handle uint32) {
+	if ubi := gl.GetUniformBlockIndex(handle, gl.Str("shadow_data\x00")); ubi != gl.INVALID_INDEX {
+		gl.UniformBlockBinding(handle, ubi, 3)
+	}
+	samplers := []struct {
+		name  string
+		unit  int32
+		count int32
+	}{
+		{"cascadeMap", CascadeUnit, NumCascades},
+		{"spotShadowMap", SpotShadowUnit, MaxSpotShadows},
+		{"pointShadowMap", PointShadowUnit, MaxPointShadows},
+	}
+	for _, s := range samplers {
+		for i := int32(0); i < s.count; i++ {
+			loc := gl.GetUniformLocation(handle, gl.Str(fmt.Sprintf("%v%v\x00", s.name, i)))
+			gl.Uniform1i(loc, s.unit+i)
+		}
+	}
+}
@@ -0,0 +1,397 @@
+package texture
+
+import "encoding/binary"
+
+// blockDecoders decode a 4x4 block of a compressed format to RGBA8 pixels
+// in row-major order. They are used when the GPU does not support the
+// format. BC6H holds float data, and is decoded by decodeBC6H instead.
+var blockDecoders = map[format]func(b []byte, out *[16][4]uint8){
+	formatBC1: decodeBC1,
+	formatBC2: decodeBC2,
+	formatBC3: decodeBC3,
+	formatBC4: decodeBC4,
+	formatBC5: decodeBC5,
+	formatBC7: decodeBC7,
+}
+
+func decodeBC1(b []byte, out *[16][4]uint8) {
+	decodeColors(b, out, true)
+}
+
+func decodeBC2(b []byte, out *[16][4]uint8) {
+	decodeColors(b[8:], out, false)
+	alpha := binary.LittleEndian.Uint64(b)
+	for i := range out {
+		out[i][3] = uint8(alpha>>(4*i)&0xf) * 17
+	}
+}
+
+func decodeBC3(b []byte, out *[16][4]uint8) {
+	decodeColors(b[8:], out, false)
+	decodeChannel(b, out, 3)
+}
+
+// decodeBC4 decodes a single channel block. Like OpenGL, green and blue
+// are zero.
+func decodeBC4(b []byte, out *[16][4]uint8) {
+	*out = [16][4]uint8{}
+	decodeChannel(b, out, 0)
+	for i := range out {
+		out[i][3] = 255
+	}
+}
+
+// decodeBC5 decodes a two channel block. Like OpenGL, blue is zero.
+func decodeBC5(b []byte, out *[16][4]uint8) {
+	*out = [16][4]uint8{}
+	decodeChannel(b, out, 0)
+	decodeChannel(b[8:], out, 1)
+	for i := range out {
+		out[i][3] = 255
+	}
+}
+
+// decodeColors decodes the color part of BC1, BC2 and BC3 blocks. Only
+// BC1 blocks may use the three color mode with transparent black.
+func decodeColors(b []byte, out *[16][4]uint8, bc1 bool) {
+	c0 := binary.LittleEndian.Uint16(b)
+	c1 := binary.LittleEndian.Uint16(b[2:])
+
+	var palette [4][4]uint8
+	palette[0], palette[1] = rgb565(c0), rgb565(c1)
+	for c := 0; c < 3; c++ {
+		p0, p1 := int(palette[0][c]), int(palette[1][c])
+		if c0 > c1 || !bc1 {
+			palette[2][c] = uint8((2*p0 + p1) / 3)
+			palette[3][c] = uint8((p0 + 2*p1) / 3)
+		} else {
+			palette[2][c] = uint8((p0 + p1) / 2)
+		}
+	}
+	palette[2][3] = 255
+	if c0 > c1 || !bc1 {
+		palette[3][3] = 255
+	}
+
+	indices := binary.LittleEndian.Uint32(b[4:])
+	for i := range out {
+		out[i] = palette[indices>>(2*i)&3]
+	}
+}
+
+// rgb565 expands a 16-bit color to 8 bits per channel.
+func rgb565(c uint16) [4]uint8 {
+	r, g, b := uint8(c>>11&0x1f), uint8(c>>5&0x3f), uint8(c&0x1f)
+	return [4]uint8{r<<3 | r>>2, g<<2 | g>>4, b<<3 | b>>2, 255}
+}
+
+// decodeChannel decodes a BC4 block into channel c of out.
+func decodeChannel(b []byte, out *[16][4]uint8, c int) {
+	a0, a1 := int(b[0]), int(b[1])
+
+	var palette [8]uint8
+	palette[0], palette[1] = b[0], b[1]
+	if a0 > a1 {
+		for i := 1; i < 7; i++ {
+			palette[i+1] = uint8(((7-i)*a0 + i*a1) / 7)
+		}
+	} else {
+		for i := 1; i < 5; i++ {
+			palette[i+1] = uint8(((5-i)*a0 + i*a1) / 5)
+		}
+		palette[6], palette[7] = 0, 255
+	}
+
+	var indices uint64
+	for i := 7; i >= 2; i-- {
+		indices = indices<<8 | uint64(b[i])
+	}
+	for i := range out {
+		out[i][c] = palette[indices>>(3*i)&7]
+	}
+}
+
+// bc7Mode describes the layout of a BC7 block mode.
+type bc7Mode struct {
+	subsets       int
+	partitionBits int
+	rotationBits  int
+	selectorBits  int // Bits selecting which indices apply to alpha.
+	colorBits     int
+	alphaBits     int
+	endpointPBits bool // Whether each endpoint has a p-bit.
+	sharedPBits   bool // Whether each subset has a p-bit.
+	indexBits     int
+	indexBits2    int // Bits of the secondary indices, or 0.
+}
+
+var bc7Modes = [8]bc7Mode{
+	{3, 4, 0, 0, 4, 0, true, false, 3, 0},
+	{2, 6, 0, 0, 6, 0, false, true, 3, 0},
+	{3, 6, 0, 0, 5, 0, false, false, 2, 0},
+	{2, 6, 0, 0, 7, 0, true, false, 2, 0},
+	{1, 0, 2, 1, 5, 6, false, false, 2, 3},
+	{1, 0, 2, 0, 7, 8, false, false, 2, 2},
+	{1, 0, 0, 0, 7, 7, true, false, 4, 0},
+	{2, 6, 0, 0, 5, 5, true, false, 2, 0},
+}
+
+// bc7Weights are the interpolation weights by number of index bits.
+var bc7Weights = [5][]int{
+	2: {0, 21, 43, 64},
+	3: {0, 9, 18, 27, 37, 46, 55, 64},
+	4: {0, 4, 9, 13, 17, 21, 26, 30, 34, 38, 43, 47, 51, 55, 60, 64},
+}
+
+// bitReader reads the bits of a 128-bit block, least significant first.
+type bitReader struct {
+	lo, hi uint64
+}
+
+func (r *bitReader) read(n int) int {
+	v := r.lo & (1<<n - 1)
+	r.lo = r.lo>>n | r.hi<<(64-n)
+	r.hi >>= n
+	return int(v)
+}
+
+func decodeBC7(b []byte, out *[16][4]uint8) {
+	r := bitReader{binary.LittleEndian.Uint64(b), binary.LittleEndian.Uint64(b[8:])}
+
+	mode := 0
+	for mode < 8 && r.read(1) == 0 {
+		mode++
+	}
+	if mode == 8 {
+		// Reserved mode
+		*out = [16][4]uint8{}
+		return
+	}
+	m := &bc7Modes[mode]
+	partition := r.read(m.partitionBits)
+	rotation := r.read(m.rotationBits)
+	selector := r.read(m.selectorBits)
+
+	// Endpoints are stored channel by channel
+	var endpoints [6][4]int
+	n := m.subsets * 2
+	for c := 0; c < 3; c++ {
+		for i := 0; i < n; i++ {
+			endpoints[i][c] = r.read(m.colorBits)
+		}
+	}
+	for i := 0; i < n && m.alphaBits > 0; i++ {
+		endpoints[i][3] = r.read(m.alphaBits)
+	}
+
+	// P-bits are appended to the endpoints of either endpoint or subset
+	colorBits, alphaBits := m.colorBits, m.alphaBits
+	if m.endpointPBits || m.sharedPBits {
+		for i := 0; i < n; i++ {
+			if m.endpointPBits || i%2 == 0 {
+				p := r.read(1)
+				addPBit(&endpoints[i], p)
+				if m.sharedPBits {
+					addPBit(&endpoints[i+1], p)
+				}
+			}
+		}
+		colorBits++
+		if alphaBits > 0 {
+			alphaBits++
+		}
+	}
+	for i := 0; i < n; i++ {
+		for c := 0; c < 3; c++ {
+			endpoints[i][c] = expandBits(endpoints[i][c], colorBits)
+		}
+		if alphaBits > 0 {
+			endpoints[i][3] = expandBits(endpoints[i][3], alphaBits)
+		} else {
+			endpoints[i][3] = 255
+		}
+	}
+
+	// The anchor pixel of each subset omits the top bit of its index
+	var subsets [16]int
+	var anchors [3]int
+	switch m.subsets {
+	case 2:
+		for i := range subsets {
+			subsets[i] = int(bc7Partitions2[partition] >> i & 1)
+		}
+		anchors[1] = int(bc7Anchors2[partition])
+	case 3:
+		for i := range subsets {
+			subsets[i] = int(bc7Partitions3[partition][i])
+		}
+		anchors[1] = int(bc7Anchors3[0][partition])
+		anchors[2] = int(bc7Anchors3[1][partition])
+	}
+	var indices, indices2 [16]int
+	for i := range indices {
+		bits := m.indexBits
+		if i == anchors[subsets[i]] {
+			bits--
+		}
+		indices[i] = r.read(bits)
+	}
+	for i := range indices2 {
+		if m.indexBits2 > 0 {
+			bits := m.indexBits2
+			if i == 0 {
+				bits--
+			}
+			indices2[i] = r.read(bits)
+		}
+	}
+
+	for i := range out {
+		e0, e1 := &endpoints[subsets[i]*2], &endpoints[subsets[i]*2+1]
+		colorW := bc7Weights[m.indexBits][indices[i]]
+		alphaW := colorW
+		if m.indexBits2 > 0 {
+			alphaW = bc7Weights[m.indexBits2][indices2[i]]
+			if selector == 1 {
+				colorW, alphaW = alphaW, colorW
+			}
+		}
+		for c := 0; c < 3; c++ {
+			out[i][c] = uint8(((64-colorW)*e0[c] + colorW*e1[c] + 32) >> 6)
+		}
+		out[i][3] = uint8(((64-alphaW)*e0[3] + alphaW*e1[3] + 32) >> 6)
+		if rotation > 0 {
+			out[i][rotation-1], out[i][3] = out[i][3], out[i][rotation-1]
+		}
+	}
+}
+
+// addPBit appends a p-bit to each channel of an endpoint.
+func addPBit(e *[4]int, p int) {
+	for c := range e {
+		e[c] = e[c]<<1 | p
+	}
+}
+
+// expandBits expands an n-bit value to 8 bits by replicating its top bits.
+func expandBits(v, n int) int {
+	v <<= 8 - n
+	return v | v>>n
+}
+
+// bc7Partitions2 holds the subset of each pixel of the two subset
+// partitions, one bit per pixel.
+var bc7Partitions2 = [64]uint16{
+	0xcccc, 0x8888, 0xeeee, 0xecc8, 0xc880, 0xfeec, 0xfec8, 0xec80,
+	0xc800, 0xffec, 0xfe80, 0xe800, 0xffe8, 0xff00, 0xfff0, 0xf000,
+	0xf710, 0x008e, 0x7100, 0x08ce, 0x008c, 0x7310, 0x3100, 0x8cce,
+	0x088c, 0x3110, 0x6666, 0x366c, 0x17e8, 0x0ff0, 0x718e, 0x399c,
+	0xaaaa, 0xf0f0, 0x5a5a, 0x33cc, 0x3c3c, 0x55aa, 0x9696, 0xa55a,
+	0x73ce, 0x13c8, 0x324c, 0x3bdc, 0x6996, 0xc33c, 0x9966, 0x0660,
+	0x0272, 0x04e4, 0x4e40, 0x2720, 0xc936, 0x936c, 0x39c6, 0x639c,
+	0x9336, 0x9cc6, 0x817e, 0xe718, 0xccf0, 0x0fcc, 0x7744, 0xee22,
+}
+
+// bc7Partitions3 holds the subset of each pixel of the three subset
+// partitions.
+var bc7Partitions3 = [64][16]uint8{
+	{0, 0, 1, 1, 0, 0, 1, 1, 0, 2, 2, 1, 2, 2, 2, 2},
+	{0, 0, 0, 1, 0, 0, 1, 1, 2, 2, 1, 1, 2, 2, 2, 1},
+	{0, 0, 0, 0, 2, 0, 0, 1, 2, 2, 1, 1, 2, 2, 1, 1},
+	{0, 2, 2, 2, 0, 0, 2, 2, 0, 0, 1, 1, 0, 1, 1, 1},
+	{0, 0, 0, 0, 0, 0, 0, 0, 1, 1, 2, 2, 1, 1, 2, 2},
+	{0, 0, 1, 1, 0, 0, 1, 1, 0, 0, 2, 2, 0, 0, 2, 2},
+	{0, 0, 2, 2, 0, 0, 2, 2, 1, 1, 1, 1, 1, 1, 1, 1},
+	{0, 0, 1, 1, 0, 0, 1, 1, 2, 2, 1, 1, 2, 2, 1, 1},
+	{0, 0, 0, 0, 0, 0, 0, 0, 1, 1, 1, 1, 2, 2, 2, 2},
+	{0, 0, 0, 0, 1, 1, 1, 1, 1, 1, 1, 1, 2, 2, 2, 2},
+	{0, 0, 0, 0, 1, 1, 1, 1, 2, 2, 2, 2, 2, 2, 2, 2},
+	{0, 0, 1, 2, 0, 0, 1, 2, 0, 0, 1, 2, 0, 0, 1, 2},
+	{0, 1, 1, 2, 0, 1, 1, 2, 0, 1, 1, 2, 0, 1, 1, 2},
+	{0, 1, 2, 2, 0, 1, 2, 2, 0, 1, 2, 2, 0, 1, 2, 2},
+	{0, 0, 1, 1, 0, 1, 1, 2, 1, 1, 2, 2, 1, 2, 2, 2},
+	{0, 0, 1, 1, 2, 0, 0, 1, 2, 2, 0, 0, 2, 2, 2, 0},
+	{0, 0, 0, 1, 0, 0, 1, 1, 0, 1, 1, 2, 1, 1, 2, 2},
+	{0, 1, 1, 1, 0, 0, 1, 1, 2, 0, 0, 1, 2, 2, 0, 0},
+	{0, 0, 0, 0, 1, 1, 2, 2, 1, 1, 2, 2, 1, 1, 2, 2},
+	{0, 0, 2, 2, 0, 0, 2, 2, 0, 0, 2, 2, 1, 1, 1, 1},
+	{0, 1, 1, 1, 0, 1, 1, 1, 0, 2, 2, 2, 0, 2, 2, 2},
+	{0, 0, 0, 1, 0, 0, 0, 1, 2, 2, 2, 1, 2, 2, 2, 1},
+	{0, 0, 0, 0, 0, 0, 1, 1, 0, 1, 2, 2, 0, 1, 2, 2},
+	{0, 0, 0, 0, 1, 1, 0, 0, 2, 2, 1, 0, 2, 2, 1, 0},
+	{0, 1, 2, 2, 0, 1, 2, 2, 0, 0, 1, 1, 0, 0, 0, 0},
+	{0, 0, 1, 2, 0, 0, 1, 2, 1, 1, 2, 2, 2, 2, 2, 2},
+	{0, 1, 1, 0, 1, 2, 2, 1, 1, 2, 2, 1, 0, 1, 1, 0},
+	{0, 0, 0, 0, 0, 1, 1, 0, 1, 2, 2, 1, 1, 2, 2, 1},
+	{0, 0, 2, 2, 1, 1, 0, 2, 1, 1, 0, 2, 0, 0, 2, 2},
+	{0, 1, 1, 0, 0, 1, 1, 0, 2, 0, 0, 2, 2, 2, 2, 2},
+	{0, 0, 1, 1, 0, 1, 2, 2, 0, 1, 2, 2, 0, 0, 1, 1},
+	{0, 0, 0, 0, 2, 0, 0, 0, 2, 2, 1, 1, 2, 2, 2, 1},
+	{0, 0, 0, 0, 0, 0, 0, 2, 1, 1, 2, 2, 1, 2, 2, 2},
+	{0, 2, 2, 2, 0, 0, 2, 2, 0, 0, 1, 2, 0, 0, 1, 1},
+	{0, 0, 1, 1, 0, 0, 1, 2, 0, 0, 2, 2, 0, 2, 2, 2},
+	{0, 1, 2, 0, 0, 1, 2, 0, 0, 1, 2, 0, 0, 1, 2, 0},
+	{0, 0, 0, 0, 1, 1, 1, 1, 2, 2, 2, 2, 0, 0, 0, 0},
+	{0, 1, 2, 0, 1, 2, 0, 1, 2, 0, 1, 2, 0, 1, 2, 0},
+	{0, 1, 2, 0, 2, 0, 1, 2, 1, 2, 0, 1, 0, 1, 2, 0},
+	{0, 0, 1, 1, 2, 2, 0, 0, 1, 1, 2, 2, 0, 0, 1, 1},
+	{0, 0, 1, 1, 1, 1, 2, 2, 2, 2, 0, 0, 0, 0, 1, 1},
+	{0, 1, 0, 1, 0, 1, 0, 1, 2, 2, 2, 2, 2, 2, 2, 2},
+	{0, 0, 0, 0, 0, 0, 0, 0, 2, 1, 2, 1, 2, 1, 2, 1},
+	{0, 0, 2, 2, 1, 1, 2, 2, 0, 0, 2, 2, 1, 1, 2, 2},
+	{0, 0, 2, 2, 0, 0, 1, 1, 0, 0, 2, 2, 0, 0, 1, 1},
+	{0, 2, 2, 0, 1, 2, 2, 1, 0, 2, 2, 0, 1, 2, 2, 1},
+	{0, 1, 0, 1, 2, 2, 2, 2, 2, 2, 2, 2, 0, 1, 0, 1},
+	{0, 0, 0, 0, 2, 1, 2, 1, 2, 1, 2, 1, 2, 1, 2, 1},
+	{0, 1, 0, 1, 0, 1, 0, 1, 0, 1, 0, 1, 2, 2, 2, 2},
+	{0, 2, 2, 2, 0, 1, 1, 1, 0, 2, 2, 2, 0, 1, 1, 1},
+	{0, 0, 0, 2, 1, 1, 1, 2, 0, 0, 0, 2, 1, 1, 1, 2},
+	{0, 0, 0, 0, 2, 1, 1, 2, 2, 1, 1, 2, 2, 1, 1, 2},
+	{0, 2, 2, 2, 0, 1, 1, 1, 0, 1, 1, 1, 0, 2, 2, 2},
+	{0, 0, 0, 2, 1, 1, 1, 2, 1, 1, 1, 2, 0, 0, 0, 2},
+	{0, 1, 1, 0, 0, 1, 1, 0, 0, 1, 1, 0, 2, 2, 2, 2},
+	{0, 0, 0, 0, 0, 0, 0, 0, 2, 1, 1, 2, 2, 1, 1, 2},
+	{0, 1, 1, 0, 0, 1, 1, 0, 2, 2, 2, 2, 2, 2, 2, 2},
+	{0, 0, 2, 2, 0, 0, 1, 1, 0, 0, 1, 1, 0, 0, 2, 2},
+	{0, 0, 2, 2, 1, 1, 2, 2, 1, 1, 2, 2, 0, 0, 2, 2},
+	{0, 0, 0, 0, 0, 0, 0, 0, 0, 0, 0, 0, 2, 1, 1, 2},
+	{0, 0, 0, 2, 0, 0, 0, 1, 0, 0, 0, 2, 0, 0, 0, 1},
+	{0, 2, 2, 2, 1, 2, 2, 2, 0, 2, 2, 2, 1, 2, 2, 2},
+	{0, 1, 0, 1, 2, 2, 2, 2, 2, 2, 2, 2, 2, 2, 2, 2},
+	{0, 1, 1, 1, 2, 0, 1, 1, 2, 2, 0, 1, 2, 2, 2, 0},
+}
+
+// bc7Anchors2 holds the anchor pixel of the second subset of the two
+// subset partitions.
+var bc7Anchors2 = [64]uint8{
+	15, 15, 15, 15, 15, 15, 15, 15,
+	15, 15, 15, 15, 15, 15, 15, 15,
+	15, 2, 8, 2, 2, 8, 8, 15,
+	2, 8, 2, 2, 8, 8, 2, 2,
+	15, 15, 6, 8, 2, 8, 15, 15,
+	2, 8, 2, 2, 2, 15, 15, 6,
+	6, 2, 6, 8, 15, 15, 2, 2,
+	15, 15, 15, 15, 15, 2, 2, 15,
+}
+
+// bc7Anchors3 holds the anchor pixels of the second and third subset of
+// the three subset partitions.
+var bc7Anchors3 = [2][64]uint8{{
+	3, 3, 15, 15, 8, 3, 15, 15,
+	8, 8, 6, 6, 6, 5, 3, 3,
+	3, 3, 8, 15, 3, 3, 6, 10,
+	5, 8, 8, 6, 8, 5, 15, 15,
+	8, 15, 3, 5, 6, 10, 8, 15,
+	15, 3, 15, 5, 15, 15, 15, 15,
+	3, 15, 5, 5, 5, 8, 5, 10,
+	5, 10, 8, 13, 15, 12, 3, 3,
+}, {
+	15, 8, 8, 3, 15, 15, 3, 8,
+	15, 15, 15, 15, 15, 15, 15, 8,
+	15, 8, 15, 3, 15, 8, 15, 8,
+	3, 15, 6, 10, 15, 15, 10, 8,
+	15, 3, 15, 10, 10, 8, 9, 10,
+	6, 15, 8, 15, 3, 6, 6, 8,
+	15, 3, 15, 15, 15, 15, 15, 15,
+	15, 15, 15, 15, 3, 15, 15, 8,
+}}
@@ -0,0 +1,173 @@
+package texture
+
+import (
+	"encoding/binary"
+	"fmt"
+	"strings"
+)
+
+// bc6hMode describes the layout of a BC6H block mode.
+type bc6hMode struct {
+	regions      int
+	transformed  bool   // Whether endpoints are stored as deltas to the first.
+	endpointBits int    // Bits of the endpoints after undoing the deltas.
+	deltaBits    [3]int // Bits of the deltas of each channel.
+
+	// layout lists the fields following the mode bits in the order they
+	// are stored. Fields are named by channel (r, g or b) and endpoint (w,
+	// x, y or z), or d for the partition. Ranges are stored from the bit
+	// on the right to the bit on the left.
+	layout string
+	fields []bc6hField
+}
+
+// bc6hField is a bit of an endpoint or the partition.
+type bc6hField struct {
+	endpoint int // Index of the endpoint, or -1 for the partition.
+	channel  int
+	bit      int
+}
+
+// bc6hModes are the modes by mode value. Two region modes have a value of
+// 2 bits if it is below 2, and of 5 bits otherwise. Other values are
+// reserved.
+var bc6hModes = map[int]*bc6hMode{
+	0x00: {2, true, 10, [3]int{5, 5, 5}, "gy4 by4 bz4 rw9:0 gw9:0 bw9:0 rx4:0 gz4 gy3:0 gx4:0 bz0 gz3:0 bx4:0 bz1 by3:0 ry4:0 bz2 rz4:0 bz3 d4:0", nil},
+	0x01: {2, true, 7, [3]int{6, 6, 6}, "gy5 gz4 gz5 rw6:0 bz0 bz1 by4 gw6:0 by5 bz2 gy4 bw6:0 bz3 bz5 bz4 rx5:0 gy3:0 gx5:0 gz3:0 bx5:0 by3:0 ry5:0 rz5:0 d4:0", nil},
+	0x02: {2, true, 11, [3]int{5, 4, 4}, "rw9:0 gw9:0 bw9:0 rx4:0 rw10 gy3:0 gx3:0 gw10 bz0 gz3:0 bx3:0 bw10 bz1 by3:0 ry4:0 bz2 rz4:0 bz3 d4:0", nil},
+	0x06: {2, true, 11, [3]int{4, 5, 4}, "rw9:0 gw9:0 bw9:0 rx3:0 rw10 gz4 gy3:0 gx4:0 gw10 gz3:0 bx3:0 bw10 bz1 by3:0 ry3:0 bz0 bz2 rz3:0 gy4 bz3 d4:0", nil},
+	0x0a: {2, true, 11, [3]int{4, 4, 5}, "rw9:0 gw9:0 bw9:0 rx3:0 rw10 by4 gy3:0 gx3:0 gw10 bz0 gz3:0 bx4:0 bw10 by3:0 ry3:0 bz1 bz2 rz3:0 bz4 bz3 d4:0", nil},
+	0x0e: {2, true, 9, [3]int{5, 5, 5}, "rw8:0 by4 gw8:0 gy4 bw8:0 bz4 rx4:0 gz4 gy3:0 gx4:0 bz0 gz3:0 bx4:0 bz1 by3:0 ry4:0 bz2 rz4:0 bz3 d4:0", nil},
+	0x12: {2, true, 8, [3]int{6, 5, 5}, "rw7:0 gz4 by4 gw7:0 bz2 gy4 bw7:0 bz3 bz4 rx5:0 gy3:0 gx4:0 bz0 gz3:0 bx4:0 bz1 by3:0 ry5:0 rz5:0 d4:0", nil},
+	0x16: {2, true, 8, [3]int{5, 6, 5}, "rw7:0 bz0 by4 gw7:0 gy5 gy4 bw7:0 gz5 bz4 rx4:0 gz4 gy3:0 gx5:0 gz3:0 bx4:0 bz1 by3:0 ry4:0 bz2 rz4:0 bz3 d4:0", nil},
+	0x1a: {2, true, 8, [3]int{5, 5, 6}, "rw7:0 bz1 by4 gw7:0 by5 gy4 bw7:0 bz5 bz4 rx4:0 gz4 gy3:0 gx4:0 bz0 gz3:0 bx5:0 by3:0 ry4:0 bz2 rz4:0 bz3 d4:0", nil},
+	0x1e: {2, false, 6, [3]int{6, 6, 6}, "rw5:0 gz4 bz0 bz1 by4 gw5:0 gy5 by5 bz2 gy4 bw5:0 gz5 bz3 bz5 bz4 rx5:0 gy3:0 gx5:0 gz3:0 bx5:0 by3:0 ry5:0 rz5:0 d4:0", nil},
+	0x03: {1, false, 10, [3]int{10, 10, 10}, "rw9:0 gw9:0 bw9:0 rx9:0 gx9:0 bx9:0", nil},
+	0x07: {1, true, 11, [3]int{9, 9, 9}, "rw9:0 gw9:0 bw9:0 rx8:0 rw10 gx8:0 gw10 bx8:0 bw10", nil},
+	0x0b: {1, true, 12, [3]int{8, 8, 8}, "rw9:0 gw9:0 bw9:0 rx7:0 rw10:11 gx7:0 gw10:11 bx7:0 bw10:11", nil},
+	0x0f: {1, true, 16, [3]int{4, 4, 4}, "rw9:0 gw9:0 bw9:0 rx3:0 rw10:15 gx3:0 gw10:15 bx3:0 bw10:15", nil},
+}
+
+func init() {
+	for _, m := range bc6hModes {
+		m.fields = parseBC6HLayout(m.layout)
+	}
+}
+
+// parseBC6HLayout returns the fields of a layout in the order they are
+// stored.
+func parseBC6HLayout(layout string) []bc6hField {
+	var out []bc6hField
+	for _, tok := range strings.Fields(layout) {
+		f := bc6hField{endpoint: -1}
+		bits := tok[1:]
+		if tok[0] != 'd' {
+			f.channel = strings.IndexByte("rgb", tok[0])
+			f.endpoint = strings.IndexByte("wxyz", tok[1])
+			bits = tok[2:]
+		}
+
+		var left, right int
+		if _, e := fmt.Sscanf(bits, "%d:%d", &left, &right); e != nil {
+			fmt.Sscanf(bits, "%d", &left)
+			right = left
+		}
+		step := 1
+		if right > left {
+			step = -1
+		}
+		for b := right; ; b += step {
+			f.bit = b
+			out = append(out, f)
+			if b == left {
+				break
+			}
+		}
+	}
+	return out
+}
+
+// decodeBC6H decodes a BC6H block of unsigned floats to RGBA32F pixels in
+// row-major order. Blocks of reserved modes decode to black.
+func decodeBC6H(b []byte, out []byte) {
+	r := bitReader{binary.LittleEndian.Uint64(b), binary.LittleEndian.Uint64(b[8:])}
+
+	mode := r.read(2)
+	if mode > 1 {
+		mode |= r.read(3) << 2
+	}
+	m, ok := bc6hModes[mode]
+	if !ok {
+		for p := 0; p < 16; p++ {
+			for c := 0; c < 4; c++ {
+				putFloat(out[p*16+c*4:], 0)
+			}
+			putFloat(out[p*16+12:], 1)
+		}
+		return
+	}
+
+	// Endpoints w, x, y and z of each channel
+	var e [4][3]int
+	partition := 0
+	for _, f := range m.fields {
+		v := r.read(1)
+		if f.endpoint < 0 {
+			partition |= v << f.bit
+		} else {
+			e[f.endpoint][f.channel] |= v << f.bit
+		}
+	}
+
+	numEndpoints := 2 * m.regions
+	for c := 0; c < 3; c++ {
+		for i := 1; i < numEndpoints && m.transformed; i++ {
+			d := e[i][c]
+			if d&(1<<(m.deltaBits[c]-1)) != 0 {
+				d -= 1 << m.deltaBits[c]
+			}
+			e[i][c] = (e[0][c] + d) & (1<<m.endpointBits - 1)
+		}
+		for i := 0; i < numEndpoints; i++ {
+			e[i][c] = bc6hUnquantize(e[i][c], m.endpointBits)
+		}
+	}
+
+	// The index of the first pixel of each region has one bit less
+	indexBits := 4
+	if m.regions == 2 {
+		indexBits = 3
+	}
+	for p := 0; p < 16; p++ {
+		region, anchor := 0, p == 0
+		if m.regions == 2 {
+			region = int(bc7Partitions2[partition] >> p & 1)
+			anchor = anchor || p == int(bc7Anchors2[partition])
+		}
+		n := indexBits
+		if anchor {
+			n--
+		}
+		w := bc7Weights[indexBits][r.read(n)]
+
+		for c := 0; c < 3; c++ {
+			v := (e[2*region][c]*(64-w) + e[2*region+1][c]*w + 32) >> 6
+			putFloat(out[p*16+c*4:], halfToFloat(uint16(v*31>>6)))
+		}
+		putFloat(out[p*16+12:], 1)
+	}
+}
+
+// bc6hUnquantize expands an unsigned endpoint of the given number of bits
+// to 16 bits.
+func bc6hUnquantize(v, bits int) int {
+	switch {
+	case bits >= 15:
+		return v
+	case v == 0:
+		return 0
+	case v == 1<<bits-1:
+		return 0xffff
+	}
+	return (v<<16 + 0x8000) >> bits
+}
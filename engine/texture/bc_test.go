@@ -0,0 +1,251 @@
+package texture
+
+import (
+	"encoding/binary"
+	"testing"
+)
+
+// bitWriter writes the bits of a 128-bit block, least significant first.
+type bitWriter struct {
+	b   [16]byte
+	pos int
+}
+
+func (w *bitWriter) write(v, n int) {
+	for i := 0; i < n; i++ {
+		if v>>i&1 != 0 {
+			w.b[w.pos/8] |= 1 << (w.pos % 8)
+		}
+		w.pos++
+	}
+}
+
+func Test_decodeBC1(t *testing.T) {
+	var b [8]byte
+	binary.LittleEndian.PutUint16(b[0:], 0xf800) // Red
+	binary.LittleEndian.PutUint16(b[2:], 0x001f) // Blue
+	binary.LittleEndian.PutUint32(b[4:], 0<<0|1<<2|2<<4|3<<6)
+
+	var out [16][4]uint8
+	decodeBC1(b[:], &out)
+	expected := [4][4]uint8{{255, 0, 0, 255}, {0, 0, 255, 255}, {170, 0, 85, 255}, {85, 0, 170, 255}}
+	for i, e := range expected {
+		if out[i] != e {
+			t.Errorf("wrong four color pixel %v. got %v, expected %v", i, out[i], e)
+		}
+	}
+
+	// Three color mode has transparent black
+	binary.LittleEndian.PutUint16(b[0:], 0x001f)
+	binary.LittleEndian.PutUint16(b[2:], 0xf800)
+	decodeBC1(b[:], &out)
+	if out[2] != [4]uint8{127, 0, 127, 255} || out[3] != [4]uint8{} {
+		t.Errorf("wrong three color pixels. got %v and %v", out[2], out[3])
+	}
+}
+
+func Test_decodeBC4(t *testing.T) {
+	b := []byte{255, 0, 0, 0, 0, 0, 0, 0}
+	indices := uint64(0<<0 | 1<<3 | 2<<6 | 7<<9)
+	for i := 0; i < 6; i++ {
+		b[2+i] = uint8(indices >> (8 * i))
+	}
+
+	var out [16][4]uint8
+	decodeBC4(b, &out)
+	for i, e := range []uint8{255, 0, 218, 36} {
+		if out[i] != [4]uint8{e, 0, 0, 255} {
+			t.Errorf("wrong pixel %v. got %v, expected red %v", i, out[i], e)
+		}
+	}
+}
+
+func Test_decodeBC7(t *testing.T) {
+	// Mode 6 with a gradient from transparent black to opaque white
+	var w bitWriter
+	w.write(1<<6, 7)
+	for c := 0; c < 4; c++ {
+		w.write(0, 7)
+		w.write(127, 7)
+	}
+	w.write(0, 1) // P-bits
+	w.write(1, 1)
+	w.write(0, 3) // Anchor index
+	w.write(8, 4)
+	for i := 2; i < 16; i++ {
+		w.write(15, 4)
+	}
+
+	var out [16][4]uint8
+	decodeBC7(w.b[:], &out)
+	if out[0] != [4]uint8{} || out[1] != [4]uint8{135, 135, 135, 135} || out[15] != [4]uint8{255, 255, 255, 255} {
+		t.Errorf("wrong mode 6 pixels. got %v, %v and %v", out[0], out[1], out[15])
+	}
+
+	// Mode 1 with partition 13, which splits the block into a top and a
+	// bottom half
+	w = bitWriter{}
+	w.write(1<<1, 2)
+	w.write(13, 6)
+	colors := [3][4]int{{63, 63, 0, 0}, {0, 0, 0, 0}, {0, 0, 63, 63}}
+	for _, c := range colors {
+		for _, v := range c {
+			w.write(v, 6)
+		}
+	}
+	w.write(1, 1) // Shared p-bits, which also raise zero channels to 2
+	w.write(1, 1)
+
+	decodeBC7(w.b[:], &out)
+	red, blue := [4]uint8{255, 2, 2, 255}, [4]uint8{2, 2, 255, 255}
+	if out[0] != red || out[7] != red || out[8] != blue || out[15] != blue {
+		t.Errorf("wrong mode 1 pixels. got %v", out)
+	}
+}
+
+func Test_bc7Tables(t *testing.T) {
+	// Anchor pixels belong to their subset
+	for p := 0; p < 64; p++ {
+		if bc7Partitions2[p]>>bc7Anchors2[p]&1 != 1 {
+			t.Errorf("anchor of two subset partition %v is not in subset 1", p)
+		}
+		if bc7Partitions3[p][bc7Anchors3[0][p]] != 1 || bc7Partitions3[p][bc7Anchors3[1][p]] != 2 {
+			t.Errorf("anchors of three subset partition %v are not in their subsets", p)
+		}
+	}
+}
+
+func Test_surface_decompress(t *testing.T) {
+	b := make([]byte, 8)
+	binary.LittleEndian.PutUint16(b, 0xffff)
+	s := &surface{format: formatBC1, levels: []level{{2, 2, b}}}
+	if err := s.decompress(); err != nil {
+		t.Fatal(err)
+	}
+	if s.format != formatRGBA8 || len(s.levels[0].data) != 2*2*4 {
+		t.Errorf("wrong surface. got %v with %v bytes", s.format, len(s.levels[0].data))
+	}
+	for i, v := range s.levels[0].data {
+		if v != 255 {
+			t.Errorf("wrong value %v of byte %v", v, i)
+			break
+		}
+	}
+
+	s = &surface{format: formatBC6H, levels: []level{{2, 2, make([]byte, 16)}}}
+	if err := s.decompress(); err != nil {
+		t.Fatal(err)
+	}
+	if s.format != formatRGBA32F || len(s.levels[0].data) != 2*2*16 {
+		t.Errorf("wrong BC6H surface. got %v with %v bytes", s.format, len(s.levels[0].data))
+	}
+}
+
+func Test_bc6hModes(t *testing.T) {
+	for value, m := range bc6hModes {
+		// Each bit of the endpoints and the partition is stored once
+		count := make(map[bc6hField]int)
+		for _, f := range m.fields {
+			count[f]++
+		}
+		for e := 0; e < 2*m.regions; e++ {
+			for c := 0; c < 3; c++ {
+				n := m.endpointBits
+				if e > 0 && m.transformed {
+					n = m.deltaBits[c]
+				}
+				for b := 0; b < n; b++ {
+					if got := count[bc6hField{e, c, b}]; got != 1 {
+						t.Errorf("mode %#x: bit %v of endpoint %v channel %v is stored %v times", value, b, e, c, got)
+					}
+				}
+			}
+		}
+		bits := 65
+		if m.regions == 2 {
+			bits = 82
+			for b := 0; b < 5; b++ {
+				if got := count[bc6hField{-1, 0, b}]; got != 1 {
+					t.Errorf("mode %#x: partition bit %v is stored %v times", value, b, got)
+				}
+			}
+		}
+
+		// The header fills the block together with the indices
+		if got := bc6hModeBits(value) + len(m.fields); got != bits {
+			t.Errorf("mode %#x: wrong header size. expected %v, got %v", value, bits, got)
+		}
+	}
+}
+
+// bc6hModeBits returns the number of bits of a BC6H mode value.
+func bc6hModeBits(value int) int {
+	if value < 2 {
+		return 2
+	}
+	return 5
+}
+
+// bc6hBlock encodes a BC6H block of the given mode, endpoints, partition
+// and indices.
+func bc6hBlock(value int, e [4][3]int, partition int, indices [16]int) []byte {
+	m := bc6hModes[value]
+	var w bitWriter
+	w.write(value, bc6hModeBits(value))
+	for _, f := range m.fields {
+		if f.endpoint < 0 {
+			w.write(partition>>f.bit&1, 1)
+		} else {
+			w.write(e[f.endpoint][f.channel]>>f.bit&1, 1)
+		}
+	}
+	bits := 4
+	if m.regions == 2 {
+		bits = 3
+	}
+	for p, i := range indices {
+		n := bits
+		if p == 0 || (m.regions == 2 && p == int(bc7Anchors2[partition])) {
+			n--
+		}
+		w.write(i, n)
+	}
+	return w.b[:]
+}
+
+// pixelAt returns pixel p of RGBA32F pixels.
+func pixelAt(b []byte, p int) [4]float32 {
+	return [4]float32{floatAt(b, p*4), floatAt(b, p*4+1), floatAt(b, p*4+2), floatAt(b, p*4+3)}
+}
+
+func Test_decodeBC6H(t *testing.T) {
+	out := make([]byte, 16*16)
+
+	// Mode 11 from 0 to 1, which 495 unquantizes to
+	var indices [16]int
+	for i := 1; i < 16; i++ {
+		indices[i] = 15
+	}
+	decodeBC6H(bc6hBlock(0x03, [4][3]int{{0, 0, 0}, {495, 495, 495}}, 0, indices), out)
+	if pixelAt(out, 0) != [4]float32{0, 0, 0, 1} || pixelAt(out, 15) != [4]float32{1, 1, 1, 1} {
+		t.Errorf("wrong mode 11 pixels. got %v and %v", pixelAt(out, 0), pixelAt(out, 15))
+	}
+
+	// Mode 1 with partition 13, which splits the block into a top and a
+	// bottom half. The bottom endpoints are deltas of 15 and -1 to the
+	// first endpoint.
+	e := [4][3]int{{0, 0, 0}, {0, 0, 0}, {15, 15, 15}, {31, 31, 31}}
+	decodeBC6H(bc6hBlock(0x00, e, 13, [16]int{}), out)
+	bottom := halfToFloat(uint16((15*64 + 32) * 31 >> 6))
+	if pixelAt(out, 7) != [4]float32{0, 0, 0, 1} || pixelAt(out, 8) != [4]float32{bottom, bottom, bottom, 1} {
+		t.Errorf("wrong mode 1 pixels. got %v and %v", pixelAt(out, 7), pixelAt(out, 8))
+	}
+
+	// Reserved modes decode to black
+	var w bitWriter
+	w.write(0x13, 5)
+	decodeBC6H(w.b[:], out)
+	if pixelAt(out, 0) != [4]float32{0, 0, 0, 1} {
+		t.Errorf("wrong reserved mode pixel. got %v", pixelAt(out, 0))
+	}
+}
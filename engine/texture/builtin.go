@@ -0,0 +1,53 @@
+package texture
+
+import (
+	"image"
+	"image/color"
+)
+
+// Handles of the built-in textures, which are created when first bound.
+var placeholderHandle, checkerHandle uint32
+
+// builtin returns the handle of a built-in texture, creating it from the
+// image returned by img if needed.
+func builtin(handle *uint32, img func() *image.RGBA) uint32 {
+	if *handle == 0 {
+		*handle = newTexture(surfaceFromImage(img()), false)
+	}
+	return *handle
+}
+
+// placeholder returns the image bound while a texture is loading.
+// It is opaque white, such that it does not alter factors it is
+// multiplied with.
+func placeholder() *image.RGBA {
+	img := image.NewRGBA(image.Rect(0, 0, 1, 1))
+	img.Set(0, 0, color.White)
+	return img
+}
+
+// checkerSize and checkerCell are the size of the checkerboard and of its
+// cells in pixels.
+const (
+	checkerSize = 64
+	checkerCell = 8
+)
+
+// checkerboard returns the image bound in place of textures which failed
+// to load. The magenta and black pattern stands out in any scene.
+func checkerboard() *image.RGBA {
+	magenta := color.RGBA{255, 0, 255, 255}
+	black := color.RGBA{0, 0, 0, 255}
+
+	img := image.NewRGBA(image.Rect(0, 0, checkerSize, checkerSize))
+	for y := 0; y < checkerSize; y++ {
+		for x := 0; x < checkerSize; x++ {
+			if (x/checkerCell+y/checkerCell)%2 == 0 {
+				img.SetRGBA(x, y, magenta)
+			} else {
+				img.SetRGBA(x, y, black)
+			}
+		}
+	}
+	return img
+}
@@ -0,0 +1,205 @@
+package texture
+
+import (
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"math/bits"
+)
+
+// Flags of the DDS header.
+const (
+	ddsdMipMapCount = 0x20000
+	ddsdDepth       = 0x800000
+	ddpfAlphaPixels = 0x1
+	ddpfFourCC      = 0x4
+	ddpfRGB         = 0x40
+	ddsCaps2Cubemap = 0x200
+	ddsMiscCubemap  = 0x4
+	ddsHeaderSize   = 128
+	ddsDX10Size     = 20
+	d3dFmtRGBA16F   = 113
+	d3dFmtRGBA32F   = 116
+)
+
+// pixelConverter converts uncompressed pixels to the layout of a format.
+type pixelConverter struct {
+	size    int // Bytes per source pixel.
+	convert func(src []byte) []byte
+}
+
+// bgra converts 8-bit BGRA pixels to RGBA. If opaque, alpha is ignored.
+func bgra(opaque bool) *pixelConverter {
+	masks := [4]uint32{0xff0000, 0xff00, 0xff, 0xff000000}
+	if opaque {
+		masks[3] = 0
+	}
+	return unmask(masks)
+}
+
+// unmask converts 32-bit pixels whose channels are given by bit masks to
+// RGBA8. Channels without a mask are zero, or opaque for alpha.
+func unmask(masks [4]uint32) *pixelConverter {
+	return &pixelConverter{4, func(src []byte) []byte {
+		dst := make([]byte, len(src))
+		for i := 0; i+4 <= len(src); i += 4 {
+			p := binary.LittleEndian.Uint32(src[i:])
+			for c, m := range masks {
+				if m == 0 {
+					if c == 3 {
+						dst[i+c] = 255
+					}
+					continue
+				}
+				v := uint64(p&m) >> bits.TrailingZeros32(m)
+				dst[i+c] = uint8(v * 255 / (1<<bits.OnesCount32(m) - 1))
+			}
+		}
+		return dst
+	}}
+}
+
+// halfRGBA converts half float RGBA pixels to float RGBA.
+var halfRGBA = &pixelConverter{8, func(src []byte) []byte {
+	dst := make([]byte, len(src)*2)
+	for i := 0; i+2 <= len(src); i += 2 {
+		putFloat(dst[i*2:], halfToFloat(binary.LittleEndian.Uint16(src[i:])))
+	}
+	return dst
+}}
+
+// decodeDDS decodes a DirectDraw Surface holding a 2D texture and its
+// mipmaps. Cube maps, arrays and volume textures are not supported.
+func decodeDDS(data []byte) (*surface, error) {
+	if len(data) < ddsHeaderSize || string(data[:4]) != "DDS " {
+		return nil, errors.New("not a DDS file")
+	}
+	u32 := func(off int) uint32 {
+		return binary.LittleEndian.Uint32(data[off:])
+	}
+	flags, pfFlags := u32(8), u32(80)
+	if flags&ddsdDepth != 0 && u32(24) > 1 || u32(112)&ddsCaps2Cubemap != 0 {
+		return nil, errors.New("cube maps and volume textures are not supported")
+	}
+	numLevels := 1
+	if flags&ddsdMipMapCount != 0 && u32(28) > 0 {
+		numLevels = int(u32(28))
+	}
+
+	var f format
+	var conv *pixelConverter
+	offset := ddsHeaderSize
+	switch {
+	case pfFlags&ddpfFourCC != 0 && string(data[84:88]) == "DX10":
+		if len(data) < ddsHeaderSize+ddsDX10Size {
+			return nil, errors.New("truncated header")
+		}
+		if u32(136)&ddsMiscCubemap != 0 || u32(140) > 1 {
+			return nil, errors.New("cube maps and arrays are not supported")
+		}
+		var ok bool
+		if f, conv, ok = dxgiFormat(u32(128)); !ok {
+			return nil, fmt.Errorf("unsupported DXGI format %v", u32(128))
+		}
+		offset += ddsDX10Size
+	case pfFlags&ddpfFourCC != 0:
+		var ok bool
+		if f, conv, ok = fourCCFormat(data[84:88]); !ok {
+			return nil, fmt.Errorf("unsupported format %q", data[84:88])
+		}
+	case pfFlags&ddpfRGB != 0 && u32(88) == 32:
+		masks := [4]uint32{u32(92), u32(96), u32(100), u32(104)}
+		if pfFlags&ddpfAlphaPixels == 0 {
+			masks[3] = 0
+		}
+		f, conv = formatRGBA8, unmask(masks)
+	default:
+		return nil, errors.New("unsupported pixel format")
+	}
+
+	width, height := int(u32(16)), int(u32(12))
+	if err := checkSize(width, height, numLevels); err != nil {
+		return nil, err
+	}
+	s := &surface{format: f}
+	for i := 0; i < numLevels; i++ {
+		size := f.size(width, height)
+		if conv != nil {
+			size = width * height * conv.size
+		}
+		if offset+size > len(data) {
+			return nil, fmt.Errorf("truncated level %v", i)
+		}
+		b := data[offset : offset+size]
+		if conv != nil {
+			b = conv.convert(b)
+		}
+		s.levels = append(s.levels, level{width, height, b})
+		offset += size
+		width, height = halve(width), halve(height)
+	}
+	return s, nil
+}
+
+// fourCCFormat returns the format of a legacy DDS file.
+func fourCCFormat(fourCC []byte) (format, *pixelConverter, bool) {
+	switch string(fourCC) {
+	case "DXT1":
+		return formatBC1, nil, true
+	case "DXT2", "DXT3":
+		return formatBC2, nil, true
+	case "DXT4", "DXT5":
+		return formatBC3, nil, true
+	case "ATI1", "BC4U":
+		return formatBC4, nil, true
+	case "ATI2", "BC5U":
+		return formatBC5, nil, true
+	}
+	switch binary.LittleEndian.Uint32(fourCC) {
+	case d3dFmtRGBA16F:
+		return formatRGBA32F, halfRGBA, true
+	case d3dFmtRGBA32F:
+		return formatRGBA32F, nil, true
+	}
+	return 0, nil, false
+}
+
+// dxgiFormat returns the format of a DDS file with the DX10 header.
+// Typeless and signed formats are not supported.
+func dxgiFormat(dxgi uint32) (format, *pixelConverter, bool) {
+	switch dxgi {
+	case 2: // R32G32B32A32_FLOAT
+		return formatRGBA32F, nil, true
+	case 10: // R16G16B16A16_FLOAT
+		return formatRGBA32F, halfRGBA, true
+	case 28, 29: // R8G8B8A8_UNORM(_SRGB)
+		return formatRGBA8, nil, true
+	case 87, 91: // B8G8R8A8_UNORM(_SRGB)
+		return formatRGBA8, bgra(false), true
+	case 88: // B8G8R8X8_UNORM
+		return formatRGBA8, bgra(true), true
+	case 71, 72: // BC1_UNORM(_SRGB)
+		return formatBC1, nil, true
+	case 74, 75: // BC2_UNORM(_SRGB)
+		return formatBC2, nil, true
+	case 77, 78: // BC3_UNORM(_SRGB)
+		return formatBC3, nil, true
+	case 80: // BC4_UNORM
+		return formatBC4, nil, true
+	case 83: // BC5_UNORM
+		return formatBC5, nil, true
+	case 95: // BC6H_UF16
+		return formatBC6H, nil, true
+	case 98, 99: // BC7_UNORM(_SRGB)
+		return formatBC7, nil, true
+	}
+	return 0, nil, false
+}
+
+// halve returns the size of the next mipmap level.
+func halve(size int) int {
+	if size > 1 {
+		return size / 2
+	}
+	return 1
+}
@@ -0,0 +1,357 @@
+package texture
+
+import (
+	"bytes"
+	"compress/zlib"
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"io"
+	"math"
+	"sort"
+)
+
+// Compression methods and pixel types of OpenEXR files.
+const (
+	exrMagic           = 20000630
+	exrCompressionNone = 0
+	exrCompressionRLE  = 1
+	exrCompressionZIPS = 2
+	exrCompressionZIP  = 3
+	exrUint            = 0
+	exrHalf            = 1
+	exrFloat           = 2
+	exrUnsupportedBits = 0x200 | 0x800 | 0x1000 // Tiled, deep and multi-part.
+)
+
+// exrChannel is a channel of an OpenEXR file.
+type exrChannel struct {
+	name      string
+	pixelType uint32
+	target    []int // Channels of the RGBA pixel receiving the values.
+}
+
+// size returns the number of bytes per value of the channel.
+func (c *exrChannel) size() int {
+	if c.pixelType == exrHalf {
+		return 2
+	}
+	return 4
+}
+
+// value returns the value at the start of b as a float.
+func (c *exrChannel) value(b []byte) float32 {
+	switch c.pixelType {
+	case exrHalf:
+		return halfToFloat(binary.LittleEndian.Uint16(b))
+	case exrFloat:
+		return math.Float32frombits(binary.LittleEndian.Uint32(b))
+	}
+	return float32(binary.LittleEndian.Uint32(b))
+}
+
+// decodeEXR decodes an OpenEXR scanline image into float RGBA. The R, G,
+// B and A channels are read, or Y for luminance images. Alpha defaults to
+// one. Only uncompressed, RLE and ZIP compressed images are supported.
+func decodeEXR(data []byte) (*surface, error) {
+	if len(data) < 8 || binary.LittleEndian.Uint32(data) != exrMagic {
+		return nil, errors.New("not an OpenEXR file")
+	}
+	if version := binary.LittleEndian.Uint32(data[4:]); version&0xff != 2 || version&exrUnsupportedBits != 0 {
+		return nil, errors.New("only single-part scanline images are supported")
+	}
+
+	// Attributes are read until an empty name
+	var channels []*exrChannel
+	compression := -1
+	var window [4]int32
+	hasWindow := false
+	r := bytes.NewReader(data[8:])
+	for {
+		name, err := readCString(r)
+		if err != nil {
+			return nil, errors.New("truncated header")
+		}
+		if name == "" {
+			break
+		}
+		typ, err := readCString(r)
+		var size uint32
+		if err == nil {
+			err = binary.Read(r, binary.LittleEndian, &size)
+		}
+		if err != nil || int64(size) > int64(r.Len()) {
+			return nil, errors.New("truncated header")
+		}
+		value := make([]byte, size)
+		r.Read(value)
+
+		switch {
+		case name == "channels" && typ == "chlist":
+			if channels, err = parseChannels(value); err != nil {
+				return nil, err
+			}
+		case name == "compression" && typ == "compression" && size == 1:
+			compression = int(value[0])
+		case name == "dataWindow" && typ == "box2i" && size == 16:
+			for i := range window {
+				window[i] = int32(binary.LittleEndian.Uint32(value[i*4:]))
+			}
+			hasWindow = true
+		}
+	}
+	if channels == nil || compression < 0 || !hasWindow {
+		return nil, errors.New("missing required attributes")
+	}
+
+	width := int(int64(window[2]) - int64(window[0]) + 1)
+	height := int(int64(window[3]) - int64(window[1]) + 1)
+	if err := checkSize(width, height, 1); err != nil {
+		return nil, err
+	}
+	linesPerChunk := 1
+	switch compression {
+	case exrCompressionNone, exrCompressionRLE, exrCompressionZIPS:
+	case exrCompressionZIP:
+		linesPerChunk = 16
+	default:
+		return nil, fmt.Errorf("unsupported compression %v", compression)
+	}
+
+	lineSize := 0
+	for _, c := range channels {
+		lineSize += width * c.size()
+	}
+
+	// Chunks are located by the offset table following the header. They
+	// are checked before allocating the image, as its size may be corrupt.
+	numChunks := (height + linesPerChunk - 1) / linesPerChunk
+	table := len(data) - r.Len()
+	if len(data) < table+numChunks*8 {
+		return nil, errors.New("truncated offset table")
+	}
+	chunks := make([]exrChunk, numChunks)
+	for i := range chunks {
+		offset := binary.LittleEndian.Uint64(data[table+i*8:])
+		if offset > uint64(len(data)-8) {
+			return nil, fmt.Errorf("invalid offset of chunk %v", i)
+		}
+		c := exrChunk{offset: int(offset)}
+		c.y = int(int32(binary.LittleEndian.Uint32(data[offset:]))) - int(window[1])
+		size := int(binary.LittleEndian.Uint32(data[offset+4:]))
+		if c.y < 0 || c.y >= height || size > len(data)-c.offset-8 {
+			return nil, fmt.Errorf("invalid chunk %v", i)
+		}
+		c.packed = data[c.offset+8 : c.offset+8+size]
+		chunks[i] = c
+	}
+	if err := checkEXRChunks(chunks, height*lineSize, compression); err != nil {
+		return nil, err
+	}
+
+	pix := make([]byte, width*height*16)
+	for i := 0; i < width*height; i++ {
+		putFloat(pix[i*16+12:], 1)
+	}
+	for i, ch := range chunks {
+		lines := linesPerChunk
+		if ch.y+lines > height {
+			lines = height - ch.y
+		}
+
+		unpacked := ch.packed
+		if expected := lines * lineSize; len(ch.packed) < expected {
+			var err error
+			if unpacked, err = exrDecompress(ch.packed, expected, compression); err != nil {
+				return nil, fmt.Errorf("chunk %v: %v", i, err)
+			}
+		}
+		if len(unpacked) != lines*lineSize {
+			return nil, fmt.Errorf("wrong size of chunk %v", i)
+		}
+
+		// Each line holds all values of one channel after another
+		for l := 0; l < lines; l++ {
+			line := unpacked[l*lineSize:]
+			for _, c := range channels {
+				for x := 0; x < width; x++ {
+					v := c.value(line[x*c.size():])
+					for _, t := range c.target {
+						putFloat(pix[((ch.y+l)*width+x)*16+t*4:], v)
+					}
+				}
+				line = line[width*c.size():]
+			}
+		}
+	}
+	return &surface{format: formatRGBA32F, levels: []level{{width, height, pix}}}, nil
+}
+
+// exrChunk is a chunk of scanlines of an OpenEXR file.
+type exrChunk struct {
+	offset int // Offset of the chunk in the file.
+	y      int // First scanline of the chunk.
+	packed []byte
+}
+
+// exrMaxRatio is the largest ratio between the unpacked and packed sizes of
+// chunk data, by compression.
+var exrMaxRatio = map[int]int{
+	exrCompressionNone: 1,
+	exrCompressionRLE:  64,   // 2 bytes for a run of 128.
+	exrCompressionZIPS: 1032, // The limit of deflate.
+	exrCompressionZIP:  1032,
+}
+
+// checkEXRChunks returns an error if chunks overlap, or if they are too
+// small to hold the given number of bytes once unpacked.
+func checkEXRChunks(chunks []exrChunk, size, compression int) error {
+	sorted := make([]exrChunk, len(chunks))
+	copy(sorted, chunks)
+	sort.Slice(sorted, func(i, j int) bool { return sorted[i].offset < sorted[j].offset })
+
+	packed := 0
+	for i, c := range sorted {
+		if i > 0 && sorted[i-1].offset+8+len(sorted[i-1].packed) > c.offset {
+			return errors.New("overlapping chunks")
+		}
+		packed += len(c.packed)
+	}
+	if packed*exrMaxRatio[compression] < size {
+		return errors.New("truncated data")
+	}
+	return nil
+}
+
+// parseChannels parses a channel list. Channels other than R, G, B, A and
+// Y have no target, but are kept since their values are part of each
+// scanline.
+func parseChannels(b []byte) ([]*exrChannel, error) {
+	var channels []*exrChannel
+	r := bytes.NewReader(b)
+	for {
+		name, err := readCString(r)
+		if err != nil {
+			return nil, errors.New("truncated channel list")
+		}
+		if name == "" {
+			return channels, nil
+		}
+		var info struct {
+			PixelType            uint32
+			Linear               uint8
+			Reserved             [3]uint8
+			XSampling, YSampling int32
+		}
+		if err := binary.Read(r, binary.LittleEndian, &info); err != nil {
+			return nil, errors.New("truncated channel list")
+		}
+		if info.PixelType > exrFloat {
+			return nil, fmt.Errorf("invalid pixel type of channel %q", name)
+		}
+		if info.XSampling != 1 || info.YSampling != 1 {
+			return nil, fmt.Errorf("subsampled channel %q is not supported", name)
+		}
+
+		c := &exrChannel{name: name, pixelType: info.PixelType}
+		switch name {
+		case "R":
+			c.target = []int{0}
+		case "G":
+			c.target = []int{1}
+		case "B":
+			c.target = []int{2}
+		case "A":
+			c.target = []int{3}
+		case "Y":
+			c.target = []int{0, 1, 2}
+		}
+		channels = append(channels, c)
+	}
+}
+
+// exrDecompress decompresses a chunk. Both RLE and ZIP compressed data are
+// reordered and delta encoded before compression.
+func exrDecompress(packed []byte, size, compression int) ([]byte, error) {
+	var tmp []byte
+	switch compression {
+	case exrCompressionRLE:
+		var err error
+		if tmp, err = exrUnRLE(packed, size); err != nil {
+			return nil, err
+		}
+	case exrCompressionZIPS, exrCompressionZIP:
+		zr, err := zlib.NewReader(bytes.NewReader(packed))
+		if err != nil {
+			return nil, err
+		}
+		tmp = make([]byte, size)
+		if _, err := io.ReadFull(zr, tmp); err != nil {
+			return nil, err
+		}
+	default:
+		return nil, fmt.Errorf("unsupported compression %v", compression)
+	}
+
+	// Undo the delta encoding
+	for i := 1; i < len(tmp); i++ {
+		tmp[i] = tmp[i-1] + tmp[i] - 128
+	}
+
+	// Interleave the two halves
+	out := make([]byte, len(tmp))
+	half := (len(tmp) + 1) / 2
+	for i := range out {
+		if i%2 == 0 {
+			out[i] = tmp[i/2]
+		} else {
+			out[i] = tmp[half+i/2]
+		}
+	}
+	return out, nil
+}
+
+// exrUnRLE decodes run-length encoded data. A negative count is followed
+// by as many literal bytes, otherwise the next byte is repeated count+1
+// times.
+func exrUnRLE(in []byte, size int) ([]byte, error) {
+	out := make([]byte, 0, size)
+	for len(in) > 0 {
+		count := int(int8(in[0]))
+		in = in[1:]
+		if count < 0 {
+			if -count > len(in) || len(out)-count > size {
+				return nil, errors.New("invalid run")
+			}
+			out = append(out, in[:-count]...)
+			in = in[-count:]
+			continue
+		}
+		if len(in) == 0 || len(out)+count+1 > size {
+			return nil, errors.New("invalid run")
+		}
+		for i := 0; i <= count; i++ {
+			out = append(out, in[0])
+		}
+		in = in[1:]
+	}
+	if len(out) != size {
+		return nil, errors.New("truncated data")
+	}
+	return out, nil
+}
+
+// readCString reads a null-terminated string.
+func readCString(r *bytes.Reader) (string, error) {
+	var b []byte
+	for {
+		c, err := r.ReadByte()
+		if err != nil {
+			return "", err
+		}
+		if c == 0 {
+			return string(b), nil
+		}
+		b = append(b, c)
+	}
+}
@@ -0,0 +1,134 @@
+package texture
+
+import (
+	"bufio"
+	"bytes"
+	"errors"
+	"fmt"
+	"io"
+	"math"
+	"strings"
+)
+
+// decodeHDR decodes a Radiance RGBE image into float RGBA.
+// Only the standard orientation with rows from top to bottom is supported.
+func decodeHDR(data []byte) (*surface, error) {
+	br := bytes.NewReader(data)
+	r := bufio.NewReader(br)
+
+	// Header lines end with an empty line
+	magic, err := r.ReadString('\n')
+	if err != nil || !strings.HasPrefix(magic, "#?") {
+		return nil, errors.New("not a Radiance file")
+	}
+	for {
+		line, err := r.ReadString('\n')
+		if err != nil {
+			return nil, errors.New("truncated header")
+		}
+		line = strings.TrimSpace(line)
+		if line == "" {
+			break
+		}
+		if f := strings.TrimPrefix(line, "FORMAT="); f != line && f != "32-bit_rle_rgbe" {
+			return nil, fmt.Errorf("unsupported format %q", f)
+		}
+	}
+
+	var width, height int
+	line, _ := r.ReadString('\n')
+	if _, err := fmt.Sscanf(line, "-Y %d +X %d", &height, &width); err != nil {
+		return nil, fmt.Errorf("unsupported resolution %q", strings.TrimSpace(line))
+	}
+	if err := checkSize(width, height, 1); err != nil {
+		return nil, err
+	}
+
+	// The size is checked against the data before allocating the image.
+	// Scanlines take 4 bytes per pixel, or if run-length encoded, 4 bytes
+	// for the header and 2 bytes per run of up to 127 values per channel.
+	minLine := 4 * width
+	if width >= 8 && width <= 0x7fff {
+		minLine = 4 + 4*2*((width+126)/127)
+	}
+	if (r.Buffered()+br.Len())/minLine < height {
+		return nil, errors.New("truncated data")
+	}
+
+	pix := make([]byte, width*height*16)
+	scanline := make([]byte, width*4)
+	for y := 0; y < height; y++ {
+		if err := readRGBE(r, scanline); err != nil {
+			return nil, fmt.Errorf("scanline %v: %v", y, err)
+		}
+		for x := 0; x < width; x++ {
+			rgbe := scanline[x*4 : x*4+4]
+			out := pix[(y*width+x)*16:]
+			scale := float32(0)
+			if rgbe[3] != 0 {
+				scale = float32(math.Ldexp(1, int(rgbe[3])-(128+8)))
+			}
+			for c := 0; c < 3; c++ {
+				putFloat(out[c*4:], float32(rgbe[c])*scale)
+			}
+			putFloat(out[12:], 1)
+		}
+	}
+	return &surface{format: formatRGBA32F, levels: []level{{width, height, pix}}}, nil
+}
+
+// readRGBE reads a scanline of RGBE pixels. Scanlines are either flat or
+// run-length encoded channel by channel.
+func readRGBE(r *bufio.Reader, scanline []byte) error {
+	width := len(scanline) / 4
+	if _, err := io.ReadFull(r, scanline[:4]); err != nil {
+		return err
+	}
+	if width < 8 || width > 0x7fff || scanline[0] != 2 || scanline[1] != 2 || scanline[2]&0x80 != 0 {
+		_, err := io.ReadFull(r, scanline[4:])
+		return err
+	}
+	if int(scanline[2])<<8|int(scanline[3]) != width {
+		return errors.New("wrong scanline width")
+	}
+
+	for c := 0; c < 4; c++ {
+		for x := 0; x < width; {
+			count, err := r.ReadByte()
+			if err != nil {
+				return err
+			}
+			if count > 128 {
+				// Run of a single value
+				n := int(count - 128)
+				v, err := r.ReadByte()
+				if err != nil {
+					return err
+				}
+				if x+n > width {
+					return errors.New("run exceeds scanline")
+				}
+				for ; n > 0; n-- {
+					scanline[x*4+c] = v
+					x++
+				}
+				continue
+			}
+
+			// Literal values
+			n := int(count)
+			if n == 0 || x+n > width {
+				return errors.New("invalid run")
+			}
+			for ; n > 0; n-- {
+				v, err := r.ReadByte()
+				if err != nil {
+					return err
+				}
+				scanline[x*4+c] = v
+				x++
+			}
+		}
+	}
+	return nil
+}
@@ -0,0 +1,111 @@
+package texture
+
+import (
+	"bytes"
+	"encoding/binary"
+	"errors"
+	"fmt"
+)
+
+// ktx2Identifier starts every KTX2 file.
+var ktx2Identifier = []byte{0xab, 'K', 'T', 'X', ' ', '2', '0', 0xbb, '\r', '\n', 0x1a, '\n'}
+
+const (
+	ktx2HeaderSize     = 80
+	ktx2LevelIndexSize = 24
+)
+
+// decodeKTX2 decodes a KTX2 file holding a 2D texture and its mipmaps.
+// Supercompressed files, cube maps, arrays and volume textures are not
+// supported.
+func decodeKTX2(data []byte) (*surface, error) {
+	if len(data) < ktx2HeaderSize || !bytes.HasPrefix(data, ktx2Identifier) {
+		return nil, errors.New("not a KTX2 file")
+	}
+	u32 := func(off int) uint32 {
+		return binary.LittleEndian.Uint32(data[off:])
+	}
+	vkFormat := u32(12)
+	width, height, depth := int(u32(20)), int(u32(24)), u32(28)
+	layers, faces, numLevels := u32(32), u32(36), int(u32(40))
+	if scheme := u32(44); scheme != 0 {
+		return nil, fmt.Errorf("unsupported supercompression scheme %v", scheme)
+	}
+	if depth > 0 || layers > 1 || faces != 1 {
+		return nil, errors.New("cube maps, arrays and volume textures are not supported")
+	}
+	if height == 0 {
+		height = 1
+	}
+	if numLevels == 0 {
+		// Mipmaps are to be generated
+		numLevels = 1
+	}
+	if err := checkSize(width, height, numLevels); err != nil {
+		return nil, err
+	}
+
+	f, conv, ok := vkFormatOf(vkFormat)
+	if !ok {
+		return nil, fmt.Errorf("unsupported Vulkan format %v", vkFormat)
+	}
+	if len(data) < ktx2HeaderSize+numLevels*ktx2LevelIndexSize {
+		return nil, errors.New("truncated level index")
+	}
+
+	s := &surface{format: f}
+	for i := 0; i < numLevels; i++ {
+		entry := data[ktx2HeaderSize+i*ktx2LevelIndexSize:]
+		offset := binary.LittleEndian.Uint64(entry)
+		length := binary.LittleEndian.Uint64(entry[8:])
+
+		size := f.size(width, height)
+		if conv != nil {
+			size = width * height * conv.size
+		}
+		if length != uint64(size) {
+			return nil, fmt.Errorf("wrong size of level %v. got %v bytes, expected %v", i, length, size)
+		}
+		if offset > uint64(len(data)) || length > uint64(len(data))-offset {
+			return nil, fmt.Errorf("truncated level %v", i)
+		}
+		b := data[offset : offset+length]
+		if conv != nil {
+			b = conv.convert(b)
+		}
+		s.levels = append(s.levels, level{width, height, b})
+		width, height = halve(width), halve(height)
+	}
+	return s, nil
+}
+
+// vkFormatOf returns the format of a KTX2 file.
+// Signed formats and formats requiring the data format descriptor are not
+// supported.
+func vkFormatOf(vkFormat uint32) (format, *pixelConverter, bool) {
+	switch vkFormat {
+	case 37, 43: // R8G8B8A8_UNORM(_SRGB)
+		return formatRGBA8, nil, true
+	case 44, 50: // B8G8R8A8_UNORM(_SRGB)
+		return formatRGBA8, bgra(false), true
+	case 97: // R16G16B16A16_SFLOAT
+		return formatRGBA32F, halfRGBA, true
+	case 109: // R32G32B32A32_SFLOAT
+		return formatRGBA32F, nil, true
+	case 131, 132, 133, 134: // BC1_RGB(A)_UNORM(_SRGB)_BLOCK
+		return formatBC1, nil, true
+	case 135, 136: // BC2_UNORM(_SRGB)_BLOCK
+		return formatBC2, nil, true
+	case 137, 138: // BC3_UNORM(_SRGB)_BLOCK
+		return formatBC3, nil, true
+	case 139: // BC4_UNORM_BLOCK
+		return formatBC4, nil, true
+	case 141: // BC5_UNORM_BLOCK
+		return formatBC5, nil, true
+	case 143: // BC6H_UFLOAT_BLOCK
+		return formatBC6H, nil, true
+	case 145, 146: // BC7_UNORM(_SRGB)_BLOCK
+		return formatBC7, nil, true
+	}
+	return 0, nil, false
+}
@@ -67,6 +67,10 @@ func (s *settings) Apply() {
 			continue
 		}
 
+		if t.data {
+			continue
+		}
+
 		gl.BindTexture(gl.TEXTURE_2D, t.handle)
 		if s.curFilter != s.newFilter {
 			if s.newFilter == Bilinear {
@@ -0,0 +1,335 @@
+package texture
+
+import (
+	"encoding/binary"
+	"fmt"
+	"image"
+	"math"
+	"path/filepath"
+	"strings"
+
+	"github.com/go-gl/gl/v3.2-core/gl"
+)
+
+// format is the pixel format of a surface.
+type format int
+
+const (
+	formatRGBA8   format = iota // 8-bit normalized RGBA.
+	formatRGBA32F               // 32-bit float RGBA, uploaded as half floats.
+	formatBC1                   // DXT1, RGB with 1-bit alpha.
+	formatBC2                   // DXT3, RGB with explicit alpha.
+	formatBC3                   // DXT5, RGB with interpolated alpha.
+	formatBC4                   // RGTC1, red only.
+	formatBC5                   // RGTC2, red and green.
+	formatBC6H                  // BPTC, unsigned float RGB.
+	formatBC7                   // BPTC, RGBA.
+)
+
+// formatInfo describes how a format is stored and uploaded.
+type formatInfo struct {
+	name      string
+	blockSize int    // Bytes per 4x4 block, or 0 if uncompressed.
+	pixelSize int    // Bytes per pixel, or 0 if compressed.
+	internal  uint32 // OpenGL internal format.
+	extension string // OpenGL extension required by the format, if any.
+}
+
+const (
+	extS3TC = "GL_EXT_texture_compression_s3tc"
+	extBPTC = "GL_ARB_texture_compression_bptc"
+)
+
+var formats = [...]formatInfo{
+	formatRGBA8:   {"RGBA8", 0, 4, gl.RGBA, ""},
+	formatRGBA32F: {"RGBA32F", 0, 16, gl.RGBA16F, ""},
+	formatBC1:     {"BC1", 8, 0, gl.COMPRESSED_RGBA_S3TC_DXT1_EXT, extS3TC},
+	formatBC2:     {"BC2", 16, 0, gl.COMPRESSED_RGBA_S3TC_DXT3_EXT, extS3TC},
+	formatBC3:     {"BC3", 16, 0, gl.COMPRESSED_RGBA_S3TC_DXT5_EXT, extS3TC},
+	formatBC4:     {"BC4", 8, 0, gl.COMPRESSED_RED_RGTC1, ""},
+	formatBC5:     {"BC5", 16, 0, gl.COMPRESSED_RG_RGTC2, ""},
+	formatBC6H:    {"BC6H", 16, 0, gl.COMPRESSED_RGB_BPTC_UNSIGNED_FLOAT_ARB, extBPTC},
+	formatBC7:     {"BC7", 16, 0, gl.COMPRESSED_RGBA_BPTC_UNORM_ARB, extBPTC},
+}
+
+func (f format) String() string {
+	return formats[f].name
+}
+
+// compressed returns whether the format is block-compressed.
+func (f format) compressed() bool {
+	return formats[f].blockSize > 0
+}
+
+// size returns the number of bytes of an image of the given size.
+func (f format) size(width, height int) int {
+	info := formats[f]
+	if info.blockSize > 0 {
+		return ((width + 3) / 4) * ((height + 3) / 4) * info.blockSize
+	}
+	return width * height * info.pixelSize
+}
+
+// supported holds the formats which the OpenGL context can upload. It is
+// filled on the main thread before the first texture is loaded, and only
+// read afterwards.
+var supported map[format]bool
+
+// initFormats queries the formats supported by the OpenGL context.
+// Must be called from the main thread.
+func initFormats() {
+	if supported != nil {
+		return
+	}
+	exts := make(map[string]bool)
+	var n int32
+	gl.GetIntegerv(gl.NUM_EXTENSIONS, &n)
+	for i := int32(0); i < n; i++ {
+		exts[gl.GoStr(gl.GetStringi(gl.EXTENSIONS, uint32(i)))] = true
+	}
+
+	supported = make(map[format]bool)
+	for f, info := range formats {
+		supported[format(f)] = info.extension == "" || exts[info.extension]
+	}
+}
+
+// maxSize is the largest width or height of a texture file.
+const maxSize = 1 << 16
+
+// checkSize returns an error if a texture file has an invalid size or
+// number of mipmap levels.
+func checkSize(width, height, numLevels int) error {
+	if width <= 0 || height <= 0 || width > maxSize || height > maxSize {
+		return fmt.Errorf("invalid size %vx%v", width, height)
+	}
+	if numLevels <= 0 || numLevels > 17 {
+		return fmt.Errorf("invalid number of levels %v", numLevels)
+	}
+	return nil
+}
+
+// level is a mipmap level of a surface.
+type level struct {
+	width, height int
+	data          []byte
+}
+
+// surface is a decoded texture which is ready to be uploaded.
+type surface struct {
+	format format
+	levels []level // Mipmap levels, largest first.
+}
+
+// loadSurface loads a texture file. Formats other than DDS, KTX2, Radiance
+// and OpenEXR are decoded by the image package.
+// The resolution is divided by res by skipping mipmap levels, or by
+// downsampling images decoded by the image package. Other files without
+// mipmaps keep their resolution.
+// Compressed formats which are not supported are decoded on the CPU.
+func loadSurface(file string, res uint) (*surface, error) {
+	var decode func([]byte) (*surface, error)
+	switch strings.ToLower(filepath.Ext(file)) {
+	case ".dds":
+		decode = decodeDDS
+	case ".ktx2":
+		decode = decodeKTX2
+	case ".hdr":
+		decode = decodeHDR
+	case ".exr":
+		decode = decodeEXR
+	default:
+		img, err := loadImage(file, res)
+		if err != nil {
+			return nil, err
+		}
+		return surfaceFromImage(img), nil
+	}
+
+	data, err := readFile(file)
+	if err != nil {
+		return nil, err
+	}
+	s, err := decode(data)
+	if err == nil {
+		err = s.validate()
+	}
+	if err != nil {
+		return nil, fileError(file, err)
+	}
+	s.reduce(res)
+	if s.format.compressed() && !supported[s.format] {
+		if err := s.decompress(); err != nil {
+			return nil, fileError(file, err)
+		}
+	}
+	return s, nil
+}
+
+// surfaceFromImage returns a surface holding a single level of img.
+func surfaceFromImage(img *image.RGBA) *surface {
+	size := img.Rect.Size()
+	return &surface{
+		format: formatRGBA8,
+		levels: []level{{size.X, size.Y, img.Pix}},
+	}
+}
+
+// reduce divides the resolution of the surface by res by discarding its
+// largest mipmap levels. The smallest level is always kept.
+func (s *surface) reduce(res uint) {
+	target := s.levels[0].width / int(res)
+	for len(s.levels) > 1 && s.levels[0].width > target {
+		s.levels = s.levels[1:]
+	}
+}
+
+// decompress decodes a block-compressed surface to RGBA8, or to RGBA32F
+// if the surface is BC6H.
+func (s *surface) decompress() error {
+	if s.format == formatBC6H {
+		s.decodeBlocks(formatRGBA32F, decodeBC6H)
+		return nil
+	}
+	decode := blockDecoders[s.format]
+	if decode == nil {
+		return fmt.Errorf("%v textures are not supported by the GPU", s.format)
+	}
+	var block [16][4]uint8
+	s.decodeBlocks(formatRGBA8, func(b []byte, out []byte) {
+		decode(b, &block)
+		for p := range block {
+			copy(out[p*4:], block[p][:])
+		}
+	})
+	return nil
+}
+
+// decodeBlocks decodes each block of the surface to pixels of the format
+// to. decode writes the 16 pixels of a block to out in row-major order.
+func (s *surface) decodeBlocks(to format, decode func(b []byte, out []byte)) {
+	blockSize := formats[s.format].blockSize
+	pixelSize := formats[to].pixelSize
+	block := make([]byte, 16*pixelSize)
+	for i, l := range s.levels {
+		pix := make([]byte, l.width*l.height*pixelSize)
+		for by := 0; by < (l.height+3)/4; by++ {
+			for bx := 0; bx < (l.width+3)/4; bx++ {
+				off := (by*((l.width+3)/4) + bx) * blockSize
+				decode(l.data[off:off+blockSize], block)
+
+				// Blocks may extend beyond the edges of the image
+				for p := 0; p < 16; p++ {
+					x, y := bx*4+p%4, by*4+p/4
+					if x < l.width && y < l.height {
+						copy(pix[(y*l.width+x)*pixelSize:], block[p*pixelSize:(p+1)*pixelSize])
+					}
+				}
+			}
+		}
+		s.levels[i].data = pix
+	}
+	s.format = to
+}
+
+// validate returns an error if the data of a level does not match its
+// size.
+func (s *surface) validate() error {
+	if len(s.levels) == 0 {
+		return fmt.Errorf("no levels")
+	}
+	for i, l := range s.levels {
+		if l.width <= 0 || l.height <= 0 {
+			return fmt.Errorf("invalid size %vx%v of level %v", l.width, l.height, i)
+		}
+		if len(l.data) != s.format.size(l.width, l.height) {
+			return fmt.Errorf("wrong size of level %v. got %v bytes, expected %v",
+				i, len(l.data), s.format.size(l.width, l.height))
+		}
+	}
+	return nil
+}
+
+// newTexture creates and uploads the texture.
+// Data textures are created without mipmaps and clamped to the edge.
+// Mipmaps are generated unless the surface provides them or is compressed.
+func newTexture(s *surface, isData bool) uint32 {
+	var handle uint32
+
+	// Create and bind texture
+	gl.GenTextures(1, &handle)
+	gl.BindTexture(gl.TEXTURE_2D, handle)
+
+	levels := s.levels
+	if isData {
+		levels = levels[:1]
+		gl.TexParameteri(gl.TEXTURE_2D, gl.TEXTURE_MAG_FILTER, gl.LINEAR)
+		gl.TexParameteri(gl.TEXTURE_2D, gl.TEXTURE_MIN_FILTER, gl.LINEAR)
+		gl.TexParameteri(gl.TEXTURE_2D, gl.TEXTURE_WRAP_S, gl.CLAMP_TO_EDGE)
+		gl.TexParameteri(gl.TEXTURE_2D, gl.TEXTURE_WRAP_T, gl.CLAMP_TO_EDGE)
+	} else {
+		// Set parameters
+		gl.TexParameteri(gl.TEXTURE_2D, gl.TEXTURE_MAG_FILTER, gl.LINEAR)
+		gl.TexParameteri(gl.TEXTURE_2D, gl.TEXTURE_MIN_FILTER, int32(Settings.curFilter))
+		gl.TexParameteri(gl.TEXTURE_2D, gl.TEXTURE_WRAP_S, gl.REPEAT)
+		gl.TexParameteri(gl.TEXTURE_2D, gl.TEXTURE_WRAP_T, gl.REPEAT)
+
+		// Anisotropic filtering
+		gl.TexParameterf(gl.TEXTURE_2D, textureMaxAnisotropyExt, Settings.curAniso)
+	}
+
+	// Upload levels
+	info := formats[s.format]
+	for i, l := range levels {
+		ptr := gl.Ptr(l.data)
+		switch {
+		case info.blockSize > 0:
+			gl.CompressedTexImage2D(gl.TEXTURE_2D, int32(i), info.internal,
+				int32(l.width), int32(l.height), 0, int32(len(l.data)), ptr)
+		case s.format == formatRGBA32F:
+			gl.TexImage2D(gl.TEXTURE_2D, int32(i), int32(info.internal),
+				int32(l.width), int32(l.height), 0, gl.RGBA, gl.FLOAT, ptr)
+		default:
+			gl.TexImage2D(gl.TEXTURE_2D, int32(i), int32(info.internal),
+				int32(l.width), int32(l.height), 0, gl.RGBA, gl.UNSIGNED_BYTE, ptr)
+		}
+	}
+
+	// Generate mipmaps
+	switch {
+	case isData:
+	case len(levels) > 1 || s.format.compressed():
+		gl.TexParameteri(gl.TEXTURE_2D, gl.TEXTURE_MAX_LEVEL, int32(len(levels)-1))
+	default:
+		gl.GenerateMipmap(gl.TEXTURE_2D)
+	}
+
+	gl.BindTexture(gl.TEXTURE_2D, 0)
+	return handle
+}
+
+// putFloat stores v in b as a little-endian float.
+func putFloat(b []byte, v float32) {
+	binary.LittleEndian.PutUint32(b, math.Float32bits(v))
+}
+
+// halfToFloat converts a half-precision float to a float.
+func halfToFloat(h uint16) float32 {
+	sign := uint32(h>>15) << 31
+	exp := uint32(h>>10) & 0x1f
+	mant := uint32(h) & 0x3ff
+	switch {
+	case exp == 0 && mant == 0:
+		return math.Float32frombits(sign)
+	case exp == 0:
+		// Subnormal
+		v := float32(mant) / (1 << 24)
+		if sign != 0 {
+			v = -v
+		}
+		return v
+	case exp == 0x1f:
+		return math.Float32frombits(sign | 0xff<<23 | mant<<13)
+	}
+	return math.Float32frombits(sign | (exp+112)<<23 | mant<<13)
+}
@@ -0,0 +1,286 @@
+package texture
+
+import (
+	"bytes"
+	"compress/zlib"
+	"encoding/binary"
+	"image"
+	"math"
+	"reflect"
+	"testing"
+)
+
+// floatAt returns the float at index i of b.
+func floatAt(b []byte, i int) float32 {
+	return math.Float32frombits(binary.LittleEndian.Uint32(b[i*4:]))
+}
+
+func Test_surface_reduce(t *testing.T) {
+	s := &surface{levels: []level{{8, 8, nil}, {4, 4, nil}, {2, 2, nil}, {1, 1, nil}}}
+	s.reduce(2)
+	if s.levels[0].width != 4 || len(s.levels) != 3 {
+		t.Errorf("wrong levels. got %v", s.levels)
+	}
+	s.reduce(16)
+	if s.levels[0].width != 1 || len(s.levels) != 1 {
+		t.Errorf("smallest level was not kept. got %v", s.levels)
+	}
+}
+
+func Test_downsample(t *testing.T) {
+	img := image.NewRGBA(image.Rect(0, 0, 2, 2))
+	copy(img.Pix, []byte{
+		0, 0, 0, 0, 100, 100, 100, 100,
+		200, 200, 200, 200, 100, 100, 100, 100,
+	})
+	out := downsample(img, 2)
+	if out.Rect.Size() != (image.Point{1, 1}) || !reflect.DeepEqual(out.Pix, []byte{100, 100, 100, 100}) {
+		t.Errorf("wrong image. got %v %v", out.Rect, out.Pix)
+	}
+}
+
+func Test_halfToFloat(t *testing.T) {
+	tests := map[uint16]float32{
+		0x0000: 0,
+		0x3c00: 1,
+		0xc000: -2,
+		0x3800: 0.5,
+		0x0001: 1.0 / (1 << 24),
+		0x7bff: 65504,
+	}
+	for h, expected := range tests {
+		if got := halfToFloat(h); got != expected {
+			t.Errorf("wrong value of %#04x. got %v, expected %v", h, got, expected)
+		}
+	}
+}
+
+func Test_decodeDDS(t *testing.T) {
+	header := func(width, height, levels uint32, fourCC string) []byte {
+		b := make([]byte, ddsHeaderSize)
+		copy(b, "DDS ")
+		binary.LittleEndian.PutUint32(b[4:], 124)
+		binary.LittleEndian.PutUint32(b[8:], ddsdMipMapCount)
+		binary.LittleEndian.PutUint32(b[12:], height)
+		binary.LittleEndian.PutUint32(b[16:], width)
+		binary.LittleEndian.PutUint32(b[28:], levels)
+		binary.LittleEndian.PutUint32(b[80:], ddpfFourCC)
+		copy(b[84:], fourCC)
+		return b
+	}
+
+	// DXT1 with a mip chain of 8x4, 4x2, 2x1 and 1x1
+	data := append(header(8, 4, 4, "DXT1"), make([]byte, 16+8+8+8)...)
+	s, err := decodeDDS(data)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if s.format != formatBC1 || len(s.levels) != 4 || s.validate() != nil {
+		t.Errorf("wrong surface. got %v with %v levels", s.format, len(s.levels))
+	}
+	if _, err := decodeDDS(data[:len(data)-1]); err == nil {
+		t.Error("expected error for truncated file")
+	}
+
+	// DX10 header with BGRA pixels
+	data = append(header(1, 1, 1, "DX10"), make([]byte, ddsDX10Size)...)
+	binary.LittleEndian.PutUint32(data[ddsHeaderSize:], 87)
+	data = append(data, 3, 2, 1, 4)
+	if s, err = decodeDDS(data); err != nil {
+		t.Fatal(err)
+	}
+	if s.format != formatRGBA8 || !reflect.DeepEqual(s.levels[0].data, []byte{1, 2, 3, 4}) {
+		t.Errorf("wrong pixels. got %v %v", s.format, s.levels[0].data)
+	}
+
+	if _, err := decodeDDS([]byte("PNG")); err == nil {
+		t.Error("expected error for wrong magic")
+	}
+}
+
+func Test_decodeKTX2(t *testing.T) {
+	data := make([]byte, ktx2HeaderSize+ktx2LevelIndexSize)
+	copy(data, ktx2Identifier)
+	binary.LittleEndian.PutUint32(data[12:], 109) // R32G32B32A32_SFLOAT
+	binary.LittleEndian.PutUint32(data[20:], 1)
+	binary.LittleEndian.PutUint32(data[24:], 1)
+	binary.LittleEndian.PutUint32(data[36:], 1)
+	binary.LittleEndian.PutUint32(data[40:], 1)
+	binary.LittleEndian.PutUint64(data[ktx2HeaderSize:], uint64(len(data)))
+	binary.LittleEndian.PutUint64(data[ktx2HeaderSize+8:], 16)
+	pixel := make([]byte, 16)
+	for i, v := range []float32{1, 2, 3, 4} {
+		putFloat(pixel[i*4:], v)
+	}
+	data = append(data, pixel...)
+
+	s, err := decodeKTX2(data)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if s.format != formatRGBA32F || len(s.levels) != 1 || !bytes.Equal(s.levels[0].data, pixel) {
+		t.Errorf("wrong surface. got %v %v", s.format, s.levels)
+	}
+
+	// Supercompression is not supported
+	binary.LittleEndian.PutUint32(data[44:], 2)
+	if _, err := decodeKTX2(data); err == nil {
+		t.Error("expected error for supercompressed file")
+	}
+}
+
+func Test_decodeHDR(t *testing.T) {
+	// A run-length encoded scanline followed by a flat one
+	data := []byte("#?RADIANCE\nFORMAT=32-bit_rle_rgbe\n\n-Y 2 +X 8\n")
+	data = append(data, 2, 2, 0, 8)
+	for _, v := range []byte{128, 64, 0, 129} {
+		data = append(data, 128+8, v)
+	}
+	for i := 0; i < 8; i++ {
+		data = append(data, 0, 0, 128, 130)
+	}
+
+	s, err := decodeHDR(data)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if s.format != formatRGBA32F || s.levels[0].width != 8 || s.levels[0].height != 2 {
+		t.Fatalf("wrong surface. got %v %vx%v", s.format, s.levels[0].width, s.levels[0].height)
+	}
+	pix := s.levels[0].data
+	for i, e := range []float32{1, 0.5, 0, 1} {
+		if got := floatAt(pix, 7*4+i); got != e {
+			t.Errorf("wrong value %v of encoded pixel. got %v, expected %v", i, got, e)
+		}
+	}
+	if got := floatAt(pix, 8*4+2); got != 2 {
+		t.Errorf("wrong blue of flat pixel. got %v, expected 2", got)
+	}
+
+	if _, err := decodeHDR(data[:len(data)-1]); err == nil {
+		t.Error("expected error for truncated file")
+	}
+
+	// The size is checked before allocating the image
+	huge := []byte("#?RADIANCE\n\n-Y 65536 +X 65536\n")
+	if _, err := decodeHDR(append(huge, data[len(data)-64:]...)); err == nil {
+		t.Error("expected error for size exceeding data")
+	}
+}
+
+// exrFile returns an uncompressed OpenEXR file of a single line of half
+// float pixels with the given channels.
+func exrFile(names string, lines ...[]uint16) []byte {
+	var b bytes.Buffer
+	put := func(v interface{}) { binary.Write(&b, binary.LittleEndian, v) }
+	attr := func(name, typ string, value []byte) {
+		b.WriteString(name + "\x00" + typ + "\x00")
+		put(uint32(len(value)))
+		b.Write(value)
+	}
+
+	put(uint32(exrMagic))
+	put(uint32(2))
+	var chlist bytes.Buffer
+	for _, n := range names {
+		chlist.WriteString(string(n) + "\x00")
+		binary.Write(&chlist, binary.LittleEndian, []int32{exrHalf, 0, 1, 1})
+	}
+	chlist.WriteByte(0)
+	attr("channels", "chlist", chlist.Bytes())
+	attr("compression", "compression", []byte{exrCompressionNone})
+	width := len(lines[0]) / len(names)
+	window := make([]byte, 16)
+	binary.LittleEndian.PutUint32(window[8:], uint32(width-1))
+	binary.LittleEndian.PutUint32(window[12:], uint32(len(lines)-1))
+	attr("dataWindow", "box2i", window)
+	b.WriteByte(0)
+
+	offset := b.Len() + 8*len(lines)
+	for _, l := range lines {
+		put(uint64(offset))
+		offset += 8 + len(l)*2
+	}
+	for y, l := range lines {
+		put(int32(y))
+		put(int32(len(l) * 2))
+		put(l)
+	}
+	return b.Bytes()
+}
+
+func Test_decodeEXR(t *testing.T) {
+	// Channels are stored in alphabetical order
+	data := exrFile("BGR", []uint16{0x3c00, 0x0000, 0x3800, 0x0000, 0xc000, 0x0000})
+	s, err := decodeEXR(data)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if s.format != formatRGBA32F || s.levels[0].width != 2 || s.levels[0].height != 1 {
+		t.Fatalf("wrong surface. got %v %vx%v", s.format, s.levels[0].width, s.levels[0].height)
+	}
+	var got []float32
+	for i := 0; i < 8; i++ {
+		got = append(got, floatAt(s.levels[0].data, i))
+	}
+	expected := []float32{-2, 0.5, 1, 1, 0, 0, 0, 1}
+	if !reflect.DeepEqual(got, expected) {
+		t.Errorf("wrong pixels. got %v, expected %v", got, expected)
+	}
+
+	if _, err := decodeEXR(data[:len(data)-1]); err == nil {
+		t.Error("expected error for truncated file")
+	}
+
+	// The size is checked against the chunks before allocating the image
+	wide := exrFile("Y", []uint16{0x3c00})
+	i := bytes.Index(wide, []byte("box2i\x00")) + 6 + 4
+	binary.LittleEndian.PutUint32(wide[i+8:], 65535)
+	if _, err := decodeEXR(wide); err == nil {
+		t.Error("expected error for size exceeding chunks")
+	}
+	overlapping := exrFile("Y", []uint16{0x3c00}, []uint16{0x3c00})
+	table := len(overlapping) - 2*8 - 2*(8+2)
+	copy(overlapping[table+8:], overlapping[table:table+8])
+	if _, err := decodeEXR(overlapping); err == nil {
+		t.Error("expected error for overlapping chunks")
+	}
+}
+
+func Test_exrDecompress(t *testing.T) {
+	raw := []byte{1, 2, 3, 4, 5, 6, 7}
+
+	// Split into even and odd bytes and delta encode
+	var tmp []byte
+	for i := 0; i < len(raw); i += 2 {
+		tmp = append(tmp, raw[i])
+	}
+	for i := 1; i < len(raw); i += 2 {
+		tmp = append(tmp, raw[i])
+	}
+	for i := len(tmp) - 1; i > 0; i-- {
+		tmp[i] = tmp[i] - tmp[i-1] + 128
+	}
+
+	var zipped bytes.Buffer
+	zw := zlib.NewWriter(&zipped)
+	zw.Write(tmp)
+	zw.Close()
+	out, err := exrDecompress(zipped.Bytes(), len(raw), exrCompressionZIP)
+	if err != nil || !bytes.Equal(out, raw) {
+		t.Errorf("wrong ZIP data. got %v (%v), expected %v", out, err, raw)
+	}
+
+	rle := append([]byte{byte(-len(tmp))}, tmp...)
+	out, err = exrDecompress(rle, len(raw), exrCompressionRLE)
+	if err != nil || !bytes.Equal(out, raw) {
+		t.Errorf("wrong RLE data. got %v (%v), expected %v", out, err, raw)
+	}
+
+	if _, err := exrUnRLE([]byte{3, 9}, 4); err != nil {
+		t.Errorf("unexpected error for run. got %v", err)
+	}
+	if _, err := exrUnRLE([]byte{3, 9}, 3); err == nil {
+		t.Error("expected error for run exceeding size")
+	}
+}
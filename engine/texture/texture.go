@@ -6,11 +6,11 @@ import (
 	_ "image/jpeg" // Support JPEG format
 	_ "image/png"  // Support PNG format
 	"os"
+	"sort"
 
+	"github.com/patrick-jessen/goplay/engine/asset"
 	"github.com/patrick-jessen/goplay/engine/worker"
 
-	"github.com/nfnt/resize"
-
 	"github.com/patrick-jessen/goplay/engine/log"
 
 	"github.com/go-gl/gl/v3.2-core/gl"
@@ -37,12 +37,61 @@ func Load(name string) *Texture {
 	return &t
 }
 
+// LoadData returns a texture holding data, such as a lookup table.
+// Unlike Load, the texture keeps its full resolution and is neither
+// filtered across mipmaps nor repeated.
+func LoadData(name string) *Texture {
+	key := "data:" + name
+	if val, ok := cache[key]; ok {
+		return val
+	}
+	t := Texture{file: name, data: true}
+	t.load()
+	cache[key] = &t
+	return &t
+}
+
+// Loading returns whether any texture is currently being loaded.
+func Loading() bool {
+	for _, t := range cache {
+		if t.loading {
+			return true
+		}
+	}
+	return false
+}
+
 // Texture represents an OpenGL texture.
 type Texture struct {
 	loaded  bool
 	loading bool
 	handle  uint32
 	file    string
+	data    bool  // Whether the texture holds data rather than an image.
+	err     error // The error which occurred when last loading the texture.
+}
+
+// File returns the path of the image file of the texture.
+func (t *Texture) File() string {
+	return textureDir + t.file
+}
+
+// Loaded returns whether the texture has finished loading.
+func (t *Texture) Loaded() bool {
+	return t.loaded && t.handle != 0
+}
+
+// Pending returns whether the texture has not been uploaded yet, such
+// that Bind binds a placeholder. Shaders should treat the texture as
+// absent, since the placeholder does not match its content.
+func (t *Texture) Pending() bool {
+	return t.handle == 0 && t.err == nil
+}
+
+// Err returns the error which occurred when loading the texture, if any.
+// Errors are of type *asset.Error.
+func (t *Texture) Err() error {
+	return t.err
 }
 
 // Unload unloads the texture and its resources.
@@ -55,12 +104,26 @@ func (t *Texture) Unload() {
 func (t *Texture) load() {
 	t.loading = true
 	res := Settings.curRes
+	if t.data {
+		res = 1
+	}
+	initFormats()
 
 	go func() {
-		img := loadImage(textureDir+t.file, res)
+		s, err := loadSurface(textureDir+t.file, res)
+		if err != nil {
+			log.Error("could not load texture", "file", textureDir+t.file, "error", err)
+			worker.CallSynchronized(func() {
+				t.err = err
+				t.loading = false
+				t.loaded = true
+			})
+			return
+		}
 		worker.CallSynchronized(func() {
 			t.Unload()
-			t.handle = newTexture(img)
+			t.handle = newTexture(s, t.data)
+			t.err = nil
 			t.loading = false
 			t.loaded = true
 		})
@@ -68,65 +131,111 @@ func (t *Texture) load() {
 }
 
 // Bind binds the texture to the given texture location.
+// Until the texture is uploaded, a neutral placeholder is bound instead.
+// If the texture failed to load, a magenta checkerboard is bound.
 func (t *Texture) Bind(idx uint32) {
 	if !t.loaded && !t.loading {
 		t.load()
 	}
 
+	handle := t.handle
+	if handle == 0 {
+		if t.err != nil {
+			handle = builtin(&checkerHandle, checkerboard)
+		} else {
+			handle = builtin(&placeholderHandle, placeholder)
+		}
+	}
 	gl.ActiveTexture(gl.TEXTURE0 + idx)
-	gl.BindTexture(gl.TEXTURE_2D, t.handle)
-}
-
-// newTexture creates and uploads the texture.
-func newTexture(data *image.RGBA) uint32 {
-	var handle uint32
-
-	// Create and bind texture
-	gl.GenTextures(1, &handle)
 	gl.BindTexture(gl.TEXTURE_2D, handle)
+}
 
-	// Set parameters
-	gl.TexParameteri(gl.TEXTURE_2D, gl.TEXTURE_MAG_FILTER, gl.LINEAR)
-	gl.TexParameteri(gl.TEXTURE_2D, gl.TEXTURE_MIN_FILTER, int32(Settings.curFilter))
-	gl.TexParameteri(gl.TEXTURE_2D, gl.TEXTURE_WRAP_S, gl.REPEAT)
-	gl.TexParameteri(gl.TEXTURE_2D, gl.TEXTURE_WRAP_T, gl.REPEAT)
-
-	// Anisotropic filtering
-	gl.TexParameterf(gl.TEXTURE_2D, textureMaxAnisotropyExt, Settings.curAniso)
-
-	// Upload image
-	gl.TexImage2D(gl.TEXTURE_2D, 0, gl.RGBA,
-		int32(data.Rect.Size().X),
-		int32(data.Rect.Size().Y),
-		0, gl.RGBA, gl.UNSIGNED_BYTE, gl.Ptr(data.Pix))
-
-	// Generate mipmaps
-	gl.GenerateMipmap(gl.TEXTURE_2D)
-
-	gl.BindTexture(gl.TEXTURE_2D, 0)
-	return handle
+// Errors returns the errors of the textures which failed to load, sorted
+// by file.
+func Errors() []error {
+	var errs []error
+	for _, t := range cache {
+		if t.err != nil {
+			errs = append(errs, t.err)
+		}
+	}
+	sort.Slice(errs, func(i, j int) bool {
+		return errs[i].Error() < errs[j].Error()
+	})
+	return errs
 }
 
 // loadImage loads an image from file.
-func loadImage(file string, res uint) *image.RGBA {
+// Errors are of type *asset.Error.
+func loadImage(file string, res uint) (*image.RGBA, error) {
 	imgFile, err := os.Open(file)
 	if err != nil {
-		log.Panic("could not open texture file", "imgFile", imgFile, "error", err)
+		return nil, &asset.Error{File: file, Err: err}
 	}
+	defer imgFile.Close()
+
 	img, _, err := image.Decode(imgFile)
 	if err != nil {
-		log.Panic("could not decode image", "error", err)
+		return nil, &asset.Error{File: file, Err: err}
+	}
+
+	rgba, ok := img.(*image.RGBA)
+	if !ok || rgba.Rect.Min != (image.Point{}) || rgba.Stride != rgba.Rect.Dx()*4 {
+		rgba = image.NewRGBA(image.Rect(0, 0, img.Bounds().Dx(), img.Bounds().Dy()))
+		draw.Draw(rgba, rgba.Bounds(), img, img.Bounds().Min, draw.Src)
+	}
+	if res > 1 {
+		rgba = downsample(rgba, int(res))
+	}
+	return rgba, nil
+}
+
+// downsample divides the size of an image by res by averaging blocks of
+// res by res pixels. Remaining pixels at the edges are dropped.
+func downsample(img *image.RGBA, res int) *image.RGBA {
+	size := img.Rect.Size()
+	out := image.NewRGBA(image.Rect(0, 0, maxInt(size.X/res, 1), maxInt(size.Y/res, 1)))
+	outSize := out.Rect.Size()
+	for y := 0; y < outSize.Y; y++ {
+		for x := 0; x < outSize.X; x++ {
+			var sum [4]int
+			n := 0
+			for sy := y * res; sy < (y+1)*res && sy < size.Y; sy++ {
+				row := img.Pix[sy*img.Stride:]
+				for sx := x * res; sx < (x+1)*res && sx < size.X; sx++ {
+					for c := range sum {
+						sum[c] += int(row[sx*4+c])
+					}
+					n++
+				}
+			}
+			for c := range sum {
+				out.Pix[y*out.Stride+x*4+c] = uint8(sum[c] / n)
+			}
+		}
 	}
+	return out
+}
 
-	width := img.Bounds().Dx() / int(res)
-	height := img.Bounds().Dy() / int(res)
-	img = resize.Resize(uint(width), uint(height), img, resize.Bicubic)
+// maxInt returns the larger of a and b.
+func maxInt(a, b int) int {
+	if a > b {
+		return a
+	}
+	return b
+}
 
-	rgba := image.NewRGBA(img.Bounds())
-	if rgba.Stride != rgba.Rect.Size().X*4 {
-		log.Panic("unsupported stride", "stride", rgba.Stride)
+// readFile reads a texture file.
+// Errors are of type *asset.Error.
+func readFile(file string) ([]byte, error) {
+	data, err := os.ReadFile(file)
+	if err != nil {
+		return nil, fileError(file, err)
 	}
-	draw.Draw(rgba, rgba.Bounds(), img, image.Point{0, 0}, draw.Src)
+	return data, nil
+}
 
-	return rgba
+// fileError returns an error of a texture file.
+func fileError(file string, err error) error {
+	return &asset.Error{File: file, Err: err}
 }
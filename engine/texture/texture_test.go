@@ -0,0 +1,46 @@
+package texture
+
+import (
+	"errors"
+	"image/color"
+	"reflect"
+	"testing"
+
+	"github.com/patrick-jessen/goplay/engine/asset"
+)
+
+func Test_loadImage(t *testing.T) {
+	_, e := loadImage("doesNotExist.png", 1)
+	if ae, ok := e.(*asset.Error); !ok || ae.File != "doesNotExist.png" {
+		t.Errorf("wrong error for missing file. got %v", e)
+	}
+}
+
+func TestErrors(t *testing.T) {
+	defer func() { cache = make(map[string]*Texture) }()
+	cache = map[string]*Texture{
+		"b":  {err: errors.New("b")},
+		"ok": {handle: 1},
+		"a":  {err: errors.New("a")},
+	}
+
+	var msgs []string
+	for _, e := range Errors() {
+		msgs = append(msgs, e.Error())
+	}
+	if !reflect.DeepEqual(msgs, []string{"a", "b"}) {
+		t.Errorf("wrong errors. got %v", msgs)
+	}
+	if cache["ok"].Pending() || cache["a"].Pending() || !(&Texture{}).Pending() {
+		t.Error("wrong pending state")
+	}
+}
+
+func Test_checkerboard(t *testing.T) {
+	img := checkerboard()
+	magenta := color.RGBA{255, 0, 255, 255}
+	if img.RGBAAt(0, 0) != magenta || img.RGBAAt(checkerCell, 0) == magenta ||
+		img.RGBAAt(checkerCell, checkerCell) != magenta {
+		t.Error("wrong checkerboard pattern")
+	}
+}
@@ -1,11 +1,13 @@
 package window
 
 import (
-	"log"
+	"errors"
+	"fmt"
 	"runtime"
 
 	"github.com/go-gl/gl/v3.2-core/gl"
 	"github.com/go-gl/glfw/v3.3/glfw"
+	"github.com/patrick-jessen/goplay/engine/log"
 )
 
 func init() {
@@ -13,28 +15,59 @@ func init() {
 }
 
 var winHandle *glfw.Window
+var headless bool
 var resizeHandlers []func(int, int)
 
 // Create creates the window.
 func Create() {
+	if err := create(true); err != nil {
+		log.Panic("failed to create window", "error", err)
+	}
+}
+
+// CreateHeadless creates a hidden window whose context can be used for
+// offscreen rendering. If a native context cannot be created, an OSMesa
+// (software) context is attempted instead.
+func CreateHeadless() error {
+	return create(false)
+}
+
+// create initializes the window system and creates the window.
+func create(visible bool) error {
 	if err := glfw.Init(); err != nil {
-		log.Panic("failed to initialize window system", "error", err)
+		return fmt.Errorf("failed to initialize window system: %v", err)
 	}
 	glfw.WindowHint(glfw.ContextVersionMajor, 3)
 	glfw.WindowHint(glfw.ContextVersionMinor, 2)
 	glfw.WindowHint(glfw.OpenGLForwardCompatible, 1)
 	glfw.WindowHint(glfw.OpenGLProfile, glfw.OpenGLCoreProfile)
 
-	mode := glfw.GetPrimaryMonitor().GetVideoMode()
-	glfw.WindowHint(glfw.RedBits, mode.RedBits)
-	glfw.WindowHint(glfw.GreenBits, mode.GreenBits)
-	glfw.WindowHint(glfw.BlueBits, mode.BlueBits)
-	glfw.WindowHint(glfw.RefreshRate, mode.RefreshRate)
+	if mon := glfw.GetPrimaryMonitor(); mon != nil {
+		mode := mon.GetVideoMode()
+		glfw.WindowHint(glfw.RedBits, mode.RedBits)
+		glfw.WindowHint(glfw.GreenBits, mode.GreenBits)
+		glfw.WindowHint(glfw.BlueBits, mode.BlueBits)
+		glfw.WindowHint(glfw.RefreshRate, mode.RefreshRate)
+	} else if visible {
+		glfw.Terminate()
+		return errors.New("no monitor available")
+	}
 
 	var err error
-	winHandle, err = glfw.CreateWindow(800, 600, "GoPlay", nil, nil)
+	if visible {
+		winHandle, err = glfw.CreateWindow(800, 600, "GoPlay", nil, nil)
+	} else {
+		glfw.WindowHint(glfw.Visible, glfw.False)
+		winHandle, err = glfw.CreateWindow(Settings.newSize[0], Settings.newSize[1], "GoPlay", nil, nil)
+		if err != nil {
+			// Fall back to a software context.
+			glfw.WindowHint(glfw.ContextCreationAPI, glfw.OSMesaContextAPI)
+			winHandle, err = glfw.CreateWindow(Settings.newSize[0], Settings.newSize[1], "GoPlay", nil, nil)
+		}
+	}
 	if err != nil {
-		log.Panic("failed to create window", "error", err)
+		glfw.Terminate()
+		return fmt.Errorf("failed to create window: %v", err)
 	}
 	winHandle.MakeContextCurrent()
 
@@ -45,21 +78,32 @@ func Create() {
 	winHandle.SetFramebufferSizeCallback(resizeCallback)
 
 	if err := gl.Init(); err != nil {
-		log.Panic("failed to initialize OpenGL", "error", err)
+		winHandle.Destroy()
+		glfw.Terminate()
+		return fmt.Errorf("failed to initialize OpenGL: %v", err)
 	}
 
+	headless = !visible
+
 	gl.Enable(gl.CULL_FACE)
 	gl.Enable(gl.FRAMEBUFFER_SRGB)
 	gl.Enable(gl.DEPTH_TEST)
 	gl.Enable(gl.MULTISAMPLE)
 
 	Settings.Apply()
+	return nil
+}
+
+// Headless returns whether the window was created by CreateHeadless.
+func Headless() bool {
+	return headless
 }
 
 // Destroy closes the window.
 func Destroy() {
 	winHandle.Destroy()
 	glfw.Terminate()
+	headless = false
 }
 
 // AddResizeHandler sets the resize handler.
@@ -1,7 +1,156 @@
+// Package worker schedules work which must run on the main thread, such
+// as OpenGL calls made on behalf of other goroutines.
+//
+// Tasks are queued by priority and run by the main loop, which drains
+// them up to a time budget per frame. Tasks of high priority are always
+// run, whereas the remaining tasks are deferred to the next frame once
+// the budget is spent.
 package worker
 
-var Channel = make(chan func(), 100)
+import (
+	"sync"
+	"time"
+)
 
+// Priority is the priority of a task. Lower values run first.
+type Priority int
+
+const (
+	// PriorityHigh is used for interactive work, such as editor commands.
+	// It is not limited by the time budget.
+	PriorityHigh Priority = iota
+	// PriorityNormal is used for asset uploads.
+	PriorityNormal
+	// PriorityLow is used for work which may be postponed.
+	PriorityLow
+	numPriorities
+)
+
+// Future is the completion of a scheduled task.
+type Future struct {
+	done chan struct{}
+}
+
+// Done returns a channel which is closed once the task has run.
+func (f *Future) Done() <-chan struct{} {
+	return f.done
+}
+
+// Wait blocks until the task has run.
+// Must not be called from the main thread, as the task would never run.
+func (f *Future) Wait() {
+	<-f.done
+}
+
+type task struct {
+	fn     func()
+	future *Future
+}
+
+// run runs the task and completes its future.
+func (t task) run() {
+	defer close(t.future.done)
+	t.fn()
+}
+
+var (
+	mu     sync.Mutex
+	queued = sync.NewCond(&mu)
+	queues [numPriorities][]task
+)
+
+// Schedule queues fn to run on the main thread with the given priority.
+// Tasks of equal priority run in the order they are scheduled.
+// Never blocks.
+func Schedule(p Priority, fn func()) *Future {
+	if p < 0 || p >= numPriorities {
+		p = PriorityNormal
+	}
+	t := task{fn: fn, future: &Future{done: make(chan struct{})}}
+
+	mu.Lock()
+	queues[p] = append(queues[p], t)
+	mu.Unlock()
+	queued.Signal()
+	return t.future
+}
+
+// Call runs fn on the main thread with the given priority, and waits for
+// it to finish. Must not be called from the main thread.
+func Call(p Priority, fn func()) {
+	Schedule(p, fn).Wait()
+}
+
+// CallSynchronized queues fn to run on the main thread with normal
+// priority.
 func CallSynchronized(fn func()) {
-	Channel <- fn
+	Schedule(PriorityNormal, fn)
+}
+
+// Run runs queued tasks in order of priority until no tasks remain or the
+// budget is spent. At least one task is run if any are queued, such that
+// work progresses even if the budget is exceeded by rendering. Tasks
+// scheduled while running are run as well.
+// Returns the number of tasks run.
+func Run(budget time.Duration) int {
+	start := time.Now()
+	n := 0
+	for {
+		limit := PriorityLow
+		if n > 0 && time.Since(start) >= budget {
+			limit = PriorityHigh
+		}
+		t, ok := next(limit)
+		if !ok {
+			return n
+		}
+		t.run()
+		n++
+	}
+}
+
+// RunNext runs the next task, waiting for one to be scheduled if none are
+// queued.
+func RunNext() {
+	mu.Lock()
+	for pending() == 0 {
+		queued.Wait()
+	}
+	mu.Unlock()
+
+	if t, ok := next(PriorityLow); ok {
+		t.run()
+	}
+}
+
+// Pending returns the number of queued tasks.
+func Pending() int {
+	mu.Lock()
+	defer mu.Unlock()
+	return pending()
+}
+
+// pending returns the number of queued tasks. Assumes mu is locked.
+func pending() int {
+	n := 0
+	for _, q := range queues {
+		n += len(q)
+	}
+	return n
+}
+
+// next removes and returns the first task of the highest priority, which
+// is at least as high as limit. Returns false if there is no such task.
+func next(limit Priority) (task, bool) {
+	mu.Lock()
+	defer mu.Unlock()
+	for p := PriorityHigh; p <= limit; p++ {
+		if q := queues[p]; len(q) > 0 {
+			t := q[0]
+			q[0] = task{}
+			queues[p] = q[1:]
+			return t, true
+		}
+	}
+	return task{}, false
 }
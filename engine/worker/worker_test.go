@@ -0,0 +1,69 @@
+package worker
+
+import (
+	"reflect"
+	"testing"
+	"time"
+)
+
+func TestRun(t *testing.T) {
+	var order []string
+	record := func(name string) func() {
+		return func() { order = append(order, name) }
+	}
+	Schedule(PriorityLow, record("low"))
+	Schedule(PriorityNormal, record("normal1"))
+	f := Schedule(PriorityHigh, record("high"))
+	Schedule(PriorityNormal, record("normal2"))
+
+	if n := Run(time.Hour); n != 4 {
+		t.Errorf("wrong number of tasks run. got %v, expected %v", n, 4)
+	}
+	expected := []string{"high", "normal1", "normal2", "low"}
+	if !reflect.DeepEqual(order, expected) {
+		t.Errorf("wrong order. got %v, expected %v", order, expected)
+	}
+	select {
+	case <-f.Done():
+	default:
+		t.Error("future was not completed")
+	}
+}
+
+func TestRun_budget(t *testing.T) {
+	defer Run(time.Hour)
+
+	var ran []string
+	Schedule(PriorityNormal, func() {
+		ran = append(ran, "normal1")
+		time.Sleep(time.Millisecond)
+	})
+	Schedule(PriorityNormal, func() {
+		ran = append(ran, "normal2")
+		Schedule(PriorityHigh, func() { ran = append(ran, "high") })
+	})
+
+	// The first task exceeds the budget, but high priority tasks still run
+	Run(0)
+	if len(ran) != 1 || Pending() != 1 {
+		t.Fatalf("budget was not respected. ran %v", ran)
+	}
+	Run(0)
+	if !reflect.DeepEqual(ran, []string{"normal1", "normal2", "high"}) {
+		t.Errorf("high priority task scheduled while running was not run. ran %v", ran)
+	}
+}
+
+func TestCall(t *testing.T) {
+	done := make(chan bool)
+	go func() {
+		x := 0
+		Call(PriorityNormal, func() { x = 42 })
+		done <- x == 42
+	}()
+
+	RunNext()
+	if !<-done {
+		t.Error("Call returned before the task ran")
+	}
+}
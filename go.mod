@@ -8,7 +8,6 @@ require (
 	github.com/go-gl/mathgl v1.0.0
 	github.com/gorilla/handlers v1.5.1
 	github.com/gorilla/mux v1.8.0
-	github.com/nfnt/resize v0.0.0-20180221191011-83c6a9932646
 )
 
 require (
@@ -1,16 +1,39 @@
 package main
 
 import (
+	"flag"
+	"os"
+
 	"github.com/patrick-jessen/goplay/engine"
+	"github.com/patrick-jessen/goplay/engine/log"
+	"github.com/patrick-jessen/goplay/engine/model"
 	"github.com/patrick-jessen/goplay/engine/texture"
 	"github.com/patrick-jessen/goplay/engine/window"
 )
 
+var (
+	headless = flag.Bool("headless", false, "render offscreen and exit")
+	frames   = flag.Int("frames", 10, "number of frames to render in headless mode")
+	output   = flag.String("out", "frame.png", "output file in headless mode")
+	validate = flag.Bool("validate", false, "validate models when loading them")
+)
+
 func main() {
+	flag.Parse()
+	model.SetValidation(*validate)
+
 	window.Settings.SetVSync(true)
 	window.Settings.SetTitle("MyGame")
 	window.Settings.SetSize(1024, 768)
 
+	if *headless {
+		if err := engine.StartHeadless("main", *frames, *output); err != nil {
+			log.Error("headless rendering failed", "error", err)
+			os.Exit(1)
+		}
+		return
+	}
+
 	texture.Settings.SetResolution(10)
 	texture.Settings.Apply()
 